@@ -0,0 +1,78 @@
+package blockchain
+
+import (
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+)
+
+// Sponsorship contracts are minimal proxies, so PolygonScan does not return a usable ABI for them.
+// This is the subset of the Sponsorship ABI the service relies on.
+const sponsorshipAbiJSON = `[
+	{"type":"event","name":"StakeUpdate","anonymous":false,"inputs":[
+		{"name":"operator","type":"address","indexed":true},
+		{"name":"stakedWei","type":"uint256","indexed":false},
+		{"name":"earningsWei","type":"uint256","indexed":false},
+		{"name":"lockedStakeWei","type":"uint256","indexed":false}]},
+	{"type":"event","name":"SponsorshipUpdate","anonymous":false,"inputs":[
+		{"name":"totalStakedWei","type":"uint256","indexed":false},
+		{"name":"remainingWei","type":"uint256","indexed":false},
+		{"name":"operatorCount","type":"uint32","indexed":false},
+		{"name":"isRunning","type":"bool","indexed":false}]},
+	{"type":"event","name":"OperatorJoined","anonymous":false,"inputs":[
+		{"name":"operator","type":"address","indexed":true}]},
+	{"type":"event","name":"OperatorLeft","anonymous":false,"inputs":[
+		{"name":"operator","type":"address","indexed":true},
+		{"name":"returnedStakeWei","type":"uint256","indexed":false}]},
+	{"type":"event","name":"SponsorshipReceived","anonymous":false,"inputs":[
+		{"name":"sponsor","type":"address","indexed":true},
+		{"name":"amount","type":"uint256","indexed":false}]},
+	{"type":"event","name":"OperatorSlashed","anonymous":false,"inputs":[
+		{"name":"operator","type":"address","indexed":true},
+		{"name":"amountWei","type":"uint256","indexed":false}]},
+	{"type":"function","name":"totalStakedWei","stateMutability":"view","inputs":[],"outputs":[{"name":"","type":"uint256"}]},
+	{"type":"function","name":"remainingWei","stateMutability":"view","inputs":[],"outputs":[{"name":"","type":"uint256"}]},
+	{"type":"function","name":"operatorCount","stateMutability":"view","inputs":[],"outputs":[{"name":"","type":"uint256"}]},
+	{"type":"function","name":"isRunning","stateMutability":"view","inputs":[],"outputs":[{"name":"","type":"bool"}]},
+	{"type":"function","name":"solventUntilTimestamp","stateMutability":"view","inputs":[],"outputs":[{"name":"","type":"uint256"}]},
+	{"type":"function","name":"minimumStakingPeriodSeconds","stateMutability":"view","inputs":[],"outputs":[{"name":"","type":"uint256"}]},
+	{"type":"function","name":"getLeavePenalty","stateMutability":"view","inputs":[{"name":"operator","type":"address"}],"outputs":[{"name":"","type":"uint256"}]},
+	{"type":"function","name":"joinTimestampOfOperator","stateMutability":"view","inputs":[{"name":"operator","type":"address"}],"outputs":[{"name":"","type":"uint256"}]},
+	{"type":"function","name":"stakedWei","stateMutability":"view","inputs":[{"name":"operator","type":"address"}],"outputs":[{"name":"","type":"uint256"}]},
+	{"type":"function","name":"lockedStakeWei","stateMutability":"view","inputs":[{"name":"operator","type":"address"}],"outputs":[{"name":"","type":"uint256"}]},
+	{"type":"function","name":"getEarnings","stateMutability":"view","inputs":[{"name":"operator","type":"address"}],"outputs":[{"name":"","type":"uint256"}]}
+]`
+
+// StreamrConfig holds the protocol parameters shared by all operators and sponsorships.
+const streamrConfigAbiJSON = `[
+	{"type":"function","name":"protocolFeeFraction","stateMutability":"view","inputs":[],"outputs":[{"name":"","type":"uint256"}]},
+	{"type":"function","name":"minimumStakeWei","stateMutability":"view","inputs":[],"outputs":[{"name":"","type":"uint256"}]},
+	{"type":"function","name":"minimumSelfDelegationFraction","stateMutability":"view","inputs":[],"outputs":[{"name":"","type":"uint256"}]}
+]`
+
+// The DATA token is an ERC-20 (ERC-677) token.
+const erc20AbiJSON = `[
+	{"type":"event","name":"Transfer","anonymous":false,"inputs":[
+		{"name":"from","type":"address","indexed":true},
+		{"name":"to","type":"address","indexed":true},
+		{"name":"value","type":"uint256","indexed":false}]},
+	{"type":"function","name":"balanceOf","stateMutability":"view","inputs":[{"name":"account","type":"address"}],"outputs":[{"name":"","type":"uint256"}]},
+	{"type":"function","name":"totalSupply","stateMutability":"view","inputs":[],"outputs":[{"name":"","type":"uint256"}]},
+	{"type":"function","name":"symbol","stateMutability":"view","inputs":[],"outputs":[{"name":"","type":"string"}]},
+	{"type":"function","name":"decimals","stateMutability":"view","inputs":[],"outputs":[{"name":"","type":"uint8"}]},
+	{"type":"function","name":"allowance","stateMutability":"view","inputs":[{"name":"owner","type":"address"},{"name":"spender","type":"address"}],"outputs":[{"name":"","type":"uint256"}]},
+	{"type":"function","name":"approve","stateMutability":"nonpayable","inputs":[{"name":"spender","type":"address"},{"name":"amount","type":"uint256"}],"outputs":[{"name":"","type":"bool"}]},
+	{"type":"function","name":"transferAndCall","stateMutability":"nonpayable","inputs":[{"name":"to","type":"address"},{"name":"value","type":"uint256"},{"name":"data","type":"bytes"}],"outputs":[{"name":"","type":"bool"}]}
+]`
+
+var SponsorshipAbi = mustParseAbi(sponsorshipAbiJSON)
+var StreamrConfigAbi = mustParseAbi(streamrConfigAbiJSON)
+var ERC20Abi = mustParseAbi(erc20AbiJSON)
+
+func mustParseAbi(abiJSON string) abi.ABI {
+	parsed, err := abi.JSON(strings.NewReader(abiJSON))
+	if err != nil {
+		panic(err)
+	}
+	return parsed
+}
@@ -0,0 +1,61 @@
+package blockchain
+
+import (
+	"context"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// healthTimeout bounds the RPC calls of the health checks, so a hanging node fails them instead of
+// blocking the probe.
+const healthTimeout = 10 * time.Second
+
+// NonceState is the nonce sequence of the signer next to the nonces the node knows of. Next is
+// missing while a transaction holds the nonce. Transactions with a nonce between Confirmed and
+// Pending are waiting to be mined.
+type NonceState struct {
+	Next      *uint64 `json:"next,omitempty"`
+	InUse     bool    `json:"inUse"`
+	Pending   uint64  `json:"pending"`   // the node's pending nonce
+	Confirmed uint64  `json:"confirmed"` // the nonce at the latest block
+}
+
+// ChainID returns the chain ID of the RPC endpoint.
+func (tm *TxManager) ChainID() (*big.Int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), healthTimeout)
+	defer cancel()
+	return tm.client.ChainID(ctx)
+}
+
+// LatestHeader returns the header of the latest block.
+func (tm *TxManager) LatestHeader() (*types.Header, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), healthTimeout)
+	defer cancel()
+	return tm.client.HeaderByNumber(ctx, nil)
+}
+
+// NonceState reads the nonce sequence without waiting for a transaction holding it.
+func (tm *TxManager) NonceState() (NonceState, error) {
+	var state NonceState
+	select {
+	case nonce := <-tm.nonce:
+		tm.nonce <- nonce
+		state.Next = &nonce
+	default:
+		state.InUse = true
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), healthTimeout)
+	defer cancel()
+	signer := tm.fromAddress()
+	var err error
+	if state.Pending, err = tm.client.PendingNonceAt(ctx, signer); err != nil {
+		return state, err
+	}
+	if state.Confirmed, err = tm.client.NonceAt(ctx, signer, nil); err != nil {
+		return state, err
+	}
+	return state, nil
+}
@@ -0,0 +1,646 @@
+package blockchain
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"strings"
+	"sync"
+	"time"
+
+	"streamr_api/config"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+var ErrIndexerDisabled = errors.New("event indexer is not running")
+
+// IndexedEvent is a decoded contract log as stored by the Indexer. Argument values are
+// stored as strings (decimal for integers, hex for addresses and hashes) so that
+// uint256 values survive the JSON round trip.
+type IndexedEvent struct {
+	Contract    ethcommon.Address `json:"contract"`
+	Name        string            `json:"name"`
+	BlockNumber uint64            `json:"blockNumber"`
+	BlockHash   ethcommon.Hash    `json:"blockHash"`
+	TxHash      ethcommon.Hash    `json:"txHash"`
+	LogIndex    uint              `json:"logIndex"`
+	Timestamp   uint64            `json:"timestamp"`
+	Args        map[string]string `json:"args"`
+}
+
+// EventFilter selects indexed events. Zero values mean "no restriction".
+type EventFilter struct {
+	Names    []string
+	Contract ethcommon.Address
+	TxHash   ethcommon.Hash
+	From     time.Time
+	To       time.Time
+	Limit    int // only the most recent Limit events are returned
+}
+
+type IndexerConfig struct {
+	StartBlock   uint64        // first block to backfill; 0 looks up the block the operator contract was deployed in
+	BatchSize    uint64        // maximum block range per eth_getLogs request
+	PollInterval time.Duration // how often new blocks are fetched when no log subscription is available
+	ReorgDepth   uint64        // how many blocks of history are kept for reorg detection
+}
+
+type IndexerStatus struct {
+	Running      bool                `json:"running"`
+	LastBlock    uint64              `json:"lastBlock"`
+	HeadBlock    uint64              `json:"headBlock"`
+	LastSync     time.Time           `json:"lastSync"`
+	LastError    string              `json:"lastError,omitempty"`
+	Sponsorships []ethcommon.Address `json:"sponsorships"`
+}
+
+// Indexer backfills and then follows the logs of the operator contract and every sponsorship
+// it has staked in, and stores the decoded events in the Store keyed by block and log index.
+type Indexer struct {
+	tm     *TxManager
+	store  *Store
+	config IndexerConfig
+	prefix string
+
+	mu           sync.RWMutex
+	sponsorships map[ethcommon.Address]bool
+	running      bool
+	lastBlock    uint64
+	headBlock    uint64
+	lastSync     time.Time
+	lastError    string
+
+	trigger             chan struct{}
+	sponsorshipsChanged chan struct{}
+	quit                chan struct{}
+	wg                  sync.WaitGroup
+}
+
+type blockCheckpoint struct {
+	Number uint64         `json:"number"`
+	Hash   ethcommon.Hash `json:"hash"`
+}
+
+func NewIndexerConfig(c config.IndexerConfig) IndexerConfig {
+	return IndexerConfig{
+		StartBlock:   c.StartBlock,
+		BatchSize:    c.BatchSize,
+		PollInterval: time.Duration(c.PollSeconds) * time.Second,
+		ReorgDepth:   128,
+	}
+}
+
+func NewIndexer(tm *TxManager, store *Store, config IndexerConfig) *Indexer {
+	return &Indexer{
+		tm:     tm,
+		store:  store,
+		config: config,
+		prefix: indexerPrefix(tm.contractAddr),
+
+		sponsorships:        make(map[ethcommon.Address]bool),
+		trigger:             make(chan struct{}, 1),
+		sponsorshipsChanged: make(chan struct{}, 1),
+		quit:                make(chan struct{}),
+	}
+}
+
+// Start loads the indexer state from the store and starts the background sync.
+func (ix *Indexer) Start() error {
+	var cursor uint64
+	found, err := ix.store.Get(ix.prefix+"cursor", &cursor)
+	if err != nil {
+		return err
+	}
+	if !found {
+		startBlock := ix.config.StartBlock
+		if startBlock == 0 {
+			startBlock, err = ix.findDeploymentBlock()
+			if err != nil {
+				return err
+			}
+		}
+		if startBlock > 0 {
+			cursor = startBlock - 1
+		}
+	}
+
+	var sponsorships []ethcommon.Address
+	_, err = ix.store.Get(ix.prefix+"sponsorships", &sponsorships)
+	if err != nil {
+		return err
+	}
+
+	// the backfill may start after the operator staked into its sponsorships, so seed the set from the contract
+	result, err := ix.tm.ContractCall("getSponsorshipsAndEarnings", []interface{}{})
+	if err != nil {
+		log.Printf("Indexer: failed to seed sponsorships: %v", err)
+	} else {
+		sponsorships = append(sponsorships, result[0].([]ethcommon.Address)...)
+	}
+
+	ix.mu.Lock()
+	ix.lastBlock = cursor
+	for _, addr := range sponsorships {
+		ix.sponsorships[addr] = true
+	}
+	ix.running = true
+	ix.mu.Unlock()
+
+	log.Printf("Indexer: starting from block %d for operator %s", cursor+1, ix.tm.contractAddr.Hex())
+
+	ix.wg.Add(2)
+	go ix.run()
+	go ix.subscribe()
+	return nil
+}
+
+// Stop stops the background sync and waits for the current batch to be written.
+func (ix *Indexer) Stop() {
+	close(ix.quit)
+	ix.wg.Wait()
+
+	ix.mu.Lock()
+	ix.running = false
+	ix.mu.Unlock()
+}
+
+func (ix *Indexer) Status() IndexerStatus {
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+
+	return IndexerStatus{
+		Running:      ix.running,
+		LastBlock:    ix.lastBlock,
+		HeadBlock:    ix.headBlock,
+		LastSync:     ix.lastSync,
+		LastError:    ix.lastError,
+		Sponsorships: ix.sponsorshipList(),
+	}
+}
+
+// CaughtUp reports whether the indexer has synced up to the head block it last saw.
+func (ix *Indexer) CaughtUp() bool {
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+	return ix.running && ix.headBlock > 0 && ix.lastBlock >= ix.headBlock
+}
+
+// Events returns the indexed events matching filter in chain order.
+func (ix *Indexer) Events(filter EventFilter) ([]IndexedEvent, error) {
+	return ListEvents(ix.store, ix.tm.contractAddr, filter)
+}
+
+// ListEvents reads the events indexed for an operator contract straight from the store, so they can
+// be queried without a running Indexer (e.g. from the command line).
+func ListEvents(store *Store, operatorAddr ethcommon.Address, filter EventFilter) ([]IndexedEvent, error) {
+	names := make(map[string]bool)
+	for _, name := range filter.Names {
+		names[name] = true
+	}
+
+	events := []IndexedEvent{}
+	err := store.ForEach(indexerPrefix(operatorAddr)+"evt/", func(key string, value []byte) error {
+		var event IndexedEvent
+		if err := json.Unmarshal(value, &event); err != nil {
+			return err
+		}
+		if len(names) > 0 && !names[event.Name] {
+			return nil
+		}
+		if filter.Contract != (ethcommon.Address{}) && event.Contract != filter.Contract {
+			return nil
+		}
+		if filter.TxHash != (ethcommon.Hash{}) && event.TxHash != filter.TxHash {
+			return nil
+		}
+		if !filter.From.IsZero() && event.Timestamp < uint64(filter.From.Unix()) {
+			return nil
+		}
+		if !filter.To.IsZero() && event.Timestamp > uint64(filter.To.Unix()) {
+			return nil
+		}
+		events = append(events, event)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if filter.Limit > 0 && len(events) > filter.Limit {
+		events = events[len(events)-filter.Limit:]
+	}
+	return events, nil
+}
+
+func (ix *Indexer) run() {
+	defer ix.wg.Done()
+
+	ticker := time.NewTicker(ix.config.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		err := ix.sync()
+		ix.mu.Lock()
+		if err != nil {
+			log.Printf("Indexer: sync failed: %v", err)
+			ix.lastError = err.Error()
+		} else {
+			ix.lastError = ""
+		}
+		ix.mu.Unlock()
+
+		select {
+		case <-ix.quit:
+			return
+		case <-ticker.C:
+		case <-ix.trigger:
+		}
+	}
+}
+
+// subscribe wakes up the sync loop whenever a log for one of the watched contracts arrives.
+// Plain HTTP endpoints do not support subscriptions, in which case the indexer only polls.
+func (ix *Indexer) subscribe() {
+	defer ix.wg.Done()
+
+	for {
+		logs := make(chan types.Log, 64)
+		query := ethereum.FilterQuery{Addresses: ix.addresses()}
+		sub, err := ix.tm.client.SubscribeFilterLogs(context.Background(), query, logs)
+		if err != nil {
+			log.Printf("Indexer: log subscription unavailable, polling every %s: %v", ix.config.PollInterval, err)
+			return
+		}
+
+		resubscribe := func() bool {
+			defer sub.Unsubscribe()
+			for {
+				select {
+				case <-ix.quit:
+					return false
+				case err := <-sub.Err():
+					log.Printf("Indexer: log subscription dropped: %v", err)
+					time.Sleep(ix.config.PollInterval)
+					return true
+				case <-ix.sponsorshipsChanged:
+					return true
+				case <-logs:
+					// removed logs (reorgs) are handled by the sync loop, which re-checks block hashes
+					select {
+					case ix.trigger <- struct{}{}:
+					default:
+					}
+				}
+			}
+		}()
+		if !resubscribe {
+			return
+		}
+	}
+}
+
+func (ix *Indexer) sync() error {
+	ctx := context.Background()
+
+	if err := ix.checkReorg(ctx); err != nil {
+		return err
+	}
+
+	head, err := ix.tm.client.BlockNumber(ctx)
+	if err != nil {
+		return err
+	}
+
+	ix.mu.Lock()
+	ix.headBlock = head
+	from := ix.lastBlock + 1
+	ix.mu.Unlock()
+
+	batchSize := ix.config.BatchSize
+	for from <= head {
+		select {
+		case <-ix.quit:
+			return nil
+		default:
+		}
+
+		to := from + batchSize - 1
+		if to > head {
+			to = head
+		}
+
+		err := ix.indexRange(ctx, from, to)
+		if err != nil {
+			// most RPC providers cap the range or result size of eth_getLogs, so retry with a smaller range
+			if batchSize > 1 {
+				batchSize /= 2
+				continue
+			}
+			return err
+		}
+		from = to + 1
+	}
+
+	ix.mu.Lock()
+	ix.lastSync = time.Now()
+	ix.mu.Unlock()
+	return nil
+}
+
+func (ix *Indexer) indexRange(ctx context.Context, from uint64, to uint64) error {
+	operatorLogs, err := ix.tm.client.FilterLogs(ctx, ethereum.FilterQuery{
+		FromBlock: new(big.Int).SetUint64(from),
+		ToBlock:   new(big.Int).SetUint64(to),
+		Addresses: []ethcommon.Address{ix.tm.contractAddr},
+	})
+	if err != nil {
+		return err
+	}
+
+	events := []IndexedEvent{}
+	newSponsorships := false
+	for _, lg := range operatorLogs {
+		event, ok := ix.decodeLog(ix.tm.contractAbi, lg)
+		if !ok {
+			continue
+		}
+		if event.Name == "Staked" {
+			addr := ethcommon.HexToAddress(event.Args["sponsorship"])
+			ix.mu.Lock()
+			if !ix.sponsorships[addr] {
+				ix.sponsorships[addr] = true
+				newSponsorships = true
+			}
+			ix.mu.Unlock()
+		}
+		events = append(events, event)
+	}
+
+	sponsorships := ix.addresses()[1:]
+	if len(sponsorships) > 0 {
+		sponsorshipLogs, err := ix.tm.client.FilterLogs(ctx, ethereum.FilterQuery{
+			FromBlock: new(big.Int).SetUint64(from),
+			ToBlock:   new(big.Int).SetUint64(to),
+			Addresses: sponsorships,
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, lg := range sponsorshipLogs {
+			event, ok := ix.decodeLog(SponsorshipAbi, lg)
+			if !ok {
+				continue
+			}
+			// sponsorships emit per-operator events for everyone staked in them, keep only ours
+			if operator, ok := event.Args["operator"]; ok && ethcommon.HexToAddress(operator) != ix.tm.contractAddr {
+				continue
+			}
+			events = append(events, event)
+		}
+	}
+
+	timestamps := make(map[uint64]uint64)
+	for i := range events {
+		ts, ok := timestamps[events[i].BlockNumber]
+		if !ok {
+			header, err := ix.tm.client.HeaderByNumber(ctx, new(big.Int).SetUint64(events[i].BlockNumber))
+			if err != nil {
+				return err
+			}
+			ts = header.Time
+			timestamps[events[i].BlockNumber] = ts
+		}
+		events[i].Timestamp = ts
+	}
+
+	header, err := ix.tm.client.HeaderByNumber(ctx, new(big.Int).SetUint64(to))
+	if err != nil {
+		return err
+	}
+
+	batch := ix.store.NewBatch()
+	for _, event := range events {
+		if err := batch.Put(ix.eventKey(event.BlockNumber, event.LogIndex), event); err != nil {
+			return err
+		}
+	}
+	if err := batch.Put(ix.checkpointKey(to), blockCheckpoint{Number: to, Hash: header.Hash()}); err != nil {
+		return err
+	}
+	if err := batch.Put(ix.prefix+"sponsorships", ix.addresses()[1:]); err != nil {
+		return err
+	}
+	if err := batch.Put(ix.prefix+"cursor", to); err != nil {
+		return err
+	}
+	if err := ix.store.Write(batch); err != nil {
+		return err
+	}
+
+	if to > ix.config.ReorgDepth {
+		if err := ix.store.DeleteRange(ix.prefix+"block/", ix.checkpointKey(to-ix.config.ReorgDepth)); err != nil {
+			return err
+		}
+	}
+
+	ix.mu.Lock()
+	ix.lastBlock = to
+	ix.mu.Unlock()
+
+	if len(events) > 0 {
+		log.Printf("Indexer: indexed %d events in blocks %d-%d", len(events), from, to)
+	}
+
+	if newSponsorships {
+		select {
+		case ix.sponsorshipsChanged <- struct{}{}:
+		default:
+		}
+	}
+	return nil
+}
+
+// checkReorg compares the stored block hashes with the canonical chain, newest first, and rolls
+// the index back to the newest block that is still canonical.
+func (ix *Indexer) checkReorg(ctx context.Context) error {
+	checkpoints := []blockCheckpoint{}
+	err := ix.store.ForEach(ix.prefix+"block/", func(key string, value []byte) error {
+		var cp blockCheckpoint
+		if err := json.Unmarshal(value, &cp); err != nil {
+			return err
+		}
+		checkpoints = append(checkpoints, cp)
+		return nil
+	})
+	if err != nil || len(checkpoints) == 0 {
+		return err
+	}
+
+	for i := len(checkpoints) - 1; i >= 0; i-- {
+		header, err := ix.tm.client.HeaderByNumber(ctx, new(big.Int).SetUint64(checkpoints[i].Number))
+		if err != nil {
+			return err
+		}
+		if header.Hash() == checkpoints[i].Hash {
+			if i == len(checkpoints)-1 {
+				return nil
+			}
+			return ix.rollback(checkpoints[i].Number)
+		}
+	}
+
+	// the reorg is deeper than the kept history, start over from before the oldest checkpoint
+	if checkpoints[0].Number < ix.config.ReorgDepth {
+		return ix.rollback(0)
+	}
+	return ix.rollback(checkpoints[0].Number - ix.config.ReorgDepth)
+}
+
+// rollback removes all events and checkpoints after block and resumes indexing from there.
+func (ix *Indexer) rollback(block uint64) error {
+	log.Printf("Indexer: chain reorganisation detected, rolling back to block %d", block)
+
+	if err := ix.store.DeleteRange(ix.eventKey(block+1, 0), ix.prefix+"evt/~"); err != nil {
+		return err
+	}
+	if err := ix.store.DeleteRange(ix.checkpointKey(block+1), ix.prefix+"block/~"); err != nil {
+		return err
+	}
+	if err := ix.store.Put(ix.prefix+"cursor", block); err != nil {
+		return err
+	}
+
+	ix.mu.Lock()
+	ix.lastBlock = block
+	ix.mu.Unlock()
+	return nil
+}
+
+// findDeploymentBlock binary searches for the first block that has code at the operator address.
+func (ix *Indexer) findDeploymentBlock() (uint64, error) {
+	ctx := context.Background()
+	head, err := ix.tm.client.BlockNumber(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	lo, hi := uint64(0), head
+	for lo < hi {
+		mid := (lo + hi) / 2
+		code, err := ix.tm.client.CodeAt(ctx, ix.tm.contractAddr, new(big.Int).SetUint64(mid))
+		if err != nil {
+			// pruned nodes cannot serve historical state, fall back to indexing from the current head
+			log.Printf("Indexer: cannot look up deployment block, starting at head %d: %v", head, err)
+			return head, nil
+		}
+		if len(code) > 0 {
+			hi = mid
+		} else {
+			lo = mid + 1
+		}
+	}
+	return lo, nil
+}
+
+func (ix *Indexer) decodeLog(contractAbi abi.ABI, lg types.Log) (IndexedEvent, bool) {
+	if len(lg.Topics) == 0 {
+		return IndexedEvent{}, false
+	}
+	event, err := contractAbi.EventByID(lg.Topics[0])
+	if err != nil {
+		// events missing from the ABI are not of interest
+		return IndexedEvent{}, false
+	}
+
+	values := make(map[string]interface{})
+	if len(lg.Data) > 0 {
+		if err := event.Inputs.UnpackIntoMap(values, lg.Data); err != nil {
+			log.Printf("Indexer: failed to unpack %s in tx %s: %v", event.Name, lg.TxHash.Hex(), err)
+			return IndexedEvent{}, false
+		}
+	}
+	var indexed abi.Arguments
+	for _, input := range event.Inputs {
+		if input.Indexed {
+			indexed = append(indexed, input)
+		}
+	}
+	if err := abi.ParseTopicsIntoMap(values, indexed, lg.Topics[1:]); err != nil {
+		log.Printf("Indexer: failed to parse topics of %s in tx %s: %v", event.Name, lg.TxHash.Hex(), err)
+		return IndexedEvent{}, false
+	}
+
+	args := make(map[string]string)
+	for name, value := range values {
+		args[name] = formatArg(value)
+	}
+
+	return IndexedEvent{
+		Contract:    lg.Address,
+		Name:        event.Name,
+		BlockNumber: lg.BlockNumber,
+		BlockHash:   lg.BlockHash,
+		TxHash:      lg.TxHash,
+		LogIndex:    lg.Index,
+		Args:        args,
+	}, true
+}
+
+// addresses returns the operator contract followed by all known sponsorships.
+func (ix *Indexer) addresses() []ethcommon.Address {
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+	return append([]ethcommon.Address{ix.tm.contractAddr}, ix.sponsorshipList()...)
+}
+
+func (ix *Indexer) sponsorshipList() []ethcommon.Address {
+	list := []ethcommon.Address{}
+	for addr := range ix.sponsorships {
+		list = append(list, addr)
+	}
+	return list
+}
+
+func indexerPrefix(operatorAddr ethcommon.Address) string {
+	return fmt.Sprintf("idx/%s/", strings.ToLower(operatorAddr.Hex()))
+}
+
+func (ix *Indexer) eventKey(block uint64, logIndex uint) string {
+	return fmt.Sprintf("%sevt/%020d/%06d", ix.prefix, block, logIndex)
+}
+
+func (ix *Indexer) checkpointKey(block uint64) string {
+	return fmt.Sprintf("%sblock/%020d", ix.prefix, block)
+}
+
+func formatArg(value interface{}) string {
+	switch v := value.(type) {
+	case *big.Int:
+		return v.String()
+	case ethcommon.Address:
+		return v.Hex()
+	case []ethcommon.Address:
+		addrs := make([]string, len(v))
+		for i, addr := range v {
+			addrs[i] = addr.Hex()
+		}
+		return strings.Join(addrs, ",")
+	case ethcommon.Hash:
+		return v.Hex()
+	case [32]byte:
+		return "0x" + hex.EncodeToString(v[:])
+	case []byte:
+		return "0x" + hex.EncodeToString(v)
+	default:
+		return fmt.Sprint(v)
+	}
+}
@@ -0,0 +1,261 @@
+package blockchain
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math/big"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"streamr_api/metrics"
+
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+const (
+	TxPending = "pending"
+	TxMined   = "mined"
+	TxFailed  = "failed"
+	TxDropped = "dropped"
+)
+
+// JournalEntry records a transaction sent by the TxManager and, once mined, its outcome and gas cost.
+type JournalEntry struct {
+	Hash        string    `json:"hash"`
+	Method      string    `json:"method"`
+	To          string    `json:"to"`
+	Params      []string  `json:"params"`
+	Nonce       uint64    `json:"nonce"`
+	SentAt      time.Time `json:"sentAt"`
+	Status      string    `json:"status"`
+	GasPriceWei *big.Int  `json:"gasPriceWei"`
+	BlockNumber uint64    `json:"blockNumber,omitempty"`
+	MinedAt     time.Time `json:"minedAt,omitempty"`
+	GasUsed     uint64    `json:"gasUsed,omitempty"`
+	GasCostWei  *big.Int  `json:"gasCostWei,omitempty"`
+}
+
+// TxJournal persists the transactions sent for one contract in the Store.
+type TxJournal struct {
+	store  *Store
+	prefix string
+	mu     sync.Mutex
+}
+
+func NewTxJournal(store *Store, contractAddr ethcommon.Address) *TxJournal {
+	return &TxJournal{
+		store:  store,
+		prefix: fmt.Sprintf("journal/%s/", strings.ToLower(contractAddr.Hex())),
+	}
+}
+
+func (j *TxJournal) Record(entry JournalEntry) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.store.Put(j.prefix+entry.Hash, entry)
+}
+
+func (j *TxJournal) Get(hash string) (JournalEntry, bool, error) {
+	var entry JournalEntry
+	found, err := j.store.Get(j.prefix+hash, &entry)
+	return entry, found, err
+}
+
+// Update applies fn to the entry with the given hash and stores the result.
+func (j *TxJournal) Update(hash string, fn func(entry *JournalEntry)) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	var entry JournalEntry
+	found, err := j.store.Get(j.prefix+hash, &entry)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return fmt.Errorf("transaction %s not found in journal", hash)
+	}
+	fn(&entry)
+	return j.store.Put(j.prefix+hash, entry)
+}
+
+// List returns the entries sent in [from, to], oldest first. Zero times mean no bound.
+func (j *TxJournal) List(from time.Time, to time.Time) ([]JournalEntry, error) {
+	entries := []JournalEntry{}
+	err := j.store.ForEach(j.prefix, func(key string, value []byte) error {
+		var entry JournalEntry
+		if err := json.Unmarshal(value, &entry); err != nil {
+			return err
+		}
+		if !from.IsZero() && entry.SentAt.Before(from) {
+			return nil
+		}
+		if !to.IsZero() && entry.SentAt.After(to) {
+			return nil
+		}
+		entries = append(entries, entry)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(entries, func(a, b int) bool { return entries[a].SentAt.Before(entries[b].SentAt) })
+	return entries, nil
+}
+
+func (j *TxJournal) Pending() ([]JournalEntry, error) {
+	entries, err := j.List(time.Time{}, time.Time{})
+	if err != nil {
+		return nil, err
+	}
+
+	pending := []JournalEntry{}
+	for _, entry := range entries {
+		if entry.Status == TxPending {
+			pending = append(pending, entry)
+		}
+	}
+	return pending, nil
+}
+
+// SetJournal makes the TxManager record every transaction it sends in journal, and starts
+// a background loop that fills in the receipts of pending transactions.
+func (tm *TxManager) SetJournal(journal *TxJournal) {
+	tm.journal = journal
+	tm.journalQuit = make(chan struct{})
+
+	tm.journalWg.Add(1)
+	go func() {
+		defer tm.journalWg.Done()
+		ticker := time.NewTicker(30 * time.Second)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-tm.journalQuit:
+				return
+			case <-ticker.C:
+			}
+			if err := tm.ReconcileJournal(); err != nil {
+				log.Printf("Failed to reconcile tx journal: %v", err)
+			}
+		}
+	}()
+}
+
+// CloseJournal stops reconciling the journal in the background, then reconciles the pending
+// entries every few seconds until none is left or ctx is done. It returns the entries still pending.
+func (tm *TxManager) CloseJournal(ctx context.Context) ([]JournalEntry, error) {
+	if tm.journal == nil {
+		return nil, nil
+	}
+	close(tm.journalQuit)
+	tm.journalWg.Wait()
+
+	ticker := time.NewTicker(5 * time.Second)
+	defer ticker.Stop()
+	for {
+		if err := tm.ReconcileJournal(); err != nil {
+			return nil, err
+		}
+		pending, err := tm.journal.Pending()
+		if err != nil || len(pending) == 0 {
+			return pending, err
+		}
+
+		select {
+		case <-ctx.Done():
+			return pending, nil
+		case <-ticker.C:
+		}
+	}
+}
+
+func (tm *TxManager) Journal() *TxJournal {
+	return tm.journal
+}
+
+// ReconcileJournal looks up the receipts of all pending journal entries.
+func (tm *TxManager) ReconcileJournal() error {
+	if tm.journal == nil {
+		return nil
+	}
+
+	pending, err := tm.journal.Pending()
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range pending {
+		if err := tm.reconcileEntry(entry); err != nil {
+			log.Printf("Failed to reconcile transaction %s: %v", entry.Hash, err)
+		}
+	}
+	return nil
+}
+
+func (tm *TxManager) reconcileEntry(entry JournalEntry) error {
+	ctx := context.Background()
+	receipt, err := tm.client.TransactionReceipt(ctx, ethcommon.HexToHash(entry.Hash))
+	if err != nil {
+		// a transaction that is still unknown after its nonce was used has been replaced or dropped
+		if time.Since(entry.SentAt) > 30*time.Minute {
+			nonce, nonceErr := tm.client.NonceAt(ctx, tm.fromAddress(), nil)
+			if nonceErr == nil && nonce > entry.Nonce {
+				return tm.journal.Update(entry.Hash, func(e *JournalEntry) {
+					if e.Status == TxPending {
+						metrics.Transactions.WithLabelValues(e.Method, "dropped").Inc()
+					}
+					e.Status = TxDropped
+				})
+			}
+		}
+		return nil
+	}
+
+	header, err := tm.client.HeaderByNumber(ctx, receipt.BlockNumber)
+	if err != nil {
+		return err
+	}
+
+	return tm.journal.Update(entry.Hash, func(e *JournalEntry) {
+		pending := e.Status == TxPending
+		applyReceipt(e, receipt, header.Time)
+		if pending {
+			observeReceipt(e)
+		}
+	})
+}
+
+func applyReceipt(entry *JournalEntry, receipt *types.Receipt, blockTime uint64) {
+	entry.Status = TxMined
+	if receipt.Status == types.ReceiptStatusFailed {
+		entry.Status = TxFailed
+	}
+	entry.BlockNumber = receipt.BlockNumber.Uint64()
+	entry.MinedAt = time.Unix(int64(blockTime), 0).UTC()
+	entry.GasUsed = receipt.GasUsed
+
+	gasPrice := receipt.EffectiveGasPrice
+	if gasPrice == nil {
+		gasPrice = entry.GasPriceWei
+	}
+	if gasPrice != nil {
+		entry.GasCostWei = new(big.Int).Mul(gasPrice, new(big.Int).SetUint64(receipt.GasUsed))
+	}
+}
+
+// observeReceipt counts a transaction that was just found mined in the metrics.
+func observeReceipt(entry *JournalEntry) {
+	outcome := "mined"
+	if entry.Status == TxFailed {
+		outcome = "reverted"
+	}
+	metrics.Transactions.WithLabelValues(entry.Method, outcome).Inc()
+	metrics.GasUsed.WithLabelValues(entry.Method).Observe(float64(entry.GasUsed))
+	metrics.GasSpent.WithLabelValues(entry.Method).Add(metrics.Float(entry.GasCostWei))
+}
@@ -0,0 +1,63 @@
+package blockchain
+
+import (
+	"context"
+	"sync"
+
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+// RPCPool shares RPC clients and nonces between the TxManagers of the operators run by one service.
+// Operators on the same RPC endpoint use one client, and operators signing with the same key draw
+// their nonces from one sequence, so their transactions never take the same nonce.
+type RPCPool struct {
+	mu      sync.Mutex
+	clients map[string]*ethclient.Client
+	nonces  map[ethcommon.Address]chan uint64
+}
+
+// DefaultRPCPool is the pool the TxManagers are created from.
+var DefaultRPCPool = NewRPCPool()
+
+func NewRPCPool() *RPCPool {
+	return &RPCPool{
+		clients: make(map[string]*ethclient.Client),
+		nonces:  make(map[ethcommon.Address]chan uint64),
+	}
+}
+
+// Client returns the client for url, dialing it the first time.
+func (p *RPCPool) Client(url string) (*ethclient.Client, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if client, ok := p.clients[url]; ok {
+		return client, nil
+	}
+	client, err := dialClient(url)
+	if err != nil {
+		return nil, err
+	}
+	p.clients[url] = client
+	return client, nil
+}
+
+// nonce returns the nonce sequence of a signer, starting it from the pending nonce the first time.
+// Senders take the next nonce from the channel and put it back once the transaction was sent.
+func (p *RPCPool) nonce(client *ethclient.Client, signer ethcommon.Address) (chan uint64, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if nonce, ok := p.nonces[signer]; ok {
+		return nonce, nil
+	}
+	pending, err := client.PendingNonceAt(context.Background(), signer)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make(chan uint64, 1)
+	nonce <- pending
+	p.nonces[signer] = nonce
+	return nonce, nil
+}
@@ -0,0 +1,70 @@
+package blockchain
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"time"
+
+	"streamr_api/metrics"
+
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// dialClient connects to the node. Requests over HTTP are counted and timed per JSON-RPC method.
+func dialClient(url string) (*ethclient.Client, error) {
+	httpClient := &http.Client{Transport: &rpcMetricsTransport{next: http.DefaultTransport}}
+	client, err := rpc.DialOptions(context.Background(), url, rpc.WithHTTPClient(httpClient))
+	if err != nil {
+		return nil, err
+	}
+	return ethclient.NewClient(client), nil
+}
+
+type rpcMetricsTransport struct {
+	next http.RoundTripper
+}
+
+func (t *rpcMetricsTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	method := "unknown"
+	if req.Body != nil && req.GetBody != nil {
+		if body, err := req.GetBody(); err == nil {
+			method = rpcMethod(body)
+			body.Close()
+		}
+	}
+
+	start := time.Now()
+	resp, err := t.next.RoundTrip(req)
+	metrics.RPCDuration.WithLabelValues(method).Observe(time.Since(start).Seconds())
+
+	outcome := "ok"
+	if err != nil || resp.StatusCode >= 400 {
+		outcome = "error"
+	}
+	metrics.RPCCalls.WithLabelValues(method, outcome).Inc()
+	return resp, err
+}
+
+// rpcMethod reads the JSON-RPC method of a request body. Batches are labeled "batch".
+func rpcMethod(body io.Reader) string {
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return "unknown"
+	}
+	data = bytes.TrimSpace(data)
+	if len(data) > 0 && data[0] == '[' {
+		return "batch"
+	}
+
+	var msg struct {
+		Method string `json:"method"`
+	}
+	if err := json.Unmarshal(data, &msg); err != nil || msg.Method == "" {
+		return "unknown"
+	}
+	return msg.Method
+}
@@ -0,0 +1,107 @@
+package blockchain
+
+import (
+	"encoding/json"
+
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/util"
+)
+
+// Store is a small JSON document store on top of LevelDB. Keys are plain strings and are
+// namespaced by the caller (e.g. "idx/<operator>/...") so several services can share one database.
+type Store struct {
+	db *leveldb.DB
+}
+
+// StoreBatch collects writes that are applied atomically by Store.Write.
+type StoreBatch struct {
+	batch *leveldb.Batch
+}
+
+func OpenStore(path string) (*Store, error) {
+	db, err := leveldb.OpenFile(path, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &Store{db: db}, nil
+}
+
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// Put stores value under key as JSON.
+func (s *Store) Put(key string, value interface{}) error {
+	bytes, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	return s.db.Put([]byte(key), bytes, nil)
+}
+
+// Get loads the JSON value stored under key into value. It returns false if the key does not exist.
+func (s *Store) Get(key string, value interface{}) (bool, error) {
+	bytes, err := s.db.Get([]byte(key), nil)
+	if err == leveldb.ErrNotFound {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, json.Unmarshal(bytes, value)
+}
+
+func (s *Store) Delete(key string) error {
+	return s.db.Delete([]byte(key), nil)
+}
+
+// ForEach calls fn for every key with the given prefix, in key order. Iteration stops at the first error.
+func (s *Store) ForEach(prefix string, fn func(key string, value []byte) error) error {
+	iter := s.db.NewIterator(util.BytesPrefix([]byte(prefix)), nil)
+	defer iter.Release()
+
+	for iter.Next() {
+		// the iterator reuses its buffers, so copy the value before handing it out
+		value := append([]byte{}, iter.Value()...)
+		if err := fn(string(iter.Key()), value); err != nil {
+			return err
+		}
+	}
+	return iter.Error()
+}
+
+// DeleteRange removes every key in [start, limit).
+func (s *Store) DeleteRange(start string, limit string) error {
+	batch := new(leveldb.Batch)
+	iter := s.db.NewIterator(&util.Range{Start: []byte(start), Limit: []byte(limit)}, nil)
+	for iter.Next() {
+		batch.Delete(append([]byte{}, iter.Key()...))
+	}
+	iter.Release()
+	if err := iter.Error(); err != nil {
+		return err
+	}
+	return s.db.Write(batch, nil)
+}
+
+func (s *Store) NewBatch() *StoreBatch {
+	return &StoreBatch{batch: new(leveldb.Batch)}
+}
+
+func (b *StoreBatch) Put(key string, value interface{}) error {
+	bytes, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	b.batch.Put([]byte(key), bytes)
+	return nil
+}
+
+func (b *StoreBatch) Delete(key string) {
+	b.batch.Delete([]byte(key))
+}
+
+// Write applies all writes collected in the batch atomically.
+func (s *Store) Write(b *StoreBatch) error {
+	return s.db.Write(b.batch, nil)
+}
@@ -0,0 +1,145 @@
+package blockchain
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// SubgraphClient queries a Streamr network subgraph over GraphQL. Any server answering the same
+// queries works, e.g. a local fake for testing.
+type SubgraphClient struct {
+	url    string
+	client *http.Client
+}
+
+// SubgraphSponsorship is a sponsorship as the subgraph indexes it. BigInt fields come as decimal
+// strings and are empty when the subgraph has no value.
+type SubgraphSponsorship struct {
+	ID     string `json:"id"`
+	Stream *struct {
+		ID string `json:"id"`
+	} `json:"stream"`
+	IsRunning                   bool   `json:"isRunning"`
+	TotalPayoutWeiPerSec        string `json:"totalPayoutWeiPerSec"`
+	TotalStakedWei              string `json:"totalStakedWei"`
+	RemainingWei                string `json:"remainingWei"`
+	OperatorCount               int    `json:"operatorCount"`
+	MaxOperators                *int   `json:"maxOperators"`
+	ProjectedInsolvency         string `json:"projectedInsolvency"`
+	MinimumStakingPeriodSeconds string `json:"minimumStakingPeriodSeconds"`
+}
+
+type subgraphRequest struct {
+	Query     string                 `json:"query"`
+	Variables map[string]interface{} `json:"variables,omitempty"`
+}
+
+type subgraphResponse struct {
+	Data   json.RawMessage `json:"data"`
+	Errors []struct {
+		Message string `json:"message"`
+	} `json:"errors"`
+}
+
+const sponsorshipsQuery = `query($first: Int!) {
+  sponsorships(first: $first, where: {remainingWei_gt: "0"}, orderBy: totalPayoutWeiPerSec, orderDirection: desc) {
+    id
+    stream { id }
+    isRunning
+    totalPayoutWeiPerSec
+    totalStakedWei
+    remainingWei
+    operatorCount
+    maxOperators
+    projectedInsolvency
+    minimumStakingPeriodSeconds
+  }
+}`
+
+const sponsorshipQuery = `query($id: ID!) {
+  sponsorship(id: $id) {
+    id
+    stream { id }
+    isRunning
+    totalPayoutWeiPerSec
+    totalStakedWei
+    remainingWei
+    operatorCount
+    maxOperators
+    projectedInsolvency
+    minimumStakingPeriodSeconds
+  }
+}`
+
+func NewSubgraphClient(url string, timeout time.Duration) *SubgraphClient {
+	return &SubgraphClient{url: url, client: &http.Client{Timeout: timeout}}
+}
+
+// Query runs a GraphQL query and decodes its data into result.
+func (s *SubgraphClient) Query(ctx context.Context, query string, variables map[string]interface{}, result interface{}) error {
+	body, err := json.Marshal(subgraphRequest{Query: query, Variables: variables})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode >= 400 {
+		return fmt.Errorf("subgraph responded with status %d: %s", resp.StatusCode, strings.TrimSpace(string(respBody)))
+	}
+
+	var response subgraphResponse
+	if err := json.Unmarshal(respBody, &response); err != nil {
+		return err
+	}
+	if len(response.Errors) > 0 {
+		messages := make([]string, len(response.Errors))
+		for i, e := range response.Errors {
+			messages[i] = e.Message
+		}
+		return fmt.Errorf("subgraph query failed: %s", strings.Join(messages, "; "))
+	}
+	return json.Unmarshal(response.Data, result)
+}
+
+// Sponsorships returns up to first funded sponsorships, the best paying first.
+func (s *SubgraphClient) Sponsorships(ctx context.Context, first int) ([]SubgraphSponsorship, error) {
+	var result struct {
+		Sponsorships []SubgraphSponsorship `json:"sponsorships"`
+	}
+	if err := s.Query(ctx, sponsorshipsQuery, map[string]interface{}{"first": first}, &result); err != nil {
+		return nil, err
+	}
+	return result.Sponsorships, nil
+}
+
+// Sponsorship returns a single sponsorship, or nil if the subgraph doesn't know it.
+func (s *SubgraphClient) Sponsorship(ctx context.Context, id string) (*SubgraphSponsorship, error) {
+	var result struct {
+		Sponsorship *SubgraphSponsorship `json:"sponsorship"`
+	}
+	// the subgraph uses lower case hex ids
+	if err := s.Query(ctx, sponsorshipQuery, map[string]interface{}{"id": strings.ToLower(id)}, &result); err != nil {
+		return nil, err
+	}
+	return result.Sponsorship, nil
+}
@@ -0,0 +1,88 @@
+package blockchain
+
+import (
+	"fmt"
+	"math/big"
+
+	ethcommon "github.com/ethereum/go-ethereum/common"
+)
+
+// Token is an ERC-20 token with the ERC-677 transferAndCall, such as DATA. Transactions are signed
+// by the TxManager's key, so approvals and transfers are made from the signing wallet.
+type Token struct {
+	tm      *TxManager
+	Address ethcommon.Address
+}
+
+func NewToken(tm *TxManager, address ethcommon.Address) *Token {
+	return &Token{tm: tm, Address: address}
+}
+
+func (t *Token) BalanceOf(account ethcommon.Address) (*big.Int, error) {
+	return t.uint("balanceOf", account)
+}
+
+// Allowance returns how much spender may still transfer from owner.
+func (t *Token) Allowance(owner ethcommon.Address, spender ethcommon.Address) (*big.Int, error) {
+	return t.uint("allowance", owner, spender)
+}
+
+func (t *Token) TotalSupply() (*big.Int, error) {
+	return t.uint("totalSupply")
+}
+
+func (t *Token) Symbol() (string, error) {
+	result, err := t.tm.ContractCallAt(t.Address, ERC20Abi, "symbol", []interface{}{})
+	if err != nil {
+		return "", err
+	}
+
+	symbol, ok := result[0].(string)
+	if !ok {
+		return "", fmt.Errorf("unexpected symbol result: %v", result[0])
+	}
+	return symbol, nil
+}
+
+func (t *Token) Decimals() (uint8, error) {
+	result, err := t.tm.ContractCallAt(t.Address, ERC20Abi, "decimals", []interface{}{})
+	if err != nil {
+		return 0, err
+	}
+
+	decimals, ok := result[0].(uint8)
+	if !ok {
+		return 0, fmt.Errorf("unexpected decimals result: %v", result[0])
+	}
+	return decimals, nil
+}
+
+// Approve lets spender transfer up to amount from the signing wallet. Zero revokes the approval.
+func (t *Token) Approve(spender ethcommon.Address, amount *big.Int) (string, error) {
+	return t.tm.ContractSendTxAt(t.Address, ERC20Abi, "approve", []interface{}{spender, amount})
+}
+
+// TransferAndCall transfers amount from the signing wallet to a contract and calls its
+// onTokenTransfer with data in the same transaction.
+func (t *Token) TransferAndCall(to ethcommon.Address, amount *big.Int, data []byte) (string, error) {
+	if data == nil {
+		data = []byte{}
+	}
+	return t.tm.ContractSendTxAt(t.Address, ERC20Abi, "transferAndCall", []interface{}{to, amount, data})
+}
+
+func (t *Token) uint(method string, params ...interface{}) (*big.Int, error) {
+	if params == nil {
+		params = []interface{}{}
+	}
+	result, err := t.tm.ContractCallAt(t.Address, ERC20Abi, method, params)
+	if err != nil {
+		return nil, err
+	}
+
+	value, ok := result[0].(*big.Int)
+	if !ok {
+		return nil, fmt.Errorf("unexpected %s result: %v", method, result[0])
+	}
+	return value, nil
+}
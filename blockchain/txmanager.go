@@ -8,9 +8,10 @@ import (
 	"fmt"
 	"log"
 	"math/big"
+	"sync"
 	"time"
 
-	"streamr_api/common"
+	"streamr_api/metrics"
 
 	"github.com/ethereum/go-ethereum"
 	"github.com/ethereum/go-ethereum/accounts/abi"
@@ -29,12 +30,17 @@ type TxManager struct {
 
 	nonce               chan uint64
 	sendContractTxQueue chan types.Transaction
+	journal             *TxJournal
+	journalQuit         chan struct{}
+	journalWg           sync.WaitGroup
 }
 
-func NewTxManager(privateKey *ecdsa.PrivateKey, contractAddr ethcommon.Address, contractAbi abi.ABI) (*TxManager, error) {
-	client, err := ethclient.Dial(common.GetStringEnvWithDefault("RPC_ADDR", "https://polygon-rpc.com"))
+// NewTxManagerAt creates a TxManager on the RPC endpoint at rpcURL. The client and the nonce
+// sequence of the signer come from DefaultRPCPool, so they are shared with other operators.
+func NewTxManagerAt(rpcURL string, privateKey *ecdsa.PrivateKey, contractAddr ethcommon.Address, contractAbi abi.ABI) (*TxManager, error) {
+	client, err := DefaultRPCPool.Client(rpcURL)
 	if err != nil {
-		panic(err)
+		return nil, err
 	}
 
 	publicKey := privateKey.Public()
@@ -44,50 +50,58 @@ func NewTxManager(privateKey *ecdsa.PrivateKey, contractAddr ethcommon.Address,
 	}
 
 	fromAddress := crypto.PubkeyToAddress(*publicKeyECDSA)
-	nonce, err := client.PendingNonceAt(context.Background(), fromAddress)
+	nonce, err := DefaultRPCPool.nonce(client, fromAddress)
 	if err != nil {
 		return nil, err
 	}
 
-	nChan := make(chan uint64, 1)
-	nChan <- nonce
 	tm := TxManager{
 		client:       client,
 		privateKey:   privateKey,
 		contractAddr: contractAddr,
 		contractAbi:  contractAbi,
 
-		nonce:               nChan,
+		nonce:               nonce,
 		sendContractTxQueue: make(chan types.Transaction, 1000),
 	}
 	return &tm, nil
 }
 
 func (tm *TxManager) ContractCall(method string, params []interface{}) ([]interface{}, error) {
+	result, err := tm.ContractCallAt(tm.contractAddr, tm.contractAbi, method, params)
+	if err != nil {
+		return nil, err
+	}
+
+	fmt.Println("Result:", result)
+	return result, nil
+}
+
+// ContractCallAt calls a view method of a contract other than the operator, e.g. a sponsorship.
+func (tm *TxManager) ContractCallAt(contractAddr ethcommon.Address, contractAbi abi.ABI, method string, params []interface{}) ([]interface{}, error) {
 	// Creating a call message
-	data, err := tm.contractAbi.Pack(method, params...)
+	data, err := contractAbi.Pack(method, params...)
 	if err != nil {
 		return nil, err
 	}
 
 	callMsg := ethereum.CallMsg{
-		To:   &tm.contractAddr,
+		To:   &contractAddr,
 		Data: data,
 	}
 
 	output, err := tm.client.CallContract(context.Background(), callMsg, nil)
 	if err != nil {
-		log.Fatalf("Failed to execute contract call: %v", err)
+		log.Printf("Failed to execute contract call: %v", err)
 		return nil, err
 	}
 
-	result, err := tm.contractAbi.Unpack(method, output)
+	result, err := contractAbi.Unpack(method, output)
 	if err != nil {
-		log.Fatalf("Failed to unpack the output: %v", err)
+		log.Printf("Failed to unpack the output: %v", err)
 		return nil, err
 	}
 
-	fmt.Println("Result:", result)
 	return result, nil
 }
 
@@ -106,13 +120,13 @@ func (tm *TxManager) ContractCallSpecial(method string, params []interface{}) ([
 
 	output, err := tm.client.CallContract(context.Background(), callMsg, nil)
 	if err != nil {
-		log.Fatalf("Failed to execute contract call: %v", err)
+		log.Printf("Failed to execute contract call: %v", err)
 		return nil, err
 	}
 
 	result, err := tm.contractAbi.Unpack(method, output)
 	if err != nil {
-		log.Fatalf("Failed to unpack the output: %v", err)
+		log.Printf("Failed to unpack the output: %v", err)
 		return nil, err
 	}
 
@@ -136,6 +150,11 @@ func (tm *TxManager) ContractSendTxWithWait(method string, params []interface{},
 }
 
 func (tm *TxManager) ContractSendTx(method string, params []interface{}) (string, error) {
+	return tm.ContractSendTxAt(tm.contractAddr, tm.contractAbi, method, params)
+}
+
+// ContractSendTxAt sends a transaction to a contract other than the operator, e.g. the DATA token.
+func (tm *TxManager) ContractSendTxAt(contractAddr ethcommon.Address, contractAbi abi.ABI, method string, params []interface{}) (string, error) {
 	gasPrice, err := tm.client.SuggestGasPrice(context.Background())
 	if err != nil {
 		return "", err
@@ -161,13 +180,13 @@ func (tm *TxManager) ContractSendTx(method string, params []interface{}) (string
 	auth.GasLimit = uint64(3000000) // set the gas limit to a suitable value
 	auth.GasPrice = gasPrice
 	// Pack the data to send in the transaction
-	inputData, err := tm.contractAbi.Pack(method, params...)
+	inputData, err := contractAbi.Pack(method, params...)
 	if err != nil {
 		return "", err
 	}
 
 	// Create the transaction
-	tx := types.NewTransaction(auth.Nonce.Uint64(), tm.contractAddr, auth.Value, auth.GasLimit, auth.GasPrice, inputData)
+	tx := types.NewTransaction(auth.Nonce.Uint64(), contractAddr, auth.Value, auth.GasLimit, auth.GasPrice, inputData)
 
 	// Sign the transaction
 	signedTx, err := types.SignTx(tx, types.NewEIP155Signer(chainID), tm.privateKey)
@@ -178,15 +197,56 @@ func (tm *TxManager) ContractSendTx(method string, params []interface{}) (string
 	// Send the transaction
 	err = tm.client.SendTransaction(context.Background(), signedTx)
 	if err != nil {
+		metrics.Transactions.WithLabelValues(method, "rejected").Inc()
 		return "", err
 	}
+	metrics.Transactions.WithLabelValues(method, "sent").Inc()
 
 	nonce += 1
 	fmt.Printf("Transaction sent! TX Hash: %s\n", signedTx.Hash().Hex())
 
+	if tm.journal != nil {
+		entryParams := make([]string, len(params))
+		for i, param := range params {
+			entryParams[i] = formatArg(param)
+		}
+		err = tm.journal.Record(JournalEntry{
+			Hash:        signedTx.Hash().Hex(),
+			Method:      method,
+			To:          contractAddr.Hex(),
+			Params:      entryParams,
+			Nonce:       signedTx.Nonce(),
+			SentAt:      time.Now().UTC(),
+			Status:      TxPending,
+			GasPriceWei: gasPrice,
+		})
+		if err != nil {
+			log.Printf("Failed to record transaction %s in journal: %v", signedTx.Hash().Hex(), err)
+		}
+	}
+
 	return signedTx.Hash().Hex(), nil
 }
 
+func (tm *TxManager) fromAddress() ethcommon.Address {
+	return crypto.PubkeyToAddress(tm.privateKey.PublicKey)
+}
+
+// SignerAddress returns the account the transactions are signed and paid for by.
+func (tm *TxManager) SignerAddress() ethcommon.Address {
+	return tm.fromAddress()
+}
+
+// Receipt returns the receipt of a mined transaction.
+func (tm *TxManager) Receipt(txHash string) (*types.Receipt, error) {
+	return tm.client.TransactionReceipt(context.Background(), ethcommon.HexToHash(txHash))
+}
+
+// BalanceAt returns the POL balance of an account in wei.
+func (tm *TxManager) BalanceAt(addr ethcommon.Address) (*big.Int, error) {
+	return tm.client.BalanceAt(context.Background(), addr, nil)
+}
+
 func (tm *TxManager) PolygonWaitForTx(txHash string, duration time.Duration) (*types.Transaction, error) {
 	ticker := time.NewTicker(5 * time.Second)
 	defer ticker.Stop()
@@ -207,6 +267,13 @@ func (tm *TxManager) PolygonWaitForTx(txHash string, duration time.Duration) (*t
 
 			if !isPending {
 				fmt.Println("Transaction has been mined.")
+				if tm.journal != nil {
+					if entry, found, err := tm.journal.Get(txHash); err == nil && found {
+						if err := tm.reconcileEntry(entry); err != nil {
+							log.Printf("Failed to update journal for %s: %v", txHash, err)
+						}
+					}
+				}
 				return tx, nil // Transaction is not pending anymore, break the loop.
 			}
 		}
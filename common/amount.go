@@ -0,0 +1,143 @@
+package common
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+const dataDecimals = 18
+
+// Amount is an amount of DATA. It marshals to JSON as both the exact wei integer and the decimal
+// DATA value, e.g. {"wei": "1500250000000000000000", "data": "1500.25"}, so clients neither lose
+// precision to 64-bit numbers nor have to scale by 10^18 themselves.
+type Amount big.Int
+
+// AmountDoc describes the JSON form of Amount for the swagger docs (see .swaggo).
+type AmountDoc struct {
+	Wei  string `json:"wei" example:"1500250000000000000000"`
+	Data string `json:"data" example:"1500.25"`
+}
+
+// NewAmount returns an Amount holding a copy of wei. A nil wei yields zero.
+func NewAmount(wei *big.Int) *Amount {
+	if wei == nil {
+		return (*Amount)(big.NewInt(0))
+	}
+	return (*Amount)(new(big.Int).Set(wei))
+}
+
+// Int returns the amount in wei. The returned value shares memory with the Amount.
+func (a *Amount) Int() *big.Int {
+	return (*big.Int)(a)
+}
+
+// String returns the amount in wei.
+func (a *Amount) String() string {
+	return a.Int().String()
+}
+
+// DATA returns the amount as a decimal number of DATA without trailing zeros, e.g. "1500.25".
+func (a *Amount) DATA() string {
+	formatted := FormatUnits(a.Int(), dataDecimals)
+	formatted = strings.TrimRight(formatted, "0")
+	return strings.TrimSuffix(formatted, ".")
+}
+
+func (a *Amount) MarshalJSON() ([]byte, error) {
+	return json.Marshal(AmountDoc{Wei: a.String(), Data: a.DATA()})
+}
+
+// UnmarshalJSON accepts the object form produced by MarshalJSON, a string understood by
+// ParseAmount, or a plain JSON number of wei.
+func (a *Amount) UnmarshalJSON(data []byte) error {
+	trimmed := strings.TrimSpace(string(data))
+	switch {
+	case strings.HasPrefix(trimmed, "{"):
+		var doc AmountDoc
+		if err := json.Unmarshal(data, &doc); err != nil {
+			return err
+		}
+		wei, ok := new(big.Int).SetString(doc.Wei, 10)
+		if !ok {
+			return fmt.Errorf("invalid wei amount: %s", doc.Wei)
+		}
+		a.Int().Set(wei)
+	case strings.HasPrefix(trimmed, "\""):
+		var s string
+		if err := json.Unmarshal(data, &s); err != nil {
+			return err
+		}
+		wei, err := ParseAmount(s)
+		if err != nil {
+			return err
+		}
+		a.Int().Set(wei)
+	default:
+		wei, ok := new(big.Int).SetString(trimmed, 10)
+		if !ok {
+			return fmt.Errorf("invalid amount: %s", trimmed)
+		}
+		a.Int().Set(wei)
+	}
+	return nil
+}
+
+// ParseAmount parses a DATA amount given as "1500.25DATA", "1500250000000000000000wei" or a plain
+// integer, which is taken as wei for compatibility with the existing endpoints. Units are case insensitive.
+func ParseAmount(s string) (*big.Int, error) {
+	value := strings.TrimSpace(s)
+	lower := strings.ToLower(value)
+
+	switch {
+	case strings.HasSuffix(lower, "data"):
+		return parseDecimal(strings.TrimSpace(value[:len(value)-4]), dataDecimals)
+	case strings.HasSuffix(lower, "wei"):
+		value = strings.TrimSpace(value[:len(value)-3])
+	}
+
+	wei, ok := new(big.Int).SetString(value, 10)
+	if !ok {
+		return nil, fmt.Errorf("invalid amount %q, expected e.g. 1500.25DATA or 1500250000000000000000wei", s)
+	}
+	if wei.Sign() < 0 {
+		return nil, fmt.Errorf("invalid amount %q, must not be negative", s)
+	}
+	return wei, nil
+}
+
+// ParseUnits parses an amount of a token with the given decimals, e.g. POL, given as a decimal
+// number of whole tokens like "2.5", or as an integer of the smallest unit with a wei suffix.
+func ParseUnits(s string, decimals int) (*big.Int, error) {
+	value := strings.TrimSpace(s)
+	if lower := strings.ToLower(value); strings.HasSuffix(lower, "wei") {
+		wei, ok := new(big.Int).SetString(strings.TrimSpace(value[:len(value)-3]), 10)
+		if !ok || wei.Sign() < 0 {
+			return nil, fmt.Errorf("invalid amount %q", s)
+		}
+		return wei, nil
+	}
+	return parseDecimal(value, decimals)
+}
+
+// parseDecimal converts a non-negative decimal string to an integer of the smallest unit.
+func parseDecimal(value string, decimals int) (*big.Int, error) {
+	whole, fraction, _ := strings.Cut(value, ".")
+	if whole == "" {
+		whole = "0"
+	}
+	if len(fraction) > decimals {
+		return nil, fmt.Errorf("invalid amount %q, at most %d decimals are allowed", value, decimals)
+	}
+	digits := whole + fraction + strings.Repeat("0", decimals-len(fraction))
+	if strings.ContainsAny(digits, "+-") {
+		return nil, fmt.Errorf("invalid amount %q", value)
+	}
+
+	result, ok := new(big.Int).SetString(digits, 10)
+	if !ok {
+		return nil, fmt.Errorf("invalid amount %q", value)
+	}
+	return result, nil
+}
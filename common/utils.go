@@ -3,9 +3,13 @@ package common
 import (
 	"crypto/rand"
 	"encoding/hex"
+	"fmt"
 	"log"
+	"math/big"
 	"os"
 	"strconv"
+	"strings"
+	"time"
 )
 
 func GetIntEnvWithDefault(key string, def int) int {
@@ -33,3 +37,37 @@ func GenerateRandomHexString(nBytes int) (string, error) {
 	}
 	return hex.EncodeToString(bytes), nil
 }
+
+// ParseTime parses a time given as RFC3339, YYYY-MM-DD or unix seconds.
+func ParseTime(value string) (time.Time, error) {
+	if seconds, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return time.Unix(seconds, 0).UTC(), nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse("2006-01-02", value); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("invalid time: %s", value)
+}
+
+// FormatUnits formats an integer amount of the smallest unit (e.g. wei) as a decimal number
+// with exactly the given number of decimals, e.g. FormatUnits(1500250000000000000000, 18) = "1500.250000000000000000".
+func FormatUnits(value *big.Int, decimals int) string {
+	if value == nil {
+		value = big.NewInt(0)
+	}
+	if decimals <= 0 {
+		return value.String()
+	}
+	digits := new(big.Int).Abs(value).String()
+	if len(digits) <= decimals {
+		digits = strings.Repeat("0", decimals-len(digits)+1) + digits
+	}
+	sign := ""
+	if value.Sign() < 0 {
+		sign = "-"
+	}
+	return sign + digits[:len(digits)-decimals] + "." + digits[len(digits)-decimals:]
+}
@@ -0,0 +1,519 @@
+// Package config loads the service configuration from a YAML file, with environment variables
+// overriding the file, and validates it before anything is started.
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"streamr_api/common"
+
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultOperatorName names the operator configured with CONTRACT_ADDR and OWNER_ADDR.
+const DefaultOperatorName = "default"
+
+var operatorNamePattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// Config is the whole service configuration. Policies and Notifications can be reloaded while the
+// service runs; the other sections, which include every secret, are only read at startup.
+type Config struct {
+	Server        ServerConfig        `yaml:"server" json:"server"`
+	RPC           RPCConfig           `yaml:"rpc" json:"rpc"`
+	Signer        SignerConfig        `yaml:"signer" json:"-"`
+	Operators     []OperatorConfig    `yaml:"operators" json:"operators"`
+	Token         TokenConfig         `yaml:"token" json:"token"`
+	Indexer       IndexerConfig       `yaml:"indexer" json:"indexer"`
+	Scheduler     SchedulerConfig     `yaml:"scheduler" json:"scheduler"`
+	Auth          AuthConfig          `yaml:"auth" json:"-"`
+	Notifications NotificationsConfig `yaml:"notifications" json:"notifications"`
+	Policies      PoliciesConfig      `yaml:"policies" json:"policies"`
+}
+
+type ServerConfig struct {
+	Port            int    `yaml:"port" json:"port"`
+	DBPath          string `yaml:"dbPath" json:"dbPath"`
+	ShutdownSeconds int    `yaml:"shutdownSeconds" json:"shutdownSeconds"` // how long a shutdown may wait for running work
+}
+
+type RPCConfig struct {
+	URL     string `yaml:"url" json:"url"`
+	ChainID uint64 `yaml:"chainId" json:"chainId"` // checked by /readyz unless 0
+}
+
+// SignerConfig is the key operators without a key of their own sign with. The key can be given
+// directly or, to keep it out of the file, as the name of an environment variable holding it.
+type SignerConfig struct {
+	PrivateKey    string `yaml:"privateKey"`
+	PrivateKeyEnv string `yaml:"privateKeyEnv"`
+}
+
+type OperatorConfig struct {
+	Name          string `yaml:"name" json:"name"`
+	Contract      string `yaml:"contract" json:"contract"`
+	Owner         string `yaml:"owner" json:"owner"`
+	PrivateKey    string `yaml:"privateKey" json:"-"`      // the signer's key if empty
+	PrivateKeyEnv string `yaml:"privateKeyEnv" json:"-"`   // the signer's key if empty
+	RPC           string `yaml:"rpc" json:"rpc,omitempty"` // rpc.url if empty
+}
+
+type TokenConfig struct {
+	Address string `yaml:"address" json:"address"` // read from the operator contract if empty
+}
+
+type IndexerConfig struct {
+	StartBlock  uint64 `yaml:"startBlock" json:"startBlock"`
+	BatchSize   uint64 `yaml:"batchSize" json:"batchSize"`
+	PollSeconds int    `yaml:"pollSeconds" json:"pollSeconds"`
+}
+
+type SchedulerConfig struct {
+	JobsFile string `yaml:"jobsFile" json:"jobsFile"`
+	BaseURL  string `yaml:"baseUrl" json:"baseUrl"` // where cron jobs send their requests, http://localhost:<port> if empty
+}
+
+// APIKeyHeader is the request header carrying the API key. "Authorization: Bearer <key>" works too.
+const APIKeyHeader = "X-API-Key"
+
+// AuthConfig lists the keys accepted by the API. The API is open when there are none.
+type AuthConfig struct {
+	APIKeys []string `yaml:"apiKeys"`
+}
+
+type NotificationsConfig struct {
+	AlertWebhookURL  string `yaml:"alertWebhookUrl" json:"alertWebhookUrl"`
+	ReviewWebhookURL string `yaml:"reviewWebhookUrl" json:"reviewWebhookUrl"`
+}
+
+type PoliciesConfig struct {
+	Accounting AccountingPolicy `yaml:"accounting" json:"accounting"`
+	Metrics    MetricsPolicy    `yaml:"metrics" json:"metrics"`
+	Guard      GuardPolicy      `yaml:"guard" json:"guard"`
+	Queue      QueuePolicy      `yaml:"queue" json:"queue"`
+	Rebalance  RebalancePolicy  `yaml:"rebalance" json:"rebalance"`
+	Discovery  DiscoveryPolicy  `yaml:"discovery" json:"discovery"`
+	Exit       ExitPolicy       `yaml:"exit" json:"exit"`
+	Reviews    ReviewPolicy     `yaml:"reviews" json:"reviews"`
+	Cut        CutPolicy        `yaml:"cut" json:"cut"`
+	Wallet     WalletPolicy     `yaml:"wallet" json:"wallet"`
+}
+
+type AccountingPolicy struct {
+	SnapshotMinutes int `yaml:"snapshotMinutes" json:"snapshotMinutes"`
+}
+
+type MetricsPolicy struct {
+	IntervalSeconds int `yaml:"intervalSeconds" json:"intervalSeconds"`
+}
+
+type GuardPolicy struct {
+	ThresholdPercent int `yaml:"thresholdPercent" json:"thresholdPercent"` // 0 disables the guard
+	IntervalSeconds  int `yaml:"intervalSeconds" json:"intervalSeconds"`
+}
+
+type QueuePolicy struct {
+	Policy          string `yaml:"policy" json:"policy"`
+	IntervalSeconds int    `yaml:"intervalSeconds" json:"intervalSeconds"` // 0 disables servicing in the background
+}
+
+type RebalancePolicy struct {
+	TolerancePercent float64 `yaml:"tolerancePercent" json:"tolerancePercent"`
+	MaxMoves         int     `yaml:"maxMoves" json:"maxMoves"`
+}
+
+type DiscoveryPolicy struct {
+	SubgraphURL  string `yaml:"subgraphUrl" json:"subgraphUrl"`
+	Limit        int    `yaml:"limit" json:"limit"`
+	CacheSeconds int    `yaml:"cacheSeconds" json:"cacheSeconds"`
+}
+
+type ExitPolicy struct {
+	MinRunwayHours  int    `yaml:"minRunwayHours" json:"minRunwayHours"` // 0 disables the runway trigger
+	MinAPYPercent   int    `yaml:"minApyPercent" json:"minApyPercent"`   // 0 disables the yield trigger
+	Allocation      string `yaml:"allocation" json:"allocation"`
+	IntervalSeconds int    `yaml:"intervalSeconds" json:"intervalSeconds"` // 0 disables checking in the background
+}
+
+type ReviewPolicy struct {
+	Policy          string `yaml:"policy" json:"policy"`
+	AlertMinutes    int    `yaml:"alertMinutes" json:"alertMinutes"`
+	IntervalSeconds int    `yaml:"intervalSeconds" json:"intervalSeconds"` // 0 disables watching in the background
+}
+
+type CutPolicy struct {
+	MaxChangePercent float64 `yaml:"maxChangePercent" json:"maxChangePercent"` // 0 for no limit
+	ChangePeriodDays int     `yaml:"changePeriodDays" json:"changePeriodDays"`
+}
+
+type WalletPolicy struct {
+	MinPOL          string  `yaml:"minPol" json:"minPol"` // in whole POL, e.g. "2.5"
+	AlertDays       float64 `yaml:"alertDays" json:"alertDays"`
+	CriticalDays    float64 `yaml:"criticalDays" json:"criticalDays"`
+	GasWindowDays   int     `yaml:"gasWindowDays" json:"gasWindowDays"`
+	IntervalSeconds int     `yaml:"intervalSeconds" json:"intervalSeconds"` // 0 disables monitoring in the background
+}
+
+// Default returns the configuration used for everything the file and the environment leave out.
+func Default() *Config {
+	return &Config{
+		Server:    ServerConfig{Port: 8080, DBPath: "streamr_db", ShutdownSeconds: 90},
+		RPC:       RPCConfig{URL: "https://polygon-rpc.com"},
+		Indexer:   IndexerConfig{BatchSize: 2000, PollSeconds: 15},
+		Scheduler: SchedulerConfig{JobsFile: "cron_jobs.json"},
+		Policies: PoliciesConfig{
+			Accounting: AccountingPolicy{SnapshotMinutes: 60},
+			Metrics:    MetricsPolicy{IntervalSeconds: 60},
+			Guard:      GuardPolicy{ThresholdPercent: 90, IntervalSeconds: 300},
+			Queue:      QueuePolicy{Policy: "prorata"},
+			Rebalance:  RebalancePolicy{TolerancePercent: 5, MaxMoves: 5},
+			Discovery:  DiscoveryPolicy{Limit: 100, CacheSeconds: 300},
+			Exit:       ExitPolicy{MinRunwayHours: 48, Allocation: "prorata"},
+			Reviews:    ReviewPolicy{Policy: "manual", AlertMinutes: 30, IntervalSeconds: 60},
+			Cut:        CutPolicy{MaxChangePercent: 5, ChangePeriodDays: 30},
+			Wallet:     WalletPolicy{MinPOL: "1", AlertDays: 7, CriticalDays: 2, GasWindowDays: 14, IntervalSeconds: 900},
+		},
+	}
+}
+
+// Path returns the configuration file to load, CONFIG_FILE or config.yaml.
+func Path() string {
+	return common.GetStringEnvWithDefault("CONFIG_FILE", "config.yaml")
+}
+
+// Load reads the file at path over the defaults, applies the environment and validates the result.
+// A missing file is not an error, so the service can still be configured with environment
+// variables alone.
+func Load(path string) (*Config, error) {
+	c := Default()
+	data, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	if err == nil {
+		decoder := yaml.NewDecoder(strings.NewReader(string(data)))
+		decoder.KnownFields(true)
+		if err := decoder.Decode(c); err != nil && !errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("invalid %s: %v", path, err)
+		}
+	}
+
+	if err := c.applyEnv(); err != nil {
+		return nil, err
+	}
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+type envVar struct {
+	name  string
+	value interface{} // pointer to the field it overrides
+}
+
+// env lists the environment variables and the fields they override.
+func (c *Config) env() []envVar {
+	p := &c.Policies
+	return []envVar{
+		{"PORT", &c.Server.Port},
+		{"DB_PATH", &c.Server.DBPath},
+		{"SHUTDOWN_TIMEOUT_SECONDS", &c.Server.ShutdownSeconds},
+		{"RPC_ADDR", &c.RPC.URL},
+		{"CHAIN_ID", &c.RPC.ChainID},
+		{"PRIVATE_KEY", &c.Signer.PrivateKey},
+		{"DATA_TOKEN_ADDRESS", &c.Token.Address},
+		{"INDEXER_START_BLOCK", &c.Indexer.StartBlock},
+		{"INDEXER_BATCH_SIZE", &c.Indexer.BatchSize},
+		{"INDEXER_POLL_SECONDS", &c.Indexer.PollSeconds},
+		{"CRON_JOB_FILE", &c.Scheduler.JobsFile},
+		{"CRON_BASE_URL", &c.Scheduler.BaseURL},
+		{"API_KEYS", &c.Auth.APIKeys},
+		{"ALERT_WEBHOOK_URL", &c.Notifications.AlertWebhookURL},
+		{"REVIEW_WEBHOOK_URL", &c.Notifications.ReviewWebhookURL},
+		{"ACCOUNTING_SNAPSHOT_MINUTES", &p.Accounting.SnapshotMinutes},
+		{"METRICS_INTERVAL_SECONDS", &p.Metrics.IntervalSeconds},
+		{"EARNINGS_GUARD_PERCENT", &p.Guard.ThresholdPercent},
+		{"EARNINGS_GUARD_INTERVAL_SECONDS", &p.Guard.IntervalSeconds},
+		{"QUEUE_POLICY", &p.Queue.Policy},
+		{"QUEUE_INTERVAL_SECONDS", &p.Queue.IntervalSeconds},
+		{"REBALANCE_TOLERANCE_PERCENT", &p.Rebalance.TolerancePercent},
+		{"REBALANCE_MAX_MOVES", &p.Rebalance.MaxMoves},
+		{"SUBGRAPH_URL", &p.Discovery.SubgraphURL},
+		{"DISCOVERY_LIMIT", &p.Discovery.Limit},
+		{"DISCOVERY_CACHE_SECONDS", &p.Discovery.CacheSeconds},
+		{"EXIT_MIN_RUNWAY_HOURS", &p.Exit.MinRunwayHours},
+		{"EXIT_MIN_APY_PERCENT", &p.Exit.MinAPYPercent},
+		{"EXIT_ALLOCATION", &p.Exit.Allocation},
+		{"EXIT_INTERVAL_SECONDS", &p.Exit.IntervalSeconds},
+		{"REVIEW_POLICY", &p.Reviews.Policy},
+		{"REVIEW_ALERT_MINUTES", &p.Reviews.AlertMinutes},
+		{"REVIEW_INTERVAL_SECONDS", &p.Reviews.IntervalSeconds},
+		{"CUT_MAX_CHANGE_PERCENT", &p.Cut.MaxChangePercent},
+		{"CUT_CHANGE_PERIOD_DAYS", &p.Cut.ChangePeriodDays},
+		{"WALLET_MIN_POL", &p.Wallet.MinPOL},
+		{"WALLET_ALERT_DAYS", &p.Wallet.AlertDays},
+		{"WALLET_CRITICAL_DAYS", &p.Wallet.CriticalDays},
+		{"WALLET_GAS_WINDOW_DAYS", &p.Wallet.GasWindowDays},
+		{"WALLET_INTERVAL_SECONDS", &p.Wallet.IntervalSeconds},
+	}
+}
+
+// applyEnv overrides the fields whose environment variable is set. Without operators in the file,
+// CONTRACT_ADDR, OWNER_ADDR and OPERATOR_NAME configure a single one.
+func (c *Config) applyEnv() error {
+	for _, v := range c.env() {
+		value, ok := os.LookupEnv(v.name)
+		if !ok || value == "" {
+			continue
+		}
+		var err error
+		switch field := v.value.(type) {
+		case *string:
+			*field = value
+		case *int:
+			*field, err = strconv.Atoi(value)
+		case *uint64:
+			*field, err = strconv.ParseUint(value, 10, 64)
+		case *float64:
+			*field, err = strconv.ParseFloat(value, 64)
+		case *[]string:
+			*field = strings.Split(value, ",")
+		}
+		if err != nil {
+			return fmt.Errorf("invalid %s %q: %v", v.name, value, err)
+		}
+	}
+
+	contract, owner := os.Getenv("CONTRACT_ADDR"), os.Getenv("OWNER_ADDR")
+	if len(c.Operators) == 0 && (contract != "" || owner != "") {
+		c.Operators = []OperatorConfig{{
+			Name:     common.GetStringEnvWithDefault("OPERATOR_NAME", DefaultOperatorName),
+			Contract: contract,
+			Owner:    owner,
+		}}
+	}
+	return nil
+}
+
+// Validate reports every problem of the configuration at once.
+func (c *Config) Validate() error {
+	var problems []string
+	add := func(format string, args ...interface{}) {
+		problems = append(problems, fmt.Sprintf(format, args...))
+	}
+
+	if c.Server.Port <= 0 || c.Server.Port > 65535 {
+		add("server.port (PORT): %d is not a port", c.Server.Port)
+	}
+	if c.Server.DBPath == "" {
+		add("server.dbPath (DB_PATH) is required")
+	}
+	if c.Server.ShutdownSeconds <= 0 {
+		add("server.shutdownSeconds (SHUTDOWN_TIMEOUT_SECONDS) must be positive")
+	}
+	if err := validURL(c.RPC.URL); err != nil {
+		add("rpc.url (RPC_ADDR): %v", err)
+	}
+	if c.Token.Address != "" && !ethcommon.IsHexAddress(c.Token.Address) {
+		add("token.address (DATA_TOKEN_ADDRESS): invalid address %q", c.Token.Address)
+	}
+	if c.Indexer.BatchSize == 0 {
+		add("indexer.batchSize (INDEXER_BATCH_SIZE) must be positive")
+	}
+	if c.Indexer.PollSeconds <= 0 {
+		add("indexer.pollSeconds (INDEXER_POLL_SECONDS) must be positive")
+	}
+	if c.Scheduler.JobsFile == "" {
+		add("scheduler.jobsFile (CRON_JOB_FILE) is required")
+	}
+	if c.Scheduler.BaseURL != "" {
+		if err := validURL(c.Scheduler.BaseURL); err != nil {
+			add("scheduler.baseUrl (CRON_BASE_URL): %v", err)
+		}
+	}
+	for i, key := range c.Auth.APIKeys {
+		if len(key) < 16 {
+			add("auth.apiKeys[%d] (API_KEYS): keys must be at least 16 characters", i)
+		}
+	}
+	for name, value := range map[string]string{
+		"notifications.alertWebhookUrl (ALERT_WEBHOOK_URL)":   c.Notifications.AlertWebhookURL,
+		"notifications.reviewWebhookUrl (REVIEW_WEBHOOK_URL)": c.Notifications.ReviewWebhookURL,
+		"policies.discovery.subgraphUrl (SUBGRAPH_URL)":       c.Policies.Discovery.SubgraphURL,
+	} {
+		if value == "" {
+			continue
+		}
+		if err := validURL(value); err != nil {
+			add("%s: %v", name, err)
+		}
+	}
+
+	if len(c.Operators) == 0 {
+		add("operators: no operator configured, list them in the file or set CONTRACT_ADDR and OWNER_ADDR")
+	}
+	names := make(map[string]bool)
+	contracts := make(map[string]string)
+	for i, o := range c.Operators {
+		field := fmt.Sprintf("operators[%d]", i)
+		if !operatorNamePattern.MatchString(o.Name) {
+			add("%s.name: invalid name %q, use letters, digits, - and _", field, o.Name)
+		} else if names[o.Name] {
+			add("%s.name: duplicate name %q", field, o.Name)
+		}
+		names[o.Name] = true
+
+		if !ethcommon.IsHexAddress(o.Contract) {
+			add("%s.contract (CONTRACT_ADDR): invalid address %q", field, o.Contract)
+		} else if other, ok := contracts[strings.ToLower(o.Contract)]; ok {
+			// both would write the same keys in the store
+			add("%s.contract: same contract as operator %s", field, other)
+		} else {
+			contracts[strings.ToLower(o.Contract)] = o.Name
+		}
+		if !ethcommon.IsHexAddress(o.Owner) {
+			add("%s.owner (OWNER_ADDR): invalid address %q", field, o.Owner)
+		}
+		if _, err := c.OperatorKey(o); err != nil {
+			add("%s: %v", field, err)
+		}
+		if o.RPC != "" {
+			if err := validURL(o.RPC); err != nil {
+				add("%s.rpc: %v", field, err)
+			}
+		}
+	}
+
+	problems = append(problems, c.Policies.problems()...)
+	if len(problems) > 0 {
+		return fmt.Errorf("invalid configuration:\n  - %s", strings.Join(problems, "\n  - "))
+	}
+	return nil
+}
+
+func (p PoliciesConfig) problems() []string {
+	var problems []string
+	positive := map[string]int{
+		"policies.accounting.snapshotMinutes (ACCOUNTING_SNAPSHOT_MINUTES)": p.Accounting.SnapshotMinutes,
+		"policies.metrics.intervalSeconds (METRICS_INTERVAL_SECONDS)":       p.Metrics.IntervalSeconds,
+		"policies.discovery.limit (DISCOVERY_LIMIT)":                        p.Discovery.Limit,
+		"policies.cut.changePeriodDays (CUT_CHANGE_PERIOD_DAYS)":            p.Cut.ChangePeriodDays,
+		"policies.wallet.gasWindowDays (WALLET_GAS_WINDOW_DAYS)":            p.Wallet.GasWindowDays,
+		"policies.rebalance.maxMoves (REBALANCE_MAX_MOVES)":                 p.Rebalance.MaxMoves,
+	}
+	notNegative := map[string]float64{
+		"policies.guard.intervalSeconds (EARNINGS_GUARD_INTERVAL_SECONDS)":  float64(p.Guard.IntervalSeconds),
+		"policies.queue.intervalSeconds (QUEUE_INTERVAL_SECONDS)":           float64(p.Queue.IntervalSeconds),
+		"policies.discovery.cacheSeconds (DISCOVERY_CACHE_SECONDS)":         float64(p.Discovery.CacheSeconds),
+		"policies.exit.minRunwayHours (EXIT_MIN_RUNWAY_HOURS)":              float64(p.Exit.MinRunwayHours),
+		"policies.exit.minApyPercent (EXIT_MIN_APY_PERCENT)":                float64(p.Exit.MinAPYPercent),
+		"policies.exit.intervalSeconds (EXIT_INTERVAL_SECONDS)":             float64(p.Exit.IntervalSeconds),
+		"policies.reviews.alertMinutes (REVIEW_ALERT_MINUTES)":              float64(p.Reviews.AlertMinutes),
+		"policies.reviews.intervalSeconds (REVIEW_INTERVAL_SECONDS)":        float64(p.Reviews.IntervalSeconds),
+		"policies.cut.maxChangePercent (CUT_MAX_CHANGE_PERCENT)":            p.Cut.MaxChangePercent,
+		"policies.rebalance.tolerancePercent (REBALANCE_TOLERANCE_PERCENT)": p.Rebalance.TolerancePercent,
+		"policies.wallet.alertDays (WALLET_ALERT_DAYS)":                     p.Wallet.AlertDays,
+		"policies.wallet.criticalDays (WALLET_CRITICAL_DAYS)":               p.Wallet.CriticalDays,
+		"policies.wallet.intervalSeconds (WALLET_INTERVAL_SECONDS)":         float64(p.Wallet.IntervalSeconds),
+	}
+	for name, value := range positive {
+		if value <= 0 {
+			problems = append(problems, fmt.Sprintf("%s must be positive", name))
+		}
+	}
+	for name, value := range notNegative {
+		if value < 0 {
+			problems = append(problems, fmt.Sprintf("%s must not be negative", name))
+		}
+	}
+	sort.Strings(problems)
+	if p.Guard.ThresholdPercent < 0 || p.Guard.ThresholdPercent > 100 {
+		problems = append(problems, "policies.guard.thresholdPercent (EARNINGS_GUARD_PERCENT) must be between 0 and 100")
+	}
+	if p.Guard.ThresholdPercent > 0 && p.Guard.IntervalSeconds == 0 {
+		problems = append(problems, "policies.guard.intervalSeconds (EARNINGS_GUARD_INTERVAL_SECONDS) must be positive while the guard is enabled")
+	}
+	if p.Wallet.CriticalDays > p.Wallet.AlertDays {
+		problems = append(problems, "policies.wallet.criticalDays (WALLET_CRITICAL_DAYS) must not be above alertDays")
+	}
+	if _, err := common.ParseUnits(p.Wallet.MinPOL, 18); err != nil {
+		problems = append(problems, fmt.Sprintf("policies.wallet.minPol (WALLET_MIN_POL): %v", err))
+	}
+	return problems
+}
+
+// OperatorKey returns the hex private key an operator signs with: its own, or the signer's.
+func (c *Config) OperatorKey(o OperatorConfig) (string, error) {
+	key, source := o.PrivateKey, "privateKey"
+	if o.PrivateKeyEnv != "" {
+		key, source = os.Getenv(o.PrivateKeyEnv), o.PrivateKeyEnv
+	}
+	if key == "" {
+		key, source = c.Signer.PrivateKey, "signer.privateKey (PRIVATE_KEY)"
+		if c.Signer.PrivateKeyEnv != "" {
+			key, source = os.Getenv(c.Signer.PrivateKeyEnv), c.Signer.PrivateKeyEnv
+		}
+	}
+	if key == "" {
+		return "", errors.New("no private key, set signer.privateKey (PRIVATE_KEY) or the operator's own")
+	}
+	key = strings.TrimPrefix(key, "0x")
+	if _, err := crypto.HexToECDSA(key); err != nil {
+		return "", fmt.Errorf("invalid private key in %s", source)
+	}
+	return key, nil
+}
+
+// OperatorRPC returns the RPC endpoint of an operator.
+func (c *Config) OperatorRPC(o OperatorConfig) string {
+	if o.RPC != "" {
+		return o.RPC
+	}
+	return c.RPC.URL
+}
+
+// SchedulerBaseURL returns where cron jobs send their requests.
+func (c *Config) SchedulerBaseURL() string {
+	if c.Scheduler.BaseURL != "" {
+		return strings.TrimSuffix(c.Scheduler.BaseURL, "/")
+	}
+	return fmt.Sprintf("http://localhost:%d", c.Server.Port)
+}
+
+// RestartRequired lists the sections that differ between c and next but are only read at startup.
+func (c *Config) RestartRequired(next *Config) []string {
+	var sections []string
+	current, other := reflect.ValueOf(c).Elem(), reflect.ValueOf(next).Elem()
+	for i := 0; i < current.NumField(); i++ {
+		name := current.Type().Field(i).Name
+		if name == "Policies" || name == "Notifications" {
+			continue
+		}
+		if !reflect.DeepEqual(current.Field(i).Interface(), other.Field(i).Interface()) {
+			sections = append(sections, strings.Split(current.Type().Field(i).Tag.Get("yaml"), ",")[0])
+		}
+	}
+	return sections
+}
+
+func validURL(value string) error {
+	u, err := url.Parse(value)
+	if err != nil {
+		return err
+	}
+	if u.Scheme == "" || u.Host == "" {
+		return fmt.Errorf("%q is not an absolute URL", value)
+	}
+	return nil
+}
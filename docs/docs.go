@@ -18,6 +18,180 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
+        "/accounting/snapshot": {
+            "get": {
+                "description": "Records the current earnings and stake of every sponsorship. Snapshots are also taken periodically in the background.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Accounting"
+                ],
+                "summary": "Take an earnings snapshot.",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.EarningsSnapshot"
+                        }
+                    }
+                }
+            }
+        },
+        "/accounting/summary": {
+            "get": {
+                "description": "Responds with accrued and withdrawn earnings per sponsorship, protocol fee, operator's cut, delegators' share and gas spent per period.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Accounting"
+                ],
+                "summary": "Get earnings and P\u0026L summaries.",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "daily (default), weekly or monthly",
+                        "name": "period",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "start time, RFC3339, YYYY-MM-DD or unix seconds",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "end time, RFC3339, YYYY-MM-DD or unix seconds",
+                        "name": "to",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.AccountingPeriod"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/accounting/withdrawals": {
+            "get": {
+                "description": "Responds with the withdrawals sent by the service, with the earnings withdrawn per sponsorship and the fee split, and the ones sent outside the service as detected from the earnings snapshots, marked external.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Accounting"
+                ],
+                "summary": "List recorded earnings withdrawals.",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "start time, RFC3339, YYYY-MM-DD or unix seconds",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "end time, RFC3339, YYYY-MM-DD or unix seconds",
+                        "name": "to",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.WithdrawalRecord"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/accounting/yield": {
+            "get": {
+                "description": "Responds with the annualized accrued and realized yield of each sponsorship relative to its average stake.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Accounting"
+                ],
+                "summary": "Get the yield per sponsorship.",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "start time, RFC3339, YYYY-MM-DD or unix seconds",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "end time, RFC3339, YYYY-MM-DD or unix seconds",
+                        "name": "to",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.SponsorshipYield"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/alerts": {
+            "get": {
+                "description": "Responds with the alerts raised by the background services, oldest first.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Alerts"
+                ],
+                "summary": "List alerts.",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "start time, RFC3339, YYYY-MM-DD or unix seconds",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "end time, RFC3339, YYYY-MM-DD or unix seconds",
+                        "name": "to",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.Alert"
+                            }
+                        }
+                    }
+                }
+            }
+        },
         "/cronjobs": {
             "get": {
                 "description": "Retrieves a list of all scheduled cron jobs.",
@@ -162,138 +336,139 @@ const docTemplate = `{
                 }
             }
         },
-        "/operator": {
+        "/delegators": {
             "get": {
-                "description": "Responds with the Operator attributes.",
+                "description": "Responds with every delegator holding operator tokens or waiting in the undelegation queue: the operator tokens, their DATA value, the first delegation, the DATA delegated and paid out, the earnings attributable to the delegator and the pending undelegations. Built from the indexed events.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "Operator"
+                    "Delegators"
                 ],
-                "summary": "Get the Streamr Operator details.",
-                "responses": {
-                    "200": {
-                        "description": "OK",
-                        "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/models.Operator"
-                            }
-                        }
+                "summary": "List the delegators.",
+                "parameters": [
+                    {
+                        "type": "boolean",
+                        "description": "also list delegators who have left",
+                        "name": "all",
+                        "in": "query"
                     }
-                }
-            }
-        },
-        "/operator/deployedstake/": {
-            "get": {
-                "description": "Responds with the Operator stake deployed in all sponsorships.",
-                "produces": [
-                    "application/json"
-                ],
-                "tags": [
-                    "Operator"
                 ],
-                "summary": "Get the Streamr Operator total deployed stake.",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
                             "type": "array",
                             "items": {
-                                "$ref": "#/definitions/models.DeployedStakeResponse"
+                                "$ref": "#/definitions/models.DelegatorSummary"
                             }
                         }
                     }
                 }
             }
         },
-        "/operator/reducestaketo/{sponsorship}/{amount}": {
+        "/delegators/{address}/statement": {
             "get": {
-                "description": "Responds with the transaction hash.",
+                "description": "Responds with a report for the delegator: its current position and earnings, pending undelegations and every delegation, undelegation and operator token transfer in the period.",
                 "produces": [
-                    "application/json"
+                    "application/json",
+                    "text/csv"
                 ],
                 "tags": [
-                    "Operator"
+                    "Delegators"
                 ],
-                "summary": "Change Streamr Operator stake on a given sponsor.",
+                "summary": "Get a delegator's statement.",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "sponsorship address",
-                        "name": "sponsorship",
+                        "description": "delegator address",
+                        "name": "address",
                         "in": "path",
                         "required": true
                     },
                     {
-                        "type": "integer",
-                        "description": "amount in wei",
-                        "name": "amount",
-                        "in": "path",
-                        "required": true
+                        "type": "string",
+                        "description": "json (default) or csv",
+                        "name": "format",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "start time, RFC3339, YYYY-MM-DD or unix seconds",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "end time, RFC3339, YYYY-MM-DD or unix seconds",
+                        "name": "to",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/models.DelegatorStatement"
                         }
                     }
                 }
             }
         },
-        "/operator/sponsorshipsandearnings": {
+        "/diagnostics": {
             "get": {
-                "description": "Responds with the list of sponsorships and uncollected earnings.",
+                "description": "Responds with the build version, every operator's latest block and how far it lags behind the wall clock, the state of the signer's nonce sequence, the number of pending transactions in the journal, and the last successful and failed run of each cron job since the service started.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "Operator"
+                    "Diagnostics"
                 ],
-                "summary": "Get the sponsorships and earnings.",
+                "summary": "Get diagnostics.",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/models.GetSponsorshipsAndEarningsResponse"
-                            }
+                            "$ref": "#/definitions/models.Diagnostics"
                         }
                     }
                 }
             }
         },
-        "/operator/stake/{sponsorship}/{amount}": {
+        "/events": {
             "get": {
-                "description": "Responds with the transaction hash.",
+                "description": "Responds with the decoded events of the operator contract and its sponsorships, oldest first.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "Operator"
+                    "Events"
                 ],
-                "summary": "Increase Streamr Operator stake on a given sponsor.",
+                "summary": "List indexed contract events.",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "sponsorship address",
-                        "name": "sponsorship",
-                        "in": "path",
-                        "required": true
+                        "description": "comma separated event names, e.g. Delegated,Undelegated",
+                        "name": "name",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "start time, RFC3339, YYYY-MM-DD or unix seconds",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "end time, RFC3339, YYYY-MM-DD or unix seconds",
+                        "name": "to",
+                        "in": "query"
                     },
                     {
                         "type": "integer",
-                        "description": "amount in wei",
-                        "name": "amount",
-                        "in": "path",
-                        "required": true
+                        "description": "return only the most recent events",
+                        "name": "limit",
+                        "in": "query"
                     }
                 ],
                 "responses": {
@@ -302,30 +477,41 @@ const docTemplate = `{
                         "schema": {
                             "type": "array",
                             "items": {
-                                "type": "string"
+                                "$ref": "#/definitions/blockchain.IndexedEvent"
                             }
                         }
                     }
                 }
             }
         },
-        "/operator/stakedinto/{address}": {
+        "/events/delegations": {
             "get": {
-                "description": "Responds with the Operator stake deployed in sponsorship.",
+                "description": "Responds with the Delegated events of the operator contract.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "Operator"
+                    "Events"
                 ],
-                "summary": "Get the Streamr Operator staked balance in sponsorship.",
+                "summary": "List delegations into the operator.",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "get deployed stake by sponsorship",
-                        "name": "address",
-                        "in": "path",
-                        "required": true
+                        "description": "start time, RFC3339, YYYY-MM-DD or unix seconds",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "end time, RFC3339, YYYY-MM-DD or unix seconds",
+                        "name": "to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "return only the most recent events",
+                        "name": "limit",
+                        "in": "query"
                     }
                 ],
                 "responses": {
@@ -334,477 +520,4001 @@ const docTemplate = `{
                         "schema": {
                             "type": "array",
                             "items": {
-                                "$ref": "#/definitions/models.StakedIntoResponse"
+                                "$ref": "#/definitions/blockchain.IndexedEvent"
                             }
                         }
                     }
                 }
             }
         },
-        "/operator/stakeprorata": {
+        "/events/stakechanges": {
             "get": {
-                "description": "Increase stake on all sponsorships with all available DATA pro-rated by sponsorship current stake.",
+                "description": "Responds with the Staked, Unstaked and StakeUpdate events of the operator contract.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "Operator"
+                    "Events"
+                ],
+                "summary": "List stake changes.",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "start time, RFC3339, YYYY-MM-DD or unix seconds",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "end time, RFC3339, YYYY-MM-DD or unix seconds",
+                        "name": "to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "return only the most recent events",
+                        "name": "limit",
+                        "in": "query"
+                    }
                 ],
-                "summary": "Distribute available DATA to all sponsorships.",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
                             "type": "array",
                             "items": {
-                                "type": "array",
-                                "items": {
-                                    "type": "string"
-                                }
+                                "$ref": "#/definitions/blockchain.IndexedEvent"
                             }
                         }
                     }
                 }
             }
         },
-        "/operator/undelegationqueue": {
+        "/events/status": {
             "get": {
-                "description": "Responds with the undelegation queue.",
+                "description": "Responds with the last indexed block, the chain head and the sponsorships being followed.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "Operator"
+                    "Events"
                 ],
-                "summary": "Get the undelegation queue.",
+                "summary": "Get the event indexer status.",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "type": "array",
-                                "items": {
-                                    "type": "integer"
-                                }
-                            }
+                            "$ref": "#/definitions/blockchain.IndexerStatus"
                         }
                     }
                 }
             }
         },
-        "/operator/valuewithoutearnings": {
+        "/events/undelegations": {
             "get": {
-                "description": "Responds with the Operator attributes.",
+                "description": "Responds with the Undelegated and QueuedDataPayout events of the operator contract.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "Operator"
+                    "Events"
+                ],
+                "summary": "List undelegations from the operator.",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "start time, RFC3339, YYYY-MM-DD or unix seconds",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "end time, RFC3339, YYYY-MM-DD or unix seconds",
+                        "name": "to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "return only the most recent events",
+                        "name": "limit",
+                        "in": "query"
+                    }
                 ],
-                "summary": "Get the Streamr Operator details.",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
                             "type": "array",
                             "items": {
-                                "type": "integer"
+                                "$ref": "#/definitions/blockchain.IndexedEvent"
                             }
                         }
                     }
                 }
             }
         },
-        "/operator/withdrawearnings": {
+        "/events/withdrawals": {
             "get": {
-                "description": "Responds with the Operator attributes.",
+                "description": "Responds with the Profit events the operator contract emits when earnings are withdrawn.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "Operator"
+                    "Events"
+                ],
+                "summary": "List earnings withdrawals.",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "start time, RFC3339, YYYY-MM-DD or unix seconds",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "end time, RFC3339, YYYY-MM-DD or unix seconds",
+                        "name": "to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "return only the most recent events",
+                        "name": "limit",
+                        "in": "query"
+                    }
                 ],
-                "summary": "Get the Streamr Operator details.",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
                             "type": "array",
                             "items": {
-                                "type": "string"
+                                "$ref": "#/definitions/blockchain.IndexedEvent"
                             }
                         }
                     }
                 }
             }
         },
-        "/operator/withdrawearningsandcompound": {
+        "/exit/check": {
             "get": {
-                "description": "Withdraws earnings from all sponsorships and restake to compound.",
+                "description": "Leaves the sponsorships whose runway or yield is under the configured minimum and that can be left without a penalty, withdrawing their earnings first, then stakes the freed DATA into the remaining sponsorships by the configured allocation. Responds once all transactions are mined.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "Operator"
+                    "Exit"
+                ],
+                "summary": "Run the exit policy.",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.ExitReport"
+                        }
+                    }
+                }
+            }
+        },
+        "/exit/plan": {
+            "get": {
+                "description": "Responds with the decision for every staked sponsorship (keep, exit, or defer while leaving would cost a penalty) and the metrics it is based on. Nothing is sent.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Exit"
+                ],
+                "summary": "Evaluate the exit policy.",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.ExitReport"
+                        }
+                    }
+                }
+            }
+        },
+        "/exit/reports": {
+            "get": {
+                "description": "Responds with every run that left or deferred leaving a sponsorship, with the metrics behind each decision and the transactions sent.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Exit"
+                ],
+                "summary": "List the exit policy runs.",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "start time, RFC3339, YYYY-MM-DD or unix seconds",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "end time, RFC3339, YYYY-MM-DD or unix seconds",
+                        "name": "to",
+                        "in": "query"
+                    }
                 ],
-                "summary": "Withdraw earnings from sponsorship and restake.",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
                             "type": "array",
                             "items": {
-                                "type": "array",
-                                "items": {
-                                    "type": "string"
-                                }
+                                "$ref": "#/definitions/models.ExitReport"
                             }
                         }
                     }
                 }
             }
-        }
-    },
-    "definitions": {
-        "abi.ABI": {
-            "type": "object",
-            "properties": {
-                "constructor": {
-                    "$ref": "#/definitions/github_com_ethereum_go-ethereum_accounts_abi.Method"
-                },
-                "errors": {
-                    "type": "object",
-                    "additionalProperties": {
-                        "$ref": "#/definitions/abi.Error"
+        },
+        "/export/ledger": {
+            "get": {
+                "description": "Responds with every stake, unstake, earnings withdrawal and queue payout of the operator, with amounts in DATA and gas costs in POL. The CSV layout suits common crypto tax tools.",
+                "produces": [
+                    "application/json",
+                    "text/csv"
+                ],
+                "tags": [
+                    "Export"
+                ],
+                "summary": "Export the operator's ledger.",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "csv (default) or json",
+                        "name": "format",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "start time, RFC3339, YYYY-MM-DD or unix seconds",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "end time, RFC3339, YYYY-MM-DD or unix seconds",
+                        "name": "to",
+                        "in": "query"
                     }
-                },
-                "events": {
-                    "type": "object",
-                    "additionalProperties": {
-                        "$ref": "#/definitions/abi.Event"
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.LedgerRow"
+                            }
+                        }
                     }
-                },
-                "fallback": {
-                    "description": "Additional \"special\" functions introduced in solidity v0.6.0.\nIt's separated from the original default fallback. Each contract\ncan only define one fallback and receive function.",
-                    "allOf": [
-                        {
-                            "$ref": "#/definitions/github_com_ethereum_go-ethereum_accounts_abi.Method"
+                }
+            }
+        },
+        "/flag/{sponsorship}/{target}": {
+            "get": {
+                "description": "Flags an operator for misbehaving in a sponsorship our operator is staked in. Part of our stake is locked until the flag is resolved and is lost if the reviewers don't kick the target.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Reviews"
+                ],
+                "summary": "Flag an operator.",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "sponsorship address",
+                        "name": "sponsorship",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "operator address to flag",
+                        "name": "target",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "flag metadata, e.g. the partition that wasn't served",
+                        "name": "metadata",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "string"
                         }
-                    ]
-                },
-                "methods": {
-                    "type": "object",
-                    "additionalProperties": {
-                        "$ref": "#/definitions/github_com_ethereum_go-ethereum_accounts_abi.Method"
                     }
-                },
-                "receive": {
-                    "$ref": "#/definitions/github_com_ethereum_go-ethereum_accounts_abi.Method"
                 }
             }
         },
-        "abi.Argument": {
-            "type": "object",
-            "properties": {
-                "indexed": {
-                    "description": "indexed is only used by events",
-                    "type": "boolean"
+        "/guard/earnings": {
+            "get": {
+                "description": "Responds with the threshold and the unwithdrawn earnings against maxAllowedEarnings at the last check.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Guard"
+                ],
+                "summary": "Get the earnings guard status.",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.EarningsGuardStatus"
+                        }
+                    }
+                }
+            }
+        },
+        "/guard/earnings/actions": {
+            "get": {
+                "description": "Responds with every automatic withdrawal, the sponsorships it withdrew from and its outcome.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Guard"
+                ],
+                "summary": "List the withdrawals sent by the earnings guard.",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "start time, RFC3339, YYYY-MM-DD or unix seconds",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "end time, RFC3339, YYYY-MM-DD or unix seconds",
+                        "name": "to",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.EarningsGuardAction"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/guard/earnings/check": {
+            "get": {
+                "description": "Compares the unwithdrawn earnings against maxAllowedEarnings and withdraws from the sponsorships over the threshold. Responds with the action taken, or null if the earnings are below the threshold. Refused with 409 while the guard is disabled.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Guard"
+                ],
+                "summary": "Run the earnings guard now.",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.EarningsGuardAction"
+                        }
+                    }
+                }
+            }
+        },
+        "/operator": {
+            "get": {
+                "description": "Responds with the Operator attributes.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Operator"
+                ],
+                "summary": "Get the Streamr Operator details.",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.Operator"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/operator/cut/change/{percent}": {
+            "get": {
+                "description": "Sets the operator cut, the owner's share of the earnings after the protocol fee. Responds with the delegators' projected yield before and after at the current earnings rate, once the transaction is mined. Refused with 409 when the change would take the total change within the period over CUT_MAX_CHANGE_PERCENT, or while anything is staked in sponsorships. Only the owner can change the cut. Add dryRun=true to only see the preview.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Fees"
+                ],
+                "summary": "Change the operator cut.",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "new cut in percent, e.g. 12.5",
+                        "name": "percent",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "only show the preview",
+                        "name": "dryRun",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.CutChangePreview"
+                        }
+                    }
+                }
+            }
+        },
+        "/operator/cut/changes": {
+            "get": {
+                "description": "Responds with the cut changes made through the API, oldest first. These are what the maximum change per period is checked against.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Fees"
+                ],
+                "summary": "List the operator cut changes.",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "start time, RFC3339, YYYY-MM-DD or unix seconds",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "end time, RFC3339, YYYY-MM-DD or unix seconds",
+                        "name": "to",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.CutChange"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/operator/deployedstake/": {
+            "get": {
+                "description": "Responds with the Operator stake deployed in all sponsorships.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Operator"
+                ],
+                "summary": "Get the Streamr Operator total deployed stake.",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.DeployedStakeResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/operator/fees": {
+            "get": {
+                "description": "Responds with the operator cut, the protocol fee, the minimum self-delegation fraction and minimum stake, and how much the cut may still move within the change period.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Fees"
+                ],
+                "summary": "Get the operator cut and fee parameters.",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.FeeParameters"
+                        }
+                    }
+                }
+            }
+        },
+        "/operator/forceunstake/{sponsorship}": {
+            "get": {
+                "description": "Previews what a forced unstake returns and forfeits (locked stake and leave penalty) and, unless dryRun is set, sends it. If anything would be forfeited, confirm=true is required and the preview is returned with 409 otherwise.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Operator"
+                ],
+                "summary": "Force leaving a sponsorship.",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "sponsorship address",
+                        "name": "sponsorship",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "only preview",
+                        "name": "dryRun",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "accept forfeiting stake",
+                        "name": "confirm",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "undelegation queue entries to pay out afterwards, 0 (default) for all",
+                        "name": "maxQueuePayouts",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.UnstakePreview"
+                        }
+                    }
+                }
+            }
+        },
+        "/operator/metadata": {
+            "get": {
+                "description": "Responds with the metadata JSON last published with updateMetadata (name, description, image URL, redundancy factor and any other fields), when and in which transaction. Read from the indexed events.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Nodes"
+                ],
+                "summary": "Get the operator metadata.",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.OperatorMetadata"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Sets the given fields on the current metadata and publishes it with updateMetadata. Fields left out are kept, an empty description or imageUrl removes it. As the whole JSON is published, the update is refused while the current metadata isn't known for sure: with 503 while the event indexer is catching up, with 409 if no MetadataUpdated event was indexed or the metadata on chain isn't valid JSON. Responds with the field by field diff and the exact JSON sent, once the transaction is mined. Add dryRun=true to only see the change.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Nodes"
+                ],
+                "summary": "Update the operator metadata.",
+                "parameters": [
+                    {
+                        "description": "fields to set",
+                        "name": "metadata",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.MetadataUpdate"
+                        }
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "only show the change",
+                        "name": "dryRun",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.MetadataChange"
+                        }
+                    }
+                }
+            }
+        },
+        "/operator/nodes": {
+            "get": {
+                "description": "Responds with the node addresses set in the operator contract and, when events are indexed, the last heartbeat each node published.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Nodes"
+                ],
+                "summary": "Get the node addresses.",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.NodeInfo"
+                            }
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Replaces the node addresses in the operator contract. Responds with the current and proposed addresses and which are added and removed, once the transaction is mined. Add dryRun=true to only see the change.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Nodes"
+                ],
+                "summary": "Set the node addresses.",
+                "parameters": [
+                    {
+                        "description": "the complete list of node addresses",
+                        "name": "addresses",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.NodeAddressesRequest"
+                        }
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "only show the change",
+                        "name": "dryRun",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.NodeAddressChange"
+                        }
+                    }
+                }
+            }
+        },
+        "/operator/reducestaketo/{sponsorship}/{amount}": {
+            "get": {
+                "description": "Responds with the transaction hash.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Operator"
+                ],
+                "summary": "Change Streamr Operator stake on a given sponsor.",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "sponsorship address",
+                        "name": "sponsorship",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "amount, e.g. 1500.25DATA or 1500250000000000000000wei; plain integers are wei",
+                        "name": "amount",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/operator/selfdelegation": {
+            "get": {
+                "description": "Responds with the owner's operator tokens and their DATA value, the owner's share against the protocol's minimum self-delegation fraction, the DATA in the owner wallet, the owner's undelegations waiting in the queue and how much more can be undelegated without going under the minimum.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Delegation"
+                ],
+                "summary": "Get the owner's self-delegation.",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.SelfDelegation"
+                        }
+                    }
+                }
+            }
+        },
+        "/operator/selfdelegation/delegate/{amount}": {
+            "get": {
+                "description": "Delegates DATA from the owner wallet into the operator, with a single DATA transferAndCall (default) or with approve followed by delegate. Responds once the transactions are mined.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Delegation"
+                ],
+                "summary": "Delegate DATA from the owner wallet.",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "amount, e.g. 1500.25DATA or 1500250000000000000000wei; plain integers are wei",
+                        "name": "amount",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "transferAndCall (default) or approve",
+                        "name": "method",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.DelegationResult"
+                        }
+                    }
+                }
+            }
+        },
+        "/operator/selfdelegation/undelegate/{amount}": {
+            "get": {
+                "description": "Queues an undelegation of DATA from the operator to the owner wallet and responds once the transaction is mined. Refused if it would take the owner under the minimum self-delegation fraction, which would stop the operator from accepting delegations.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Delegation"
+                ],
+                "summary": "Queue an undelegation for the owner.",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "amount, e.g. 1500.25DATA or 1500250000000000000000wei; plain integers are wei",
+                        "name": "amount",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.DelegationResult"
+                        }
+                    }
+                }
+            }
+        },
+        "/operator/signer": {
+            "get": {
+                "description": "Compares the address of the signing key with the configured owner and the owner of the operator contract, reads the roles it holds and responds with the actions it can take (stake, reduceStakeTo, withdraw, ...). Requests for actions it can't take are refused with 403. If the contract can't be read, the roles last read are used, and without any requests needing a role are refused with 503. The result is reused for a few minutes unless refresh is set.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Operator"
+                ],
+                "summary": "Verify the signing key.",
+                "parameters": [
+                    {
+                        "type": "boolean",
+                        "description": "read the contract again",
+                        "name": "refresh",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.SignerStatus"
+                        }
+                    }
+                }
+            }
+        },
+        "/operator/sponsorshipsandearnings": {
+            "get": {
+                "description": "Responds with the list of sponsorships and uncollected earnings.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Operator"
+                ],
+                "summary": "Get the sponsorships and earnings.",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.GetSponsorshipsAndEarningsResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/operator/stake/{sponsorship}/{amount}": {
+            "get": {
+                "description": "Responds with the transaction hash.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Operator"
+                ],
+                "summary": "Increase Streamr Operator stake on a given sponsor.",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "sponsorship address",
+                        "name": "sponsorship",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "amount, e.g. 1500.25DATA or 1500250000000000000000wei; plain integers are wei",
+                        "name": "amount",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/operator/stakedinto/{address}": {
+            "get": {
+                "description": "Responds with the Operator stake deployed in sponsorship.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Operator"
+                ],
+                "summary": "Get the Streamr Operator staked balance in sponsorship.",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "get deployed stake by sponsorship",
+                        "name": "address",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.StakedIntoResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/operator/stakeprorata": {
+            "get": {
+                "description": "Increase stake on all sponsorships with all available DATA pro-rated by sponsorship current stake.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Operator"
+                ],
+                "summary": "Distribute available DATA to all sponsorships.",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "type": "array",
+                                "items": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/operator/transactions": {
+            "get": {
+                "description": "Responds with the tx journal: method, parameters, status and gas cost of every transaction sent.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Operator"
+                ],
+                "summary": "List the transactions sent by the service.",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "start time, RFC3339, YYYY-MM-DD or unix seconds",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "end time, RFC3339, YYYY-MM-DD or unix seconds",
+                        "name": "to",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/blockchain.JournalEntry"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/operator/undelegationqueue": {
+            "get": {
+                "description": "Responds with the undelegation queue.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Operator"
+                ],
+                "summary": "Get the undelegation queue.",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "type": "array",
+                                "items": {
+                                    "$ref": "#/definitions/models.UndelegationRecordResponse"
+                                }
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/operator/undelegationqueue/plan": {
+            "get": {
+                "description": "Responds with the DATA the queue needs, what the operator holds, the earnings that would be withdrawn and the stake reductions the policy would make. Nothing is sent.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Operator"
+                ],
+                "summary": "Plan the payout of the undelegation queue.",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "prorata (default), lowest-yield or largest",
+                        "name": "policy",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.QueuePlan"
+                        }
+                    }
+                }
+            }
+        },
+        "/operator/undelegationqueue/reports": {
+            "get": {
+                "description": "Responds with every run of the queue servicing workflow and the transactions it sent.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Operator"
+                ],
+                "summary": "List undelegation queue payouts.",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "start time, RFC3339, YYYY-MM-DD or unix seconds",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "end time, RFC3339, YYYY-MM-DD or unix seconds",
+                        "name": "to",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.QueueServiceReport"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/operator/undelegationqueue/service": {
+            "get": {
+                "description": "Withdraws earnings and reduces stake by the policy as far as needed to pay out the undelegation queue, then pays it out. Responds once all transactions are mined.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Operator"
+                ],
+                "summary": "Pay out the undelegation queue.",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "prorata (default), lowest-yield or largest",
+                        "name": "policy",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.QueueServiceReport"
+                        }
+                    }
+                }
+            }
+        },
+        "/operator/unstake/{sponsorship}": {
+            "get": {
+                "description": "Previews what leaving returns (stake, locked stake, earnings, leave penalty, end of the minimum staking period) and, unless dryRun is set, unstakes. Refused with 409 while stake is locked in flags or leaving would cost a penalty; use forceunstake for that.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Operator"
+                ],
+                "summary": "Leave a sponsorship.",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "sponsorship address",
+                        "name": "sponsorship",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "only preview",
+                        "name": "dryRun",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.UnstakePreview"
+                        }
+                    }
+                }
+            }
+        },
+        "/operator/valuewithoutearnings": {
+            "get": {
+                "description": "Responds with the Operator value without unwithdrawn earnings, in wei and DATA.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Operator"
+                ],
+                "summary": "Get the Streamr Operator value without earnings.",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/common.AmountDoc"
+                        }
+                    }
+                }
+            }
+        },
+        "/operator/withdrawearnings": {
+            "get": {
+                "description": "Responds with the Operator attributes.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Operator"
+                ],
+                "summary": "Get the Streamr Operator details.",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/operator/withdrawearnings/selective": {
+            "get": {
+                "description": "Withdraws earnings only from the sponsorships matching the filters, which combine: an explicit list, a minimum of earnings per sponsorship and the top N by earnings. Sponsorships without earnings are always skipped, and a sponsorship listed twice is withdrawn from once. Refused with 400 when a listed sponsorship isn't staked in or nothing with earnings matches. Responds with the expected earnings per sponsorship and, once the transaction is mined, the amounts actually withdrawn.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Operator"
+                ],
+                "summary": "Withdraw earnings from chosen sponsorships.",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "comma separated sponsorship addresses (default all)",
+                        "name": "sponsorships",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "minimum earnings per sponsorship, e.g. 10DATA",
+                        "name": "minEarnings",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "only the N sponsorships with the most earnings",
+                        "name": "top",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "only show what would be withdrawn",
+                        "name": "dryRun",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "seconds to wait for the transaction before responding (default 120, 0 responds right away)",
+                        "name": "wait",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.WithdrawalResult"
+                        }
+                    }
+                }
+            }
+        },
+        "/operator/withdrawearningsandcompound": {
+            "get": {
+                "description": "Withdraws earnings from all sponsorships and restakes the DATA the operator then holds, minus what the undelegation queue needs, according to the allocation. Responds once all transactions are mined.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Operator"
+                ],
+                "summary": "Withdraw earnings from sponsorship and restake.",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "prorata (default, by current stake), equal or earnings (back where the earnings came from)",
+                        "name": "allocation",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.CompoundResult"
+                        }
+                    }
+                }
+            }
+        },
+        "/operators": {
+            "get": {
+                "description": "Responds with the operators run by the service. Every operator's routes are under /operators/{name}, e.g. /operators/{name}/operator/deployedstake; the routes without a name act on the first one.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Operators"
+                ],
+                "summary": "List the operators.",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.OperatorInfo"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/portfolio": {
+            "get": {
+                "description": "Responds with the value, deployed stake and unwithdrawn earnings of every operator, their totals and the stake per sponsorship across operators. Operators that couldn't be read have an error and are left out of the totals.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Operators"
+                ],
+                "summary": "Get the portfolio of all operators.",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Portfolio"
+                        }
+                    }
+                }
+            }
+        },
+        "/rebalance/execute": {
+            "get": {
+                "description": "Sends the planned reduceStakeTo transactions, waits for them to be mined, then sends the stake transactions. Responds with the moves and their tx hashes.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Rebalance"
+                ],
+                "summary": "Rebalance stake towards the targets.",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.RebalancePlan"
+                        }
+                    }
+                }
+            }
+        },
+        "/rebalance/plan": {
+            "get": {
+                "description": "Responds with the ordered moves towards the targets, reductions first, and the moves skipped for being within the tolerance or over the move limit. Nothing is sent.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Rebalance"
+                ],
+                "summary": "Plan a rebalance.",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.RebalancePlan"
+                        }
+                    }
+                }
+            }
+        },
+        "/rebalance/targets": {
+            "get": {
+                "description": "Responds with the target allocation, the drift tolerance and the move limit the rebalancer works with.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Rebalance"
+                ],
+                "summary": "Get the rebalancing targets.",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.RebalanceTargets"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Declares the target allocation: mode \"weights\" with relative weights per sponsorship (e.g. 40/30/30), \"equal\", or \"yield\" (proportional to each sponsorship's yield over the last 30 days).",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Rebalance"
+                ],
+                "summary": "Set the rebalancing targets.",
+                "parameters": [
+                    {
+                        "description": "Rebalancing targets",
+                        "name": "targets",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.RebalanceTargets"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.RebalanceTargets"
+                        }
+                    }
+                }
+            }
+        },
+        "/reviews": {
+            "get": {
+                "description": "Responds with the review requests addressed to our operator, the most recent first: the sponsorship, the flagged operator, the voting period and our vote. Missing a vote risks getting slashed. Built from the indexed events.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Reviews"
+                ],
+                "summary": "List the flag reviews.",
+                "parameters": [
+                    {
+                        "type": "boolean",
+                        "description": "also list voted and expired reviews",
+                        "name": "all",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.Review"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/reviews/vote/{sponsorship}/{target}/{vote}": {
+            "get": {
+                "description": "Casts our vote on the pending review of the flagged operator in the sponsorship. Responds once the transaction is mined.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Reviews"
+                ],
+                "summary": "Vote on a flag review.",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "sponsorship address",
+                        "name": "sponsorship",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "flagged operator address",
+                        "name": "target",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "kick or no-kick",
+                        "name": "vote",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Review"
+                        }
+                    }
+                }
+            }
+        },
+        "/sponsorships": {
+            "get": {
+                "description": "Responds with the sponsorships from the subgraph and those the operator is staked in, with their remaining funding, payout rate, total stake, operator count, runway and the projected APY of staking the given amount. The state is cached for DISCOVERY_CACHE_SECONDS unless refresh is set.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Sponsorships"
+                ],
+                "summary": "Discover sponsorships.",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "additional stake to evaluate, e.g. 5000DATA (default the protocol minimum stake)",
+                        "name": "stake",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "apy (default), runway, remaining or payout",
+                        "name": "sort",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "return at most this many",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "read the state again instead of using the cache",
+                        "name": "refresh",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.SponsorshipMetrics"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/sponsorships/{address}": {
+            "get": {
+                "description": "Reads a single sponsorship fresh from the subgraph and the contract and responds with its metrics for staking the given amount.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Sponsorships"
+                ],
+                "summary": "Evaluate a sponsorship.",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Sponsorship address",
+                        "name": "address",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "additional stake to evaluate, e.g. 5000DATA (default the protocol minimum stake)",
+                        "name": "stake",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.SponsorshipMetrics"
+                        }
+                    }
+                }
+            }
+        },
+        "/token": {
+            "get": {
+                "description": "Responds with the address of the DATA token, whether it came from DATA_TOKEN_ADDRESS or the operator contract, and its symbol, decimals and total supply.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Token"
+                ],
+                "summary": "Get the DATA token.",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.TokenInfo"
+                        }
+                    }
+                }
+            }
+        },
+        "/token/allowance/{spender}": {
+            "get": {
+                "description": "Responds with how much DATA the spender may transfer from the owner, by default the signing wallet.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Token"
+                ],
+                "summary": "Get a DATA allowance.",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "spender address",
+                        "name": "spender",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "owner address, the signing wallet by default",
+                        "name": "owner",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Allowance"
+                        }
+                    }
+                }
+            }
+        },
+        "/token/approve/{spender}/{amount}": {
+            "get": {
+                "description": "Sets how much DATA the spender may transfer from the signing wallet. 0 revokes the approval. Responds with the allowance before and after once the transaction is mined; nothing is sent when the allowance already is the amount.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Token"
+                ],
+                "summary": "Approve a DATA spender.",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "spender address",
+                        "name": "spender",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "amount, e.g. 1500.25DATA or 1500250000000000000000wei; plain integers are wei",
+                        "name": "amount",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.ApprovalResult"
+                        }
+                    }
+                }
+            }
+        },
+        "/token/balance/{address}": {
+            "get": {
+                "description": "Responds with the DATA held by any account.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Token"
+                ],
+                "summary": "Get the DATA balance of an account.",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "account address",
+                        "name": "address",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/common.AmountDoc"
+                        }
+                    }
+                }
+            }
+        },
+        "/token/balances": {
+            "get": {
+                "description": "Responds with the DATA held by the operator contract, the owner wallet and the signing wallet.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Token"
+                ],
+                "summary": "Get the DATA balances.",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.TokenBalances"
+                        }
+                    }
+                }
+            }
+        },
+        "/wallet": {
+            "get": {
+                "description": "Responds with the POL and DATA balances of the wallet that signs and pays for transactions at the last check, the average daily gas spend from the transaction journal and how many days of gas the POL covers.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Wallet"
+                ],
+                "summary": "Get the signing wallet status.",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.WalletStatus"
+                        }
+                    }
+                }
+            }
+        },
+        "/wallet/check": {
+            "get": {
+                "description": "Reads the balances and the gas spend fresh, updates the metrics and alerts if the POL runs low.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Wallet"
+                ],
+                "summary": "Check the signing wallet now.",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.WalletStatus"
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "abi.ABI": {
+            "type": "object",
+            "properties": {
+                "constructor": {
+                    "$ref": "#/definitions/github_com_ethereum_go-ethereum_accounts_abi.Method"
+                },
+                "errors": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "$ref": "#/definitions/abi.Error"
+                    }
+                },
+                "events": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "$ref": "#/definitions/abi.Event"
+                    }
+                },
+                "fallback": {
+                    "description": "Additional \"special\" functions introduced in solidity v0.6.0.\nIt's separated from the original default fallback. Each contract\ncan only define one fallback and receive function.",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/github_com_ethereum_go-ethereum_accounts_abi.Method"
+                        }
+                    ]
+                },
+                "methods": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "$ref": "#/definitions/github_com_ethereum_go-ethereum_accounts_abi.Method"
+                    }
+                },
+                "receive": {
+                    "$ref": "#/definitions/github_com_ethereum_go-ethereum_accounts_abi.Method"
+                }
+            }
+        },
+        "abi.Argument": {
+            "type": "object",
+            "properties": {
+                "indexed": {
+                    "description": "indexed is only used by events",
+                    "type": "boolean"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "type": {
+                    "$ref": "#/definitions/github_com_ethereum_go-ethereum_accounts_abi.Type"
+                }
+            }
+        },
+        "abi.Error": {
+            "type": "object",
+            "properties": {
+                "id": {
+                    "description": "ID returns the canonical representation of the error's signature used by the\nabi definition to identify event names and types.",
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                },
+                "inputs": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/abi.Argument"
+                    }
+                },
+                "name": {
+                    "type": "string"
+                },
+                "sig": {
+                    "description": "Sig contains the string signature according to the ABI spec.\ne.g. error foo(uint32 a, int b) = \"foo(uint32,int256)\"\nPlease note that \"int\" is substitute for its canonical representation \"int256\"",
+                    "type": "string"
+                }
+            }
+        },
+        "abi.Event": {
+            "type": "object",
+            "properties": {
+                "anonymous": {
+                    "type": "boolean"
+                },
+                "id": {
+                    "description": "ID returns the canonical representation of the event's signature used by the\nabi definition to identify event names and types.",
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                },
+                "inputs": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/abi.Argument"
+                    }
+                },
+                "name": {
+                    "description": "Name is the event name used for internal representation. It's derived from\nthe raw name and a suffix will be added in the case of event overloading.\n\ne.g.\nThese are two events that have the same name:\n* foo(int,int)\n* foo(uint,uint)\nThe event name of the first one will be resolved as foo while the second one\nwill be resolved as foo0.",
+                    "type": "string"
+                },
+                "rawName": {
+                    "description": "RawName is the raw event name parsed from ABI.",
+                    "type": "string"
+                },
+                "sig": {
+                    "description": "Sig contains the string signature according to the ABI spec.\ne.g.\t event foo(uint32 a, int b) = \"foo(uint32,int256)\"\nPlease note that \"int\" is substitute for its canonical representation \"int256\"",
+                    "type": "string"
+                }
+            }
+        },
+        "abi.FunctionType": {
+            "type": "integer",
+            "enum": [
+                0,
+                1,
+                2,
+                3
+            ],
+            "x-enum-varnames": [
+                "Constructor",
+                "Fallback",
+                "Receive",
+                "Function"
+            ]
+        },
+        "big.Int": {
+            "type": "object"
+        },
+        "blockchain.IndexedEvent": {
+            "type": "object",
+            "properties": {
+                "args": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "blockHash": {
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                },
+                "blockNumber": {
+                    "type": "integer"
+                },
+                "contract": {
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                },
+                "logIndex": {
+                    "type": "integer"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "timestamp": {
+                    "type": "integer"
+                },
+                "txHash": {
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                }
+            }
+        },
+        "blockchain.IndexerStatus": {
+            "type": "object",
+            "properties": {
+                "headBlock": {
+                    "type": "integer"
+                },
+                "lastBlock": {
+                    "type": "integer"
+                },
+                "lastError": {
+                    "type": "string"
+                },
+                "lastSync": {
+                    "type": "string"
+                },
+                "running": {
+                    "type": "boolean"
+                },
+                "sponsorships": {
+                    "type": "array",
+                    "items": {
+                        "type": "array",
+                        "items": {
+                            "type": "integer"
+                        }
+                    }
+                }
+            }
+        },
+        "blockchain.JournalEntry": {
+            "type": "object",
+            "properties": {
+                "blockNumber": {
+                    "type": "integer"
+                },
+                "gasCostWei": {
+                    "$ref": "#/definitions/big.Int"
+                },
+                "gasPriceWei": {
+                    "$ref": "#/definitions/big.Int"
+                },
+                "gasUsed": {
+                    "type": "integer"
+                },
+                "hash": {
+                    "type": "string"
+                },
+                "method": {
+                    "type": "string"
+                },
+                "minedAt": {
+                    "type": "string"
+                },
+                "nonce": {
+                    "type": "integer"
+                },
+                "params": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "sentAt": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                },
+                "to": {
+                    "type": "string"
+                }
+            }
+        },
+        "blockchain.NonceState": {
+            "type": "object",
+            "properties": {
+                "confirmed": {
+                    "description": "the nonce at the latest block",
+                    "type": "integer"
+                },
+                "inUse": {
+                    "type": "boolean"
+                },
+                "next": {
+                    "type": "integer"
+                },
+                "pending": {
+                    "description": "the node's pending nonce",
+                    "type": "integer"
+                }
+            }
+        },
+        "blockchain.TxManager": {
+            "type": "object"
+        },
+        "common.AmountDoc": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "type": "string",
+                    "example": "1500.25"
+                },
+                "wei": {
+                    "type": "string",
+                    "example": "1500250000000000000000"
+                }
+            }
+        },
+        "github_com_ethereum_go-ethereum_accounts_abi.Method": {
+            "type": "object",
+            "properties": {
+                "constant": {
+                    "description": "Legacy indicators generated by compiler before v0.6.0",
+                    "type": "boolean"
+                },
+                "id": {
+                    "description": "ID returns the canonical representation of the method's signature used by the\nabi definition to identify method names and types.",
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                },
+                "inputs": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/abi.Argument"
+                    }
+                },
+                "name": {
+                    "description": "Name is the method name used for internal representation. It's derived from\nthe raw name and a suffix will be added in the case of a function overload.\n\ne.g.\nThese are two functions that have the same name:\n* foo(int,int)\n* foo(uint,uint)\nThe method name of the first one will be resolved as foo while the second one\nwill be resolved as foo0.",
+                    "type": "string"
+                },
+                "outputs": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/abi.Argument"
+                    }
+                },
+                "payable": {
+                    "type": "boolean"
+                },
+                "rawName": {
+                    "description": "RawName is the raw method name parsed from ABI",
+                    "type": "string"
+                },
+                "sig": {
+                    "description": "Sig returns the methods string signature according to the ABI spec.\ne.g.\t\tfunction foo(uint32 a, int b) = \"foo(uint32,int256)\"\nPlease note that \"int\" is substitute for its canonical representation \"int256\"",
+                    "type": "string"
+                },
+                "stateMutability": {
+                    "description": "StateMutability indicates the mutability state of method,\nthe default value is nonpayable. It can be empty if the abi\nis generated by legacy compiler.",
+                    "type": "string"
+                },
+                "type": {
+                    "description": "Type indicates whether the method is a\nspecial fallback introduced in solidity v0.6.0",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/abi.FunctionType"
+                        }
+                    ]
+                }
+            }
+        },
+        "github_com_ethereum_go-ethereum_accounts_abi.Type": {
+            "type": "object",
+            "properties": {
+                "elem": {
+                    "$ref": "#/definitions/github_com_ethereum_go-ethereum_accounts_abi.Type"
+                },
+                "size": {
+                    "type": "integer"
+                },
+                "t": {
+                    "description": "Our own type checking",
+                    "type": "integer"
+                },
+                "tupleElems": {
+                    "description": "Type information of all tuple fields",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/github_com_ethereum_go-ethereum_accounts_abi.Type"
+                    }
+                },
+                "tupleRawName": {
+                    "description": "Tuple relative fields",
+                    "type": "string"
+                },
+                "tupleRawNames": {
+                    "description": "Raw field name of all tuple fields",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "tupleType": {
+                    "description": "Underlying struct of the tuple"
+                }
+            }
+        },
+        "handlers.NodeAddressesRequest": {
+            "type": "object",
+            "properties": {
+                "addresses": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "models.AccountingPeriod": {
+            "type": "object",
+            "properties": {
+                "accrued": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "$ref": "#/definitions/common.AmountDoc"
+                    }
+                },
+                "accruedTotal": {
+                    "$ref": "#/definitions/common.AmountDoc"
+                },
+                "delegatorsShare": {
+                    "$ref": "#/definitions/common.AmountDoc"
+                },
+                "end": {
+                    "type": "string"
+                },
+                "gasSpent": {
+                    "description": "in POL",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/common.AmountDoc"
+                        }
+                    ]
+                },
+                "operatorCut": {
+                    "$ref": "#/definitions/common.AmountDoc"
+                },
+                "protocolFee": {
+                    "$ref": "#/definitions/common.AmountDoc"
+                },
+                "start": {
+                    "type": "string"
+                },
+                "transactions": {
+                    "type": "integer"
+                },
+                "withdrawn": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "$ref": "#/definitions/common.AmountDoc"
+                    }
+                },
+                "withdrawnTotal": {
+                    "$ref": "#/definitions/common.AmountDoc"
+                }
+            }
+        },
+        "models.Alert": {
+            "type": "object",
+            "properties": {
+                "level": {
+                    "type": "string",
+                    "example": "warning"
+                },
+                "message": {
+                    "type": "string"
+                },
+                "operator": {
+                    "type": "string"
+                },
+                "source": {
+                    "type": "string",
+                    "example": "earnings-guard"
+                },
+                "timestamp": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.Allowance": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "$ref": "#/definitions/common.AmountDoc"
+                },
+                "owner": {
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                },
+                "spender": {
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                }
+            }
+        },
+        "models.ApprovalResult": {
+            "type": "object",
+            "properties": {
+                "after": {
+                    "$ref": "#/definitions/models.Allowance"
+                },
+                "before": {
+                    "$ref": "#/definitions/models.Allowance"
+                },
+                "tx": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.CompoundResult": {
+            "type": "object",
+            "properties": {
+                "allocation": {
+                    "type": "string"
+                },
+                "balance": {
+                    "description": "DATA held by the operator after the withdrawal",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/common.AmountDoc"
+                        }
+                    ]
+                },
+                "reserved": {
+                    "description": "DATA kept back for the undelegation queue",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/common.AmountDoc"
+                        }
+                    ]
+                },
+                "restaked": {
+                    "$ref": "#/definitions/common.AmountDoc"
+                },
+                "stakes": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.StakeAddition"
+                    }
+                },
+                "transactions": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "withdrawal": {
+                    "description": "tx hash, empty if there were no earnings",
+                    "type": "string"
+                }
+            }
+        },
+        "models.CronJob": {
+            "type": "object",
+            "properties": {
+                "enabled": {
+                    "type": "boolean",
+                    "example": true
+                },
+                "endpoint": {
+                    "type": "string",
+                    "example": "/api/v1/sample-endpoint"
+                },
+                "method": {
+                    "type": "string",
+                    "example": "GET"
+                },
+                "name": {
+                    "type": "string",
+                    "example": "sample job"
+                },
+                "schedule": {
+                    "type": "string",
+                    "example": "0/5 * * * * *"
+                }
+            }
+        },
+        "models.CronJobRun": {
+            "type": "object",
+            "properties": {
+                "lastError": {
+                    "type": "string"
+                },
+                "lastFailure": {
+                    "type": "string"
+                },
+                "lastSuccess": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.CutChange": {
+            "type": "object",
+            "properties": {
+                "changedAt": {
+                    "type": "string"
+                },
+                "from": {
+                    "type": "number"
+                },
+                "to": {
+                    "type": "number"
+                },
+                "txHash": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.CutChangePreview": {
+            "type": "object",
+            "properties": {
+                "changedInPeriod": {
+                    "description": "percentage points, before this change",
+                    "type": "number"
+                },
+                "current": {
+                    "type": "number"
+                },
+                "currentDelegatorApy": {
+                    "type": "number"
+                },
+                "currentOwnerEarnings": {
+                    "$ref": "#/definitions/common.AmountDoc"
+                },
+                "maxChangePercent": {
+                    "type": "number"
+                },
+                "operatorValue": {
+                    "$ref": "#/definitions/common.AmountDoc"
+                },
+                "proposed": {
+                    "type": "number"
+                },
+                "proposedDelegatorApy": {
+                    "type": "number"
+                },
+                "proposedOwnerEarnings": {
+                    "$ref": "#/definitions/common.AmountDoc"
+                },
+                "txHash": {
+                    "type": "string"
+                },
+                "warnings": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "withinLimit": {
+                    "type": "boolean"
+                },
+                "yearlyEarnings": {
+                    "$ref": "#/definitions/common.AmountDoc"
+                }
+            }
+        },
+        "models.DelegationResult": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "$ref": "#/definitions/common.AmountDoc"
+                },
+                "before": {
+                    "$ref": "#/definitions/models.SelfDelegation"
+                },
+                "method": {
+                    "type": "string"
+                },
+                "transactions": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "models.DelegatorStatement": {
+            "type": "object",
+            "properties": {
+                "delegated": {
+                    "description": "in the period",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/common.AmountDoc"
+                        }
+                    ]
+                },
+                "entries": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.StatementEntry"
+                    }
+                },
+                "from": {
+                    "type": "string"
+                },
+                "generated": {
+                    "type": "string"
+                },
+                "operator": {
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                },
+                "summary": {
+                    "$ref": "#/definitions/models.DelegatorSummary"
+                },
+                "to": {
+                    "type": "string"
+                },
+                "undelegated": {
+                    "description": "in the period",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/common.AmountDoc"
+                        }
+                    ]
+                }
+            }
+        },
+        "models.DelegatorSummary": {
+            "type": "object",
+            "properties": {
+                "delegated": {
+                    "$ref": "#/definitions/common.AmountDoc"
+                },
+                "delegator": {
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                },
+                "earnings": {
+                    "$ref": "#/definitions/common.AmountDoc"
+                },
+                "firstDelegation": {
+                    "type": "string"
+                },
+                "operatorTokens": {
+                    "$ref": "#/definitions/common.AmountDoc"
+                },
+                "pending": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.PendingUndelegation"
+                    }
+                },
+                "pendingTotal": {
+                    "$ref": "#/definitions/common.AmountDoc"
+                },
+                "share": {
+                    "description": "of all operator tokens",
+                    "type": "number"
+                },
+                "transferred": {
+                    "description": "operator tokens were transferred in or out",
+                    "type": "boolean"
+                },
+                "undelegated": {
+                    "$ref": "#/definitions/common.AmountDoc"
+                },
+                "value": {
+                    "description": "DATA value of the operator tokens",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/common.AmountDoc"
+                        }
+                    ]
+                }
+            }
+        },
+        "models.DeployedStakeResponse": {
+            "type": "object",
+            "properties": {
+                "deployedBySponsorship": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "$ref": "#/definitions/common.AmountDoc"
+                    }
+                },
+                "totalDeployed": {
+                    "$ref": "#/definitions/common.AmountDoc"
+                }
+            }
+        },
+        "models.Diagnostics": {
+            "type": "object",
+            "properties": {
+                "cronJobs": {
+                    "description": "by job ID",
+                    "type": "object",
+                    "additionalProperties": {
+                        "$ref": "#/definitions/models.CronJobRun"
+                    }
+                },
+                "operators": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.OperatorDiagnostics"
+                    }
+                },
+                "timestamp": {
+                    "type": "string"
+                },
+                "version": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.EarningsGuardAction": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string"
+                },
+                "maxAllowed": {
+                    "$ref": "#/definitions/common.AmountDoc"
+                },
+                "sponsorships": {
+                    "type": "array",
+                    "items": {
+                        "type": "array",
+                        "items": {
+                            "type": "integer"
+                        }
+                    }
+                },
+                "threshold": {
+                    "$ref": "#/definitions/common.AmountDoc"
+                },
+                "timestamp": {
+                    "type": "string"
+                },
+                "total": {
+                    "$ref": "#/definitions/common.AmountDoc"
+                },
+                "txHash": {
+                    "type": "string"
+                },
+                "withdrawn": {
+                    "$ref": "#/definitions/common.AmountDoc"
+                }
+            }
+        },
+        "models.EarningsGuardStatus": {
+            "type": "object",
+            "properties": {
+                "enabled": {
+                    "type": "boolean"
+                },
+                "interval": {
+                    "type": "string"
+                },
+                "lastCheck": {
+                    "type": "string"
+                },
+                "lastError": {
+                    "type": "string"
+                },
+                "maxAllowed": {
+                    "$ref": "#/definitions/common.AmountDoc"
+                },
+                "ratio": {
+                    "description": "total unwithdrawn earnings / maxAllowedEarnings",
+                    "type": "number"
+                },
+                "thresholdPercent": {
+                    "type": "integer"
+                },
+                "total": {
+                    "$ref": "#/definitions/common.AmountDoc"
+                }
+            }
+        },
+        "models.EarningsSnapshot": {
+            "type": "object",
+            "properties": {
+                "earnings": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "$ref": "#/definitions/common.AmountDoc"
+                    }
+                },
+                "stakes": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "$ref": "#/definitions/common.AmountDoc"
+                    }
+                },
+                "timestamp": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.ExitDecision": {
+            "type": "object",
+            "properties": {
+                "action": {
+                    "type": "string"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "leavePenalty": {
+                    "$ref": "#/definitions/common.AmountDoc"
+                },
+                "metrics": {
+                    "$ref": "#/definitions/models.SponsorshipMetrics"
+                },
+                "penaltyFreeAt": {
+                    "description": "end of the minimum staking period",
+                    "type": "string"
+                },
+                "reasons": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "sponsorship": {
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                },
+                "unstake": {
+                    "description": "tx hash",
+                    "type": "string"
+                },
+                "withdrawal": {
+                    "description": "tx hash",
+                    "type": "string"
+                }
+            }
+        },
+        "models.ExitReport": {
+            "type": "object",
+            "properties": {
+                "decisions": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.ExitDecision"
+                    }
+                },
+                "dryRun": {
+                    "type": "boolean"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "freed": {
+                    "description": "DATA the exits returned to the operator",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/common.AmountDoc"
+                        }
+                    ]
+                },
+                "redeployed": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.StakeAddition"
+                    }
+                },
+                "reserved": {
+                    "description": "DATA kept back for the undelegation queue",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/common.AmountDoc"
+                        }
+                    ]
+                },
+                "timestamp": {
+                    "type": "string"
+                },
+                "transactions": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "models.FeeParameters": {
+            "type": "object",
+            "properties": {
+                "changedInPeriod": {
+                    "description": "percentage points changed in the current period",
+                    "type": "number"
+                },
+                "maxChangePercent": {
+                    "description": "0 means no limit",
+                    "type": "number"
+                },
+                "minimumSelfDelegation": {
+                    "description": "share of operator tokens the owner must hold",
+                    "type": "number"
+                },
+                "minimumStake": {
+                    "description": "per sponsorship",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/common.AmountDoc"
+                        }
+                    ]
+                },
+                "operatorsCut": {
+                    "description": "share of the earnings after the protocol fee the owner takes",
+                    "type": "number"
+                },
+                "periodDays": {
+                    "type": "number"
+                },
+                "protocolFee": {
+                    "description": "share of the earnings the protocol takes on withdrawal",
+                    "type": "number"
+                }
+            }
+        },
+        "models.FieldChange": {
+            "type": "object",
+            "properties": {
+                "current": {},
+                "field": {
+                    "type": "string"
+                },
+                "proposed": {}
+            }
+        },
+        "models.GetSponsorshipsAndEarningsResponse": {
+            "type": "object",
+            "properties": {
+                "addresses": {
+                    "type": "array",
+                    "items": {
+                        "type": "array",
+                        "items": {
+                            "type": "integer"
+                        }
+                    }
+                },
+                "earnings": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/common.AmountDoc"
+                    }
+                },
+                "maxAllowedEarnings": {
+                    "$ref": "#/definitions/common.AmountDoc"
+                }
+            }
+        },
+        "models.LedgerRow": {
+            "type": "object",
+            "properties": {
+                "action": {
+                    "type": "string"
+                },
+                "amount": {
+                    "$ref": "#/definitions/common.AmountDoc"
+                },
+                "block": {
+                    "type": "integer"
+                },
+                "counterparty": {
+                    "description": "the sponsorship(s), or the delegator for payouts",
+                    "type": "string"
+                },
+                "gasCost": {
+                    "$ref": "#/definitions/common.AmountDoc"
+                },
+                "protocolFee": {
+                    "$ref": "#/definitions/common.AmountDoc"
+                },
+                "status": {
+                    "type": "string"
+                },
+                "timestamp": {
+                    "type": "string"
+                },
+                "txHash": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.MetadataChange": {
+            "type": "object",
+            "properties": {
+                "current": {
+                    "$ref": "#/definitions/models.OperatorMetadata"
+                },
+                "diff": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.FieldChange"
+                    }
+                },
+                "proposed": {
+                    "type": "string"
+                },
+                "tx": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.MetadataUpdate": {
+            "type": "object",
+            "properties": {
+                "description": {
+                    "type": "string"
+                },
+                "imageUrl": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "redundancyFactor": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.NodeAddressChange": {
+            "type": "object",
+            "properties": {
+                "added": {
+                    "type": "array",
+                    "items": {
+                        "type": "array",
+                        "items": {
+                            "type": "integer"
+                        }
+                    }
+                },
+                "current": {
+                    "type": "array",
+                    "items": {
+                        "type": "array",
+                        "items": {
+                            "type": "integer"
+                        }
+                    }
+                },
+                "proposed": {
+                    "type": "array",
+                    "items": {
+                        "type": "array",
+                        "items": {
+                            "type": "integer"
+                        }
+                    }
+                },
+                "removed": {
+                    "type": "array",
+                    "items": {
+                        "type": "array",
+                        "items": {
+                            "type": "integer"
+                        }
+                    }
+                },
+                "tx": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.NodeInfo": {
+            "type": "object",
+            "properties": {
+                "address": {
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                },
+                "heartbeat": {
+                    "description": "JSON the node sent with it",
+                    "type": "string"
+                },
+                "lastHeartbeat": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.Operator": {
+            "type": "object",
+            "properties": {
+                "contractAbi": {
+                    "$ref": "#/definitions/abi.ABI"
+                },
+                "contractAddr": {
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                },
+                "name": {
+                    "type": "string"
+                },
+                "ownerAddr": {
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                },
+                "txManager": {
+                    "$ref": "#/definitions/blockchain.TxManager"
+                }
+            }
+        },
+        "models.OperatorDiagnostics": {
+            "type": "object",
+            "properties": {
+                "blockLagSeconds": {
+                    "description": "wall clock minus the latest block time",
+                    "type": "number"
+                },
+                "blockNumber": {
+                    "type": "integer"
+                },
+                "blockTime": {
+                    "type": "string"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "nonce": {
+                    "$ref": "#/definitions/blockchain.NonceState"
+                },
+                "pendingTxs": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.OperatorInfo": {
+            "type": "object",
+            "properties": {
+                "contract": {
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                },
+                "name": {
+                    "type": "string"
+                },
+                "owner": {
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                },
+                "signer": {
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                }
+            }
+        },
+        "models.OperatorMetadata": {
+            "type": "object",
+            "properties": {
+                "fields": {
+                    "type": "object",
+                    "additionalProperties": true
+                },
+                "raw": {
+                    "type": "string"
+                },
+                "tx": {
+                    "description": "transaction that set it",
+                    "type": "string"
+                },
+                "updatedAt": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.OperatorPosition": {
+            "type": "object",
+            "properties": {
+                "contract": {
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                },
+                "deployedStake": {
+                    "$ref": "#/definitions/common.AmountDoc"
+                },
+                "earnings": {
+                    "description": "unwithdrawn",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/common.AmountDoc"
+                        }
+                    ]
+                },
+                "error": {
+                    "type": "string"
+                },
+                "maxAllowedEarnings": {
+                    "$ref": "#/definitions/common.AmountDoc"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "owner": {
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                },
+                "signer": {
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                },
+                "sponsorships": {
+                    "type": "integer"
+                },
+                "value": {
+                    "description": "valueWithoutEarnings",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/common.AmountDoc"
+                        }
+                    ]
+                }
+            }
+        },
+        "models.PendingUndelegation": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "description": "as requested, the payout is capped at the delegator's balance",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/common.AmountDoc"
+                        }
+                    ]
+                },
+                "queuedAt": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.Portfolio": {
+            "type": "object",
+            "properties": {
+                "operators": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.OperatorPosition"
+                    }
+                },
+                "sponsorships": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.SponsorshipExposure"
+                    }
+                },
+                "timestamp": {
+                    "type": "string"
+                },
+                "totalEarnings": {
+                    "$ref": "#/definitions/common.AmountDoc"
+                },
+                "totalStake": {
+                    "$ref": "#/definitions/common.AmountDoc"
+                },
+                "totalValue": {
+                    "$ref": "#/definitions/common.AmountDoc"
+                }
+            }
+        },
+        "models.QueuePlan": {
+            "type": "object",
+            "properties": {
+                "balance": {
+                    "description": "DATA held by the operator contract",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/common.AmountDoc"
+                        }
+                    ]
+                },
+                "earnings": {
+                    "description": "unwithdrawn earnings after the protocol fee",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/common.AmountDoc"
+                        }
+                    ]
+                },
+                "entries": {
+                    "type": "integer"
+                },
+                "pending": {
+                    "description": "DATA the queue needs, capped at each delegator's balance",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/common.AmountDoc"
+                        }
+                    ]
+                },
+                "policy": {
+                    "type": "string"
+                },
+                "reductions": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.StakeReduction"
+                    }
+                },
+                "shortfall": {
+                    "description": "what can't be freed without leaving sponsorships",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/common.AmountDoc"
+                        }
+                    ]
+                },
+                "timestamp": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.QueueServiceReport": {
+            "type": "object",
+            "properties": {
+                "balance": {
+                    "description": "DATA held by the operator contract",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/common.AmountDoc"
+                        }
+                    ]
+                },
+                "earnings": {
+                    "description": "unwithdrawn earnings after the protocol fee",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/common.AmountDoc"
+                        }
+                    ]
+                },
+                "entries": {
+                    "type": "integer"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "pending": {
+                    "description": "DATA the queue needs, capped at each delegator's balance",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/common.AmountDoc"
+                        }
+                    ]
+                },
+                "policy": {
+                    "type": "string"
+                },
+                "reductions": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.StakeReduction"
+                    }
+                },
+                "shortfall": {
+                    "description": "what can't be freed without leaving sponsorships",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/common.AmountDoc"
+                        }
+                    ]
+                },
+                "timestamp": {
+                    "type": "string"
+                },
+                "transactions": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "models.RebalanceMove": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "description": "always positive, the direction is given by the method",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/common.AmountDoc"
+                        }
+                    ]
+                },
+                "current": {
+                    "$ref": "#/definitions/common.AmountDoc"
+                },
+                "method": {
+                    "description": "reduceStakeTo or stake",
+                    "type": "string",
+                    "example": "reduceStakeTo"
+                },
+                "sponsorship": {
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                },
+                "target": {
+                    "$ref": "#/definitions/common.AmountDoc"
+                },
+                "txHash": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.RebalancePlan": {
+            "type": "object",
+            "properties": {
+                "available": {
+                    "description": "free DATA not reserved for the undelegation queue",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/common.AmountDoc"
+                        }
+                    ]
+                },
+                "error": {
+                    "type": "string"
+                },
+                "moves": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.RebalanceMove"
+                    }
+                },
+                "skipped": {
+                    "description": "within tolerance or over the move limit",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.RebalanceMove"
+                    }
+                },
+                "targets": {
+                    "$ref": "#/definitions/models.RebalanceTargets"
+                },
+                "timestamp": {
+                    "type": "string"
+                },
+                "total": {
+                    "description": "staked plus free DATA, minus what the undelegation queue needs",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/common.AmountDoc"
+                        }
+                    ]
+                }
+            }
+        },
+        "models.RebalanceTargets": {
+            "type": "object",
+            "properties": {
+                "maxMoves": {
+                    "description": "stake and reduceStakeTo transactions per run, 0 for no limit",
+                    "type": "integer",
+                    "example": 5
+                },
+                "mode": {
+                    "type": "string",
+                    "example": "weights"
+                },
+                "tolerancePercent": {
+                    "description": "drift of the operator's value ignored per sponsorship",
+                    "type": "number",
+                    "example": 5
+                },
+                "weights": {
+                    "description": "relative, only used by the weights mode",
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "number"
+                    }
+                }
+            }
+        },
+        "models.Review": {
+            "type": "object",
+            "properties": {
+                "metadata": {
+                    "type": "string"
+                },
+                "requestTx": {
+                    "type": "string"
+                },
+                "sponsorship": {
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                },
+                "status": {
+                    "type": "string"
+                },
+                "target": {
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                },
+                "vote": {
+                    "$ref": "#/definitions/models.ReviewVote"
+                },
+                "voteEnd": {
+                    "type": "string"
+                },
+                "voteStart": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.ReviewVote": {
+            "type": "object",
+            "properties": {
+                "kick": {
+                    "type": "boolean"
+                },
+                "source": {
+                    "description": "api or the policy that cast it",
+                    "type": "string"
+                },
+                "status": {
+                    "description": "sent or mined",
+                    "type": "string"
+                },
+                "txHash": {
+                    "type": "string"
+                },
+                "votedAt": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.Scheduler": {
+            "type": "object",
+            "properties": {
+                "jobs": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "$ref": "#/definitions/models.CronJob"
+                    }
+                }
+            }
+        },
+        "models.SelfDelegation": {
+            "type": "object",
+            "properties": {
+                "fraction": {
+                    "description": "owner's share of the operator tokens",
+                    "type": "number"
+                },
+                "maxUndelegation": {
+                    "description": "DATA the owner can undelegate and stay above the minimum",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/common.AmountDoc"
+                        }
+                    ]
+                },
+                "minimumFraction": {
+                    "description": "minimumSelfDelegationFraction of the protocol",
+                    "type": "number"
+                },
+                "operatorTokens": {
+                    "$ref": "#/definitions/common.AmountDoc"
+                },
+                "owner": {
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                },
+                "queued": {
+                    "description": "DATA of the owner's undelegations waiting in the queue",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/common.AmountDoc"
+                        }
+                    ]
+                },
+                "totalSupply": {
+                    "$ref": "#/definitions/common.AmountDoc"
+                },
+                "value": {
+                    "description": "DATA value of the operator tokens",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/common.AmountDoc"
+                        }
+                    ]
+                },
+                "walletBalance": {
+                    "description": "DATA in the owner wallet",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/common.AmountDoc"
+                        }
+                    ]
+                }
+            }
+        },
+        "models.SignerStatus": {
+            "type": "object",
+            "properties": {
+                "actions": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "boolean"
+                    }
+                },
+                "checkedAt": {
+                    "type": "string"
+                },
+                "configuredOwner": {
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                },
+                "contractOwner": {
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                },
+                "controllerRole": {
+                    "type": "boolean"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "nodeAddress": {
+                    "type": "boolean"
+                },
+                "ownerRole": {
+                    "type": "boolean"
+                },
+                "problems": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "rolesCheckedAt": {
+                    "type": "string"
+                },
+                "signer": {
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                },
+                "signerIsOwner": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "models.SponsorshipEarnings": {
+            "type": "object",
+            "properties": {
+                "earnings": {
+                    "$ref": "#/definitions/common.AmountDoc"
+                },
+                "sponsorship": {
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                }
+            }
+        },
+        "models.SponsorshipExposure": {
+            "type": "object",
+            "properties": {
+                "operators": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "sponsorship": {
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                },
+                "stake": {
+                    "$ref": "#/definitions/common.AmountDoc"
+                }
+            }
+        },
+        "models.SponsorshipMetrics": {
+            "type": "object",
+            "properties": {
+                "additionalStake": {
+                    "$ref": "#/definitions/common.AmountDoc"
+                },
+                "currentApy": {
+                    "description": "what the stake already in the sponsorship earns, same units",
+                    "type": "number"
+                },
+                "fundedUntil": {
+                    "type": "string"
+                },
+                "isRunning": {
+                    "type": "boolean"
+                },
+                "maxOperators": {
+                    "description": "0 if the subgraph doesn't say",
+                    "type": "integer"
+                },
+                "minimumStakingPeriodSeconds": {
+                    "type": "integer"
+                },
+                "operatorCount": {
+                    "type": "integer"
+                },
+                "ownStake": {
+                    "description": "the operator's current stake",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/common.AmountDoc"
+                        }
+                    ]
+                },
+                "payoutPerSecond": {
+                    "description": "paid out to all operators together",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/common.AmountDoc"
+                        }
+                    ]
+                },
+                "projectedApy": {
+                    "description": "annualized, after the protocol fee, e.g. 0.15 for 15%",
+                    "type": "number"
+                },
+                "remaining": {
+                    "description": "unallocated sponsorship funding",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/common.AmountDoc"
+                        }
+                    ]
+                },
+                "runwaySeconds": {
+                    "description": "until the funding runs out, -1 if it is not being paid out",
+                    "type": "integer"
+                },
+                "source": {
+                    "description": "chain, subgraph or subgraph+chain",
+                    "type": "string"
+                },
+                "sponsorship": {
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                },
+                "stream": {
+                    "type": "string"
+                },
+                "totalStake": {
+                    "$ref": "#/definitions/common.AmountDoc"
+                },
+                "updated": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.SponsorshipYield": {
+            "type": "object",
+            "properties": {
+                "accrued": {
+                    "$ref": "#/definitions/common.AmountDoc"
+                },
+                "accruedYield": {
+                    "description": "annualized accrued earnings / average stake",
+                    "type": "number"
+                },
+                "averageStake": {
+                    "$ref": "#/definitions/common.AmountDoc"
+                },
+                "realizedYield": {
+                    "description": "annualized withdrawn earnings / average stake",
+                    "type": "number"
+                },
+                "sponsorship": {
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                },
+                "withdrawn": {
+                    "$ref": "#/definitions/common.AmountDoc"
+                }
+            }
+        },
+        "models.StakeAddition": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "$ref": "#/definitions/common.AmountDoc"
+                },
+                "sponsorship": {
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                }
+            }
+        },
+        "models.StakeReduction": {
+            "type": "object",
+            "properties": {
+                "reduce": {
+                    "$ref": "#/definitions/common.AmountDoc"
+                },
+                "sponsorship": {
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                },
+                "stake": {
+                    "$ref": "#/definitions/common.AmountDoc"
+                },
+                "target": {
+                    "$ref": "#/definitions/common.AmountDoc"
+                },
+                "yield": {
+                    "description": "annualized accrued yield over the last 30 days, if known",
+                    "type": "number"
+                }
+            }
+        },
+        "models.StakedIntoResponse": {
+            "type": "object",
+            "properties": {
+                "stakedInto": {
+                    "$ref": "#/definitions/common.AmountDoc"
+                }
+            }
+        },
+        "models.StatementEntry": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "description": "DATA for delegations and undelegations, operator tokens for transfers",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/common.AmountDoc"
+                        }
+                    ]
+                },
+                "block": {
+                    "type": "integer"
+                },
+                "counterparty": {
+                    "type": "string"
+                },
+                "timestamp": {
+                    "type": "string"
                 },
-                "name": {
+                "txHash": {
                     "type": "string"
                 },
                 "type": {
-                    "$ref": "#/definitions/github_com_ethereum_go-ethereum_accounts_abi.Type"
+                    "type": "string"
                 }
             }
         },
-        "abi.Error": {
+        "models.TokenBalances": {
             "type": "object",
             "properties": {
-                "id": {
-                    "description": "ID returns the canonical representation of the error's signature used by the\nabi definition to identify event names and types.",
-                    "type": "array",
-                    "items": {
-                        "type": "integer"
-                    }
-                },
-                "inputs": {
-                    "type": "array",
-                    "items": {
-                        "$ref": "#/definitions/abi.Argument"
-                    }
+                "operator": {
+                    "description": "free to stake or pay out, not staked in sponsorships",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/common.AmountDoc"
+                        }
+                    ]
                 },
-                "name": {
-                    "type": "string"
+                "owner": {
+                    "$ref": "#/definitions/common.AmountDoc"
                 },
-                "sig": {
-                    "description": "Sig contains the string signature according to the ABI spec.\ne.g. error foo(uint32 a, int b) = \"foo(uint32,int256)\"\nPlease note that \"int\" is substitute for its canonical representation \"int256\"",
-                    "type": "string"
+                "signer": {
+                    "description": "the wallet transactions are signed with, usually the owner",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/common.AmountDoc"
+                        }
+                    ]
                 }
             }
         },
-        "abi.Event": {
+        "models.TokenInfo": {
             "type": "object",
             "properties": {
-                "anonymous": {
-                    "type": "boolean"
-                },
-                "id": {
-                    "description": "ID returns the canonical representation of the event's signature used by the\nabi definition to identify event names and types.",
+                "address": {
                     "type": "array",
                     "items": {
                         "type": "integer"
                     }
                 },
-                "inputs": {
-                    "type": "array",
-                    "items": {
-                        "$ref": "#/definitions/abi.Argument"
-                    }
+                "decimals": {
+                    "type": "integer"
                 },
-                "name": {
-                    "description": "Name is the event name used for internal representation. It's derived from\nthe raw name and a suffix will be added in the case of event overloading.\n\ne.g.\nThese are two events that have the same name:\n* foo(int,int)\n* foo(uint,uint)\nThe event name of the first one will be resolved as foo while the second one\nwill be resolved as foo0.",
+                "source": {
+                    "description": "config (DATA_TOKEN_ADDRESS) or operator (its token())",
                     "type": "string"
                 },
-                "rawName": {
-                    "description": "RawName is the raw event name parsed from ABI.",
+                "symbol": {
                     "type": "string"
                 },
-                "sig": {
-                    "description": "Sig contains the string signature according to the ABI spec.\ne.g.\t event foo(uint32 a, int b) = \"foo(uint32,int256)\"\nPlease note that \"int\" is substitute for its canonical representation \"int256\"",
-                    "type": "string"
+                "totalSupply": {
+                    "$ref": "#/definitions/common.AmountDoc"
                 }
             }
         },
-        "abi.FunctionType": {
-            "type": "integer",
-            "enum": [
-                0,
-                1,
-                2,
-                3
-            ],
-            "x-enum-varnames": [
-                "Constructor",
-                "Fallback",
-                "Receive",
-                "Function"
-            ]
-        },
-        "big.Int": {
-            "type": "object"
-        },
-        "blockchain.TxManager": {
-            "type": "object"
-        },
-        "ecdsa.PrivateKey": {
+        "models.UndelegationRecordResponse": {
             "type": "object",
             "properties": {
-                "d": {
-                    "$ref": "#/definitions/big.Int"
+                "amountWei": {
+                    "$ref": "#/definitions/common.AmountDoc"
+                },
+                "delegator": {
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
                 },
-                "elliptic.Curve": {},
-                "x": {
+                "timestamp": {
                     "$ref": "#/definitions/big.Int"
                 }
             }
         },
-        "github_com_ethereum_go-ethereum_accounts_abi.Method": {
+        "models.UnstakePreview": {
             "type": "object",
             "properties": {
-                "constant": {
-                    "description": "Legacy indicators generated by compiler before v0.6.0",
+                "canUnstake": {
+                    "description": "a normal unstake would go through",
                     "type": "boolean"
                 },
-                "id": {
-                    "description": "ID returns the canonical representation of the method's signature used by the\nabi definition to identify method names and types.",
-                    "type": "array",
-                    "items": {
-                        "type": "integer"
-                    }
-                },
-                "inputs": {
-                    "type": "array",
-                    "items": {
-                        "$ref": "#/definitions/abi.Argument"
-                    }
-                },
-                "name": {
-                    "description": "Name is the method name used for internal representation. It's derived from\nthe raw name and a suffix will be added in the case of a function overload.\n\ne.g.\nThese are two functions that have the same name:\n* foo(int,int)\n* foo(uint,uint)\nThe method name of the first one will be resolved as foo while the second one\nwill be resolved as foo0.",
-                    "type": "string"
-                },
-                "outputs": {
-                    "type": "array",
-                    "items": {
-                        "$ref": "#/definitions/abi.Argument"
-                    }
+                "earnings": {
+                    "description": "paid out along with the stake",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/common.AmountDoc"
+                        }
+                    ]
                 },
-                "payable": {
+                "forced": {
                     "type": "boolean"
                 },
-                "rawName": {
-                    "description": "RawName is the raw method name parsed from ABI",
-                    "type": "string"
+                "forfeit": {
+                    "description": "lost by a forced unstake",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/common.AmountDoc"
+                        }
+                    ]
                 },
-                "sig": {
-                    "description": "Sig returns the methods string signature according to the ABI spec.\ne.g.\t\tfunction foo(uint32 a, int b) = \"foo(uint32,int256)\"\nPlease note that \"int\" is substitute for its canonical representation \"int256\"",
+                "joinedAt": {
                     "type": "string"
                 },
-                "stateMutability": {
-                    "description": "StateMutability indicates the mutability state of method,\nthe default value is nonpayable. It can be empty if the abi\nis generated by legacy compiler.",
-                    "type": "string"
+                "leavePenalty": {
+                    "$ref": "#/definitions/common.AmountDoc"
                 },
-                "type": {
-                    "description": "Type indicates whether the method is a\nspecial fallback introduced in solidity v0.6.0",
+                "lockedStake": {
+                    "description": "committed to open flags",
                     "allOf": [
                         {
-                            "$ref": "#/definitions/abi.FunctionType"
+                            "$ref": "#/definitions/common.AmountDoc"
                         }
                     ]
-                }
-            }
-        },
-        "github_com_ethereum_go-ethereum_accounts_abi.Type": {
-            "type": "object",
-            "properties": {
-                "elem": {
-                    "$ref": "#/definitions/github_com_ethereum_go-ethereum_accounts_abi.Type"
                 },
-                "size": {
+                "maxQueuePayouts": {
+                    "description": "queue entries a forced unstake pays out, 0 for all",
                     "type": "integer"
                 },
-                "t": {
-                    "description": "Our own type checking",
-                    "type": "integer"
+                "needsConfirmation": {
+                    "description": "a forced unstake would forfeit stake",
+                    "type": "boolean"
                 },
-                "tupleElems": {
-                    "description": "Type information of all tuple fields",
+                "penaltyFreeAt": {
+                    "description": "end of the minimum staking period, if still ahead",
+                    "type": "string"
+                },
+                "returned": {
+                    "description": "stake returned to the operator",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/common.AmountDoc"
+                        }
+                    ]
+                },
+                "sponsorship": {
                     "type": "array",
                     "items": {
-                        "$ref": "#/definitions/github_com_ethereum_go-ethereum_accounts_abi.Type"
+                        "type": "integer"
                     }
                 },
-                "tupleRawName": {
-                    "description": "Tuple relative fields",
+                "stake": {
+                    "$ref": "#/definitions/common.AmountDoc"
+                },
+                "txHash": {
                     "type": "string"
                 },
-                "tupleRawNames": {
-                    "description": "Raw field name of all tuple fields",
+                "warnings": {
                     "type": "array",
                     "items": {
                         "type": "string"
                     }
-                },
-                "tupleType": {
-                    "description": "Underlying struct of the tuple"
                 }
             }
         },
-        "models.CronJob": {
+        "models.WalletStatus": {
             "type": "object",
             "properties": {
-                "enabled": {
-                    "type": "boolean",
-                    "example": true
+                "address": {
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
                 },
-                "endpoint": {
-                    "type": "string",
-                    "example": "/api/v1/sample-endpoint"
+                "dailyGas": {
+                    "description": "average POL spent on gas per day",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/common.AmountDoc"
+                        }
+                    ]
                 },
-                "method": {
-                    "type": "string",
-                    "example": "GET"
+                "data": {
+                    "$ref": "#/definitions/common.AmountDoc"
                 },
-                "name": {
-                    "type": "string",
-                    "example": "sample job"
+                "daysCovered": {
+                    "description": "-1 when nothing was spent, so there is no estimate",
+                    "type": "number"
                 },
-                "schedule": {
-                    "type": "string",
-                    "example": "0/5 * * * * *"
+                "gasSpent": {
+                    "description": "POL paid for gas within the window",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/common.AmountDoc"
+                        }
+                    ]
+                },
+                "lastCheck": {
+                    "type": "string"
+                },
+                "lastError": {
+                    "type": "string"
+                },
+                "level": {
+                    "description": "ok, warning or critical",
+                    "type": "string"
+                },
+                "pol": {
+                    "$ref": "#/definitions/common.AmountDoc"
+                },
+                "transactions": {
+                    "description": "mined or reverted within the window",
+                    "type": "integer"
+                },
+                "windowDays": {
+                    "description": "span the spend is averaged over, shorter than configured while the journal is young",
+                    "type": "number"
                 }
             }
         },
-        "models.DeployedStakeResponse": {
+        "models.WithdrawalRecord": {
             "type": "object",
             "properties": {
-                "deployedBySponsorship": {
+                "earnings": {
                     "type": "object",
                     "additionalProperties": {
-                        "$ref": "#/definitions/big.Int"
+                        "$ref": "#/definitions/common.AmountDoc"
                     }
                 },
-                "totalDeployed": {
-                    "$ref": "#/definitions/big.Int"
+                "external": {
+                    "description": "not sent by the service",
+                    "type": "boolean"
+                },
+                "feeEstimated": {
+                    "description": "true until the Profit event of the tx is indexed",
+                    "type": "boolean"
+                },
+                "operatorCut": {
+                    "$ref": "#/definitions/common.AmountDoc"
+                },
+                "protocolFee": {
+                    "$ref": "#/definitions/common.AmountDoc"
+                },
+                "timestamp": {
+                    "type": "string"
+                },
+                "total": {
+                    "$ref": "#/definitions/common.AmountDoc"
+                },
+                "txHash": {
+                    "type": "string"
                 }
             }
         },
-        "models.GetSponsorshipsAndEarningsResponse": {
+        "models.WithdrawalResult": {
             "type": "object",
             "properties": {
-                "addresses": {
+                "actual": {
                     "type": "array",
                     "items": {
-                        "type": "array",
-                        "items": {
-                            "type": "integer"
-                        }
+                        "$ref": "#/definitions/models.SponsorshipEarnings"
                     }
                 },
-                "earnings": {
-                    "type": "array",
-                    "items": {
-                        "$ref": "#/definitions/big.Int"
-                    }
+                "actualTotal": {
+                    "$ref": "#/definitions/common.AmountDoc"
                 },
-                "maxAllowedEarnings": {
-                    "$ref": "#/definitions/big.Int"
-                }
-            }
-        },
-        "models.Operator": {
-            "type": "object",
-            "properties": {
-                "contractAbi": {
-                    "$ref": "#/definitions/abi.ABI"
+                "expectedTotal": {
+                    "$ref": "#/definitions/common.AmountDoc"
                 },
-                "contractAddr": {
+                "operatorCut": {
+                    "$ref": "#/definitions/common.AmountDoc"
+                },
+                "protocolFee": {
+                    "$ref": "#/definitions/common.AmountDoc"
+                },
+                "selected": {
                     "type": "array",
                     "items": {
-                        "type": "integer"
+                        "$ref": "#/definitions/models.SponsorshipEarnings"
                     }
                 },
-                "ownerAddr": {
+                "skipped": {
                     "type": "array",
                     "items": {
-                        "type": "integer"
+                        "$ref": "#/definitions/models.SponsorshipEarnings"
                     }
                 },
-                "privateKey": {
-                    "$ref": "#/definitions/ecdsa.PrivateKey"
+                "status": {
+                    "description": "pending, mined or failed; empty for a dry run",
+                    "type": "string"
                 },
-                "txManager": {
-                    "$ref": "#/definitions/blockchain.TxManager"
-                }
-            }
-        },
-        "models.Scheduler": {
-            "type": "object",
-            "properties": {
-                "jobs": {
-                    "type": "object",
-                    "additionalProperties": {
-                        "$ref": "#/definitions/models.CronJob"
-                    }
-                }
-            }
-        },
-        "models.StakedIntoResponse": {
-            "type": "object",
-            "properties": {
-                "stakedInto": {
-                    "$ref": "#/definitions/big.Int"
+                "txHash": {
+                    "type": "string"
                 }
             }
         }
@@ -0,0 +1,198 @@
+package handlers
+
+import (
+	"net/http"
+
+	"streamr_api/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AccountingSummary godoc
+// @Summary      Get earnings and P&L summaries.
+// @Description  Responds with accrued and withdrawn earnings per sponsorship, protocol fee, operator's cut, delegators' share and gas spent per period.
+// @Tags         Accounting
+// @Produce      json
+// @Param        period  query     string  false  "daily (default), weekly or monthly"
+// @Param        from    query     string  false  "start time, RFC3339, YYYY-MM-DD or unix seconds"
+// @Param        to      query     string  false  "end time, RFC3339, YYYY-MM-DD or unix seconds"
+// @Success      200  {array}  models.AccountingPeriod
+// @Router       /accounting/summary [get]
+func AccountingSummary(o *models.Operator) gin.HandlerFunc {
+	fn := func(c *gin.Context) {
+		if o.Accounting == nil {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "accounting is not running"})
+			return
+		}
+
+		period := c.DefaultQuery("period", models.PeriodDaily)
+		if period != models.PeriodDaily && period != models.PeriodWeekly && period != models.PeriodMonthly {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid period"})
+			return
+		}
+		from, err := parseTimeQuery(c, "from")
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		to, err := parseTimeQuery(c, "to")
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		result, err := o.Accounting.Summaries(period, from, to)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, result)
+	}
+
+	return gin.HandlerFunc(fn)
+}
+
+// AccountingYield godoc
+// @Summary      Get the yield per sponsorship.
+// @Description  Responds with the annualized accrued and realized yield of each sponsorship relative to its average stake.
+// @Tags         Accounting
+// @Produce      json
+// @Param        from    query     string  false  "start time, RFC3339, YYYY-MM-DD or unix seconds"
+// @Param        to      query     string  false  "end time, RFC3339, YYYY-MM-DD or unix seconds"
+// @Success      200  {array}  models.SponsorshipYield
+// @Router       /accounting/yield [get]
+func AccountingYield(o *models.Operator) gin.HandlerFunc {
+	fn := func(c *gin.Context) {
+		if o.Accounting == nil {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "accounting is not running"})
+			return
+		}
+
+		from, err := parseTimeQuery(c, "from")
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		to, err := parseTimeQuery(c, "to")
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		result, err := o.Accounting.Yield(from, to)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, result)
+	}
+
+	return gin.HandlerFunc(fn)
+}
+
+// AccountingWithdrawals godoc
+// @Summary      List recorded earnings withdrawals.
+// @Description  Responds with the withdrawals sent by the service, with the earnings withdrawn per sponsorship and the fee split, and the ones sent outside the service as detected from the earnings snapshots, marked external.
+// @Tags         Accounting
+// @Produce      json
+// @Param        from    query     string  false  "start time, RFC3339, YYYY-MM-DD or unix seconds"
+// @Param        to      query     string  false  "end time, RFC3339, YYYY-MM-DD or unix seconds"
+// @Success      200  {array}  models.WithdrawalRecord
+// @Router       /accounting/withdrawals [get]
+func AccountingWithdrawals(o *models.Operator) gin.HandlerFunc {
+	fn := func(c *gin.Context) {
+		if o.Accounting == nil {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "accounting is not running"})
+			return
+		}
+
+		from, err := parseTimeQuery(c, "from")
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		to, err := parseTimeQuery(c, "to")
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		result, err := o.Accounting.Withdrawals(from, to)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, result)
+	}
+
+	return gin.HandlerFunc(fn)
+}
+
+// AccountingSnapshot godoc
+// @Summary      Take an earnings snapshot.
+// @Description  Records the current earnings and stake of every sponsorship. Snapshots are also taken periodically in the background.
+// @Tags         Accounting
+// @Produce      json
+// @Success      200  {object}  models.EarningsSnapshot
+// @Router       /accounting/snapshot [get]
+func AccountingSnapshot(o *models.Operator) gin.HandlerFunc {
+	fn := func(c *gin.Context) {
+		if o.Accounting == nil {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "accounting is not running"})
+			return
+		}
+
+		result, err := o.Accounting.Snapshot()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, result)
+	}
+
+	return gin.HandlerFunc(fn)
+}
+
+// Transactions godoc
+// @Summary      List the transactions sent by the service.
+// @Description  Responds with the tx journal: method, parameters, status and gas cost of every transaction sent.
+// @Tags         Operator
+// @Produce      json
+// @Param        from    query     string  false  "start time, RFC3339, YYYY-MM-DD or unix seconds"
+// @Param        to      query     string  false  "end time, RFC3339, YYYY-MM-DD or unix seconds"
+// @Success      200  {array}  blockchain.JournalEntry
+// @Router       /operator/transactions [get]
+func Transactions(o *models.Operator) gin.HandlerFunc {
+	fn := func(c *gin.Context) {
+		journal := o.TxManager.Journal()
+		if journal == nil {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "tx journal is not enabled"})
+			return
+		}
+
+		from, err := parseTimeQuery(c, "from")
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		to, err := parseTimeQuery(c, "to")
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		result, err := journal.List(from, to)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, result)
+	}
+
+	return gin.HandlerFunc(fn)
+}
@@ -0,0 +1,48 @@
+package handlers
+
+import (
+	"net/http"
+
+	"streamr_api/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Alerts godoc
+// @Summary      List alerts.
+// @Description  Responds with the alerts raised by the background services, oldest first.
+// @Tags         Alerts
+// @Produce      json
+// @Param        from    query     string  false  "start time, RFC3339, YYYY-MM-DD or unix seconds"
+// @Param        to      query     string  false  "end time, RFC3339, YYYY-MM-DD or unix seconds"
+// @Success      200  {array}  models.Alert
+// @Router       /alerts [get]
+func Alerts(o *models.Operator) gin.HandlerFunc {
+	fn := func(c *gin.Context) {
+		if o.Alerts == nil {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "alerts are not enabled"})
+			return
+		}
+
+		from, err := parseTimeQuery(c, "from")
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		to, err := parseTimeQuery(c, "to")
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		result, err := o.Alerts.Alerts(from, to)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, result)
+	}
+
+	return gin.HandlerFunc(fn)
+}
@@ -0,0 +1,36 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"streamr_api/config"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequireAPIKey rejects requests without one of the keys, given in the X-API-Key header or as a
+// bearer token. Without keys every request is let through.
+func RequireAPIKey(keys []string) gin.HandlerFunc {
+	fn := func(c *gin.Context) {
+		if len(keys) == 0 {
+			c.Next()
+			return
+		}
+
+		given := c.GetHeader(config.APIKeyHeader)
+		if given == "" {
+			given = strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+		}
+		for _, key := range keys {
+			if subtle.ConstantTimeCompare([]byte(given), []byte(key)) == 1 {
+				c.Next()
+				return
+			}
+		}
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Missing or invalid API key"})
+	}
+
+	return gin.HandlerFunc(fn)
+}
@@ -0,0 +1,121 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"streamr_api/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Fees godoc
+// @Summary      Get the operator cut and fee parameters.
+// @Description  Responds with the operator cut, the protocol fee, the minimum self-delegation fraction and minimum stake, and how much the cut may still move within the change period.
+// @Tags         Fees
+// @Produce      json
+// @Success      200  {object}  models.FeeParameters
+// @Router       /operator/fees [get]
+func Fees(o *models.Operator) gin.HandlerFunc {
+	fn := func(c *gin.Context) {
+		if o.Cut == nil {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "operator cut management is not running"})
+			return
+		}
+
+		result, err := o.Cut.Fees()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, result)
+	}
+
+	return gin.HandlerFunc(fn)
+}
+
+// ChangeCut godoc
+// @Summary      Change the operator cut.
+// @Description  Sets the operator cut, the owner's share of the earnings after the protocol fee. Responds with the delegators' projected yield before and after at the current earnings rate, once the transaction is mined. Refused with 409 when the change would take the total change within the period over CUT_MAX_CHANGE_PERCENT, or while anything is staked in sponsorships. Only the owner can change the cut. Add dryRun=true to only see the preview.
+// @Tags         Fees
+// @Produce      json
+// @Param        percent  path      string  true   "new cut in percent, e.g. 12.5"
+// @Param        dryRun   query     bool    false  "only show the preview"
+// @Success      200  {object}  models.CutChangePreview
+// @Router       /operator/cut/change/{percent} [get]
+func ChangeCut(o *models.Operator) gin.HandlerFunc {
+	fn := func(c *gin.Context) {
+		if o.Cut == nil {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "operator cut management is not running"})
+			return
+		}
+		proposed, err := models.ParseCutPercent(c.Param("percent"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		if c.Query("dryRun") == "true" {
+			preview, err := o.Cut.Preview(proposed)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
+			c.JSON(http.StatusOK, preview)
+			return
+		}
+
+		preview, err := o.Cut.Change(proposed)
+		if err != nil {
+			status := http.StatusInternalServerError
+			if errors.Is(err, models.ErrCutChangeLimit) || errors.Is(err, models.ErrNoChange) || errors.Is(err, models.ErrStakedInSponsorships) {
+				status = http.StatusConflict
+			}
+			c.JSON(status, gin.H{"error": err.Error(), "preview": preview})
+			return
+		}
+
+		c.JSON(http.StatusOK, preview)
+	}
+
+	return gin.HandlerFunc(fn)
+}
+
+// CutChanges godoc
+// @Summary      List the operator cut changes.
+// @Description  Responds with the cut changes made through the API, oldest first. These are what the maximum change per period is checked against.
+// @Tags         Fees
+// @Produce      json
+// @Param        from  query     string  false  "start time, RFC3339, YYYY-MM-DD or unix seconds"
+// @Param        to    query     string  false  "end time, RFC3339, YYYY-MM-DD or unix seconds"
+// @Success      200  {array}   models.CutChange
+// @Router       /operator/cut/changes [get]
+func CutChanges(o *models.Operator) gin.HandlerFunc {
+	fn := func(c *gin.Context) {
+		if o.Cut == nil {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "operator cut management is not running"})
+			return
+		}
+		from, err := parseTimeQuery(c, "from")
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		to, err := parseTimeQuery(c, "to")
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		result, err := o.Cut.Changes(from, to)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, result)
+	}
+
+	return gin.HandlerFunc(fn)
+}
@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"net/http"
+
+	"streamr_api/common"
+	"streamr_api/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SelfDelegation godoc
+// @Summary      Get the owner's self-delegation.
+// @Description  Responds with the owner's operator tokens and their DATA value, the owner's share against the protocol's minimum self-delegation fraction, the DATA in the owner wallet, the owner's undelegations waiting in the queue and how much more can be undelegated without going under the minimum.
+// @Tags         Delegation
+// @Produce      json
+// @Success      200  {object}  models.SelfDelegation
+// @Router       /operator/selfdelegation [get]
+func SelfDelegation(o *models.Operator) gin.HandlerFunc {
+	fn := func(c *gin.Context) {
+		result, err := o.GetSelfDelegation()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, result)
+	}
+
+	return gin.HandlerFunc(fn)
+}
+
+// SelfDelegate godoc
+// @Summary      Delegate DATA from the owner wallet.
+// @Description  Delegates DATA from the owner wallet into the operator, with a single DATA transferAndCall (default) or with approve followed by delegate. Responds once the transactions are mined.
+// @Tags         Delegation
+// @Produce      json
+// @Param        amount  path      string  true   "amount, e.g. 1500.25DATA or 1500250000000000000000wei; plain integers are wei"
+// @Param        method  query     string  false  "transferAndCall (default) or approve"
+// @Success      200  {object}  models.DelegationResult
+// @Router       /operator/selfdelegation/delegate/{amount} [get]
+func SelfDelegate(o *models.Operator) gin.HandlerFunc {
+	fn := func(c *gin.Context) {
+		amount, err := common.ParseAmount(c.Param("amount"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		method := c.DefaultQuery("method", models.DelegateTransferAndCall)
+		if err := models.ValidDelegateMethod(method); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		result, err := o.SelfDelegate(amount, method)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error(), "result": result})
+			return
+		}
+
+		c.JSON(http.StatusOK, result)
+	}
+
+	return gin.HandlerFunc(fn)
+}
+
+// SelfUndelegate godoc
+// @Summary      Queue an undelegation for the owner.
+// @Description  Queues an undelegation of DATA from the operator to the owner wallet and responds once the transaction is mined. Refused if it would take the owner under the minimum self-delegation fraction, which would stop the operator from accepting delegations.
+// @Tags         Delegation
+// @Produce      json
+// @Param        amount  path      string  true  "amount, e.g. 1500.25DATA or 1500250000000000000000wei; plain integers are wei"
+// @Success      200  {object}  models.DelegationResult
+// @Router       /operator/selfdelegation/undelegate/{amount} [get]
+func SelfUndelegate(o *models.Operator) gin.HandlerFunc {
+	fn := func(c *gin.Context) {
+		amount, err := common.ParseAmount(c.Param("amount"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		result, err := o.SelfUndelegate(amount)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error(), "result": result})
+			return
+		}
+
+		c.JSON(http.StatusOK, result)
+	}
+
+	return gin.HandlerFunc(fn)
+}
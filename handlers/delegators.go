@@ -0,0 +1,104 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"streamr_api/blockchain"
+	"streamr_api/models"
+
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/gin-gonic/gin"
+)
+
+// Delegators godoc
+// @Summary      List the delegators.
+// @Description  Responds with every delegator holding operator tokens or waiting in the undelegation queue: the operator tokens, their DATA value, the first delegation, the DATA delegated and paid out, the earnings attributable to the delegator and the pending undelegations. Built from the indexed events.
+// @Tags         Delegators
+// @Produce      json
+// @Param        all  query     bool  false  "also list delegators who have left"
+// @Success      200  {array}   models.DelegatorSummary
+// @Router       /delegators [get]
+func Delegators(o *models.Operator) gin.HandlerFunc {
+	fn := func(c *gin.Context) {
+		result, err := o.Delegators(c.Query("all") == "true")
+		if err != nil {
+			status := http.StatusInternalServerError
+			if errors.Is(err, blockchain.ErrIndexerDisabled) {
+				status = http.StatusServiceUnavailable
+			}
+			c.JSON(status, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, result)
+	}
+
+	return gin.HandlerFunc(fn)
+}
+
+// DelegatorStatement godoc
+// @Summary      Get a delegator's statement.
+// @Description  Responds with a report for the delegator: its current position and earnings, pending undelegations and every delegation, undelegation and operator token transfer in the period.
+// @Tags         Delegators
+// @Produce      json
+// @Produce      text/csv
+// @Param        address  path      string  true   "delegator address"
+// @Param        format   query     string  false  "json (default) or csv"
+// @Param        from     query     string  false  "start time, RFC3339, YYYY-MM-DD or unix seconds"
+// @Param        to       query     string  false  "end time, RFC3339, YYYY-MM-DD or unix seconds"
+// @Success      200  {object}  models.DelegatorStatement
+// @Router       /delegators/{address}/statement [get]
+func DelegatorStatement(o *models.Operator) gin.HandlerFunc {
+	fn := func(c *gin.Context) {
+		if !ethcommon.IsHexAddress(c.Param("address")) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid delegator address"})
+			return
+		}
+		delegator := ethcommon.HexToAddress(c.Param("address"))
+		format := c.DefaultQuery("format", "json")
+		if format != "csv" && format != "json" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid format"})
+			return
+		}
+		from, err := parseTimeQuery(c, "from")
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		to, err := parseTimeQuery(c, "to")
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		statement, err := o.DelegatorStatement(delegator, from, to)
+		if err != nil {
+			status := http.StatusInternalServerError
+			if errors.Is(err, blockchain.ErrIndexerDisabled) {
+				status = http.StatusServiceUnavailable
+			} else if errors.Is(err, models.ErrUnknownDelegator) {
+				status = http.StatusNotFound
+			}
+			c.JSON(status, gin.H{"error": err.Error()})
+			return
+		}
+
+		if format == "json" {
+			c.JSON(http.StatusOK, statement)
+			return
+		}
+
+		filename := fmt.Sprintf("statement-%s.csv", strings.ToLower(delegator.Hex()))
+		c.Header("Content-Type", "text/csv")
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+		c.Status(http.StatusOK)
+		if err := models.WriteStatementCSV(c.Writer, statement); err != nil {
+			c.Error(err)
+		}
+	}
+
+	return gin.HandlerFunc(fn)
+}
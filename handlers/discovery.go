@@ -0,0 +1,114 @@
+package handlers
+
+import (
+	"math/big"
+	"net/http"
+	"strconv"
+
+	"streamr_api/common"
+	"streamr_api/models"
+
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/gin-gonic/gin"
+)
+
+// Sponsorships godoc
+// @Summary      Discover sponsorships.
+// @Description  Responds with the sponsorships from the subgraph and those the operator is staked in, with their remaining funding, payout rate, total stake, operator count, runway and the projected APY of staking the given amount. The state is cached for DISCOVERY_CACHE_SECONDS unless refresh is set.
+// @Tags         Sponsorships
+// @Produce      json
+// @Param        stake    query     string  false  "additional stake to evaluate, e.g. 5000DATA (default the protocol minimum stake)"
+// @Param        sort     query     string  false  "apy (default), runway, remaining or payout"
+// @Param        limit    query     int     false  "return at most this many"
+// @Param        refresh  query     bool    false  "read the state again instead of using the cache"
+// @Success      200  {array}   models.SponsorshipMetrics
+// @Router       /sponsorships [get]
+func Sponsorships(o *models.Operator) gin.HandlerFunc {
+	fn := func(c *gin.Context) {
+		if o.Discovery == nil {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "sponsorship discovery is not running"})
+			return
+		}
+
+		additional, ok := parseStakeQuery(c)
+		if !ok {
+			return
+		}
+		sortBy := c.DefaultQuery("sort", models.SortAPY)
+		if err := models.ValidSort(sortBy); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		limit, err := strconv.Atoi(c.DefaultQuery("limit", "0"))
+		if err != nil || limit < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit"})
+			return
+		}
+		refresh := c.Query("refresh") == "true"
+
+		result, err := o.Discovery.Evaluate(additional, sortBy, refresh)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if limit > 0 && len(result) > limit {
+			result = result[:limit]
+		}
+
+		c.JSON(http.StatusOK, result)
+	}
+
+	return gin.HandlerFunc(fn)
+}
+
+// Sponsorship godoc
+// @Summary      Evaluate a sponsorship.
+// @Description  Reads a single sponsorship fresh from the subgraph and the contract and responds with its metrics for staking the given amount.
+// @Tags         Sponsorships
+// @Produce      json
+// @Param        address  path      string  true   "Sponsorship address"
+// @Param        stake    query     string  false  "additional stake to evaluate, e.g. 5000DATA (default the protocol minimum stake)"
+// @Success      200  {object}  models.SponsorshipMetrics
+// @Router       /sponsorships/{address} [get]
+func Sponsorship(o *models.Operator) gin.HandlerFunc {
+	fn := func(c *gin.Context) {
+		if o.Discovery == nil {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "sponsorship discovery is not running"})
+			return
+		}
+
+		if !ethcommon.IsHexAddress(c.Param("address")) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid sponsorship address"})
+			return
+		}
+		additional, ok := parseStakeQuery(c)
+		if !ok {
+			return
+		}
+
+		result, err := o.Discovery.EvaluateOne(ethcommon.HexToAddress(c.Param("address")), additional)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, result)
+	}
+
+	return gin.HandlerFunc(fn)
+}
+
+// parseStakeQuery reads the optional stake query parameter. It responds with an error and returns
+// false if the value is invalid.
+func parseStakeQuery(c *gin.Context) (*big.Int, bool) {
+	value := c.Query("stake")
+	if value == "" {
+		return nil, true
+	}
+	amount, err := common.ParseAmount(value)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	return amount, true
+}
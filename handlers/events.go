@@ -0,0 +1,183 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"streamr_api/blockchain"
+	"streamr_api/common"
+	"streamr_api/models"
+
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/gin-gonic/gin"
+)
+
+// IndexerStatus godoc
+// @Summary      Get the event indexer status.
+// @Description  Responds with the last indexed block, the chain head and the sponsorships being followed.
+// @Tags         Events
+// @Produce      json
+// @Success      200  {object}  blockchain.IndexerStatus
+// @Router       /events/status [get]
+func IndexerStatus(o *models.Operator) gin.HandlerFunc {
+	fn := func(c *gin.Context) {
+		if o.Indexer == nil {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": blockchain.ErrIndexerDisabled.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, o.Indexer.Status())
+	}
+
+	return gin.HandlerFunc(fn)
+}
+
+// Events godoc
+// @Summary      List indexed contract events.
+// @Description  Responds with the decoded events of the operator contract and its sponsorships, oldest first.
+// @Tags         Events
+// @Produce      json
+// @Param        name   query     string  false  "comma separated event names, e.g. Delegated,Undelegated"
+// @Param        from   query     string  false  "start time, RFC3339, YYYY-MM-DD or unix seconds"
+// @Param        to     query     string  false  "end time, RFC3339, YYYY-MM-DD or unix seconds"
+// @Param        limit  query     int     false  "return only the most recent events"
+// @Success      200  {array}  blockchain.IndexedEvent
+// @Router       /events [get]
+func Events(o *models.Operator) gin.HandlerFunc {
+	fn := func(c *gin.Context) {
+		var names []string
+		if c.Query("name") != "" {
+			names = strings.Split(c.Query("name"), ",")
+		}
+		listEvents(c, o, names)
+	}
+
+	return gin.HandlerFunc(fn)
+}
+
+// Delegations godoc
+// @Summary      List delegations into the operator.
+// @Description  Responds with the Delegated events of the operator contract.
+// @Tags         Events
+// @Produce      json
+// @Param        from   query     string  false  "start time, RFC3339, YYYY-MM-DD or unix seconds"
+// @Param        to     query     string  false  "end time, RFC3339, YYYY-MM-DD or unix seconds"
+// @Param        limit  query     int     false  "return only the most recent events"
+// @Success      200  {array}  blockchain.IndexedEvent
+// @Router       /events/delegations [get]
+func Delegations(o *models.Operator) gin.HandlerFunc {
+	fn := func(c *gin.Context) {
+		listEvents(c, o, []string{"Delegated"})
+	}
+
+	return gin.HandlerFunc(fn)
+}
+
+// Undelegations godoc
+// @Summary      List undelegations from the operator.
+// @Description  Responds with the Undelegated and QueuedDataPayout events of the operator contract.
+// @Tags         Events
+// @Produce      json
+// @Param        from   query     string  false  "start time, RFC3339, YYYY-MM-DD or unix seconds"
+// @Param        to     query     string  false  "end time, RFC3339, YYYY-MM-DD or unix seconds"
+// @Param        limit  query     int     false  "return only the most recent events"
+// @Success      200  {array}  blockchain.IndexedEvent
+// @Router       /events/undelegations [get]
+func Undelegations(o *models.Operator) gin.HandlerFunc {
+	fn := func(c *gin.Context) {
+		listEvents(c, o, []string{"Undelegated", "QueuedDataPayout"})
+	}
+
+	return gin.HandlerFunc(fn)
+}
+
+// StakeChanges godoc
+// @Summary      List stake changes.
+// @Description  Responds with the Staked, Unstaked and StakeUpdate events of the operator contract.
+// @Tags         Events
+// @Produce      json
+// @Param        from   query     string  false  "start time, RFC3339, YYYY-MM-DD or unix seconds"
+// @Param        to     query     string  false  "end time, RFC3339, YYYY-MM-DD or unix seconds"
+// @Param        limit  query     int     false  "return only the most recent events"
+// @Success      200  {array}  blockchain.IndexedEvent
+// @Router       /events/stakechanges [get]
+func StakeChanges(o *models.Operator) gin.HandlerFunc {
+	fn := func(c *gin.Context) {
+		listEvents(c, o, []string{"Staked", "Unstaked", "StakeUpdate"}, o.ContractAddr)
+	}
+
+	return gin.HandlerFunc(fn)
+}
+
+// EarningsWithdrawals godoc
+// @Summary      List earnings withdrawals.
+// @Description  Responds with the Profit events the operator contract emits when earnings are withdrawn.
+// @Tags         Events
+// @Produce      json
+// @Param        from   query     string  false  "start time, RFC3339, YYYY-MM-DD or unix seconds"
+// @Param        to     query     string  false  "end time, RFC3339, YYYY-MM-DD or unix seconds"
+// @Param        limit  query     int     false  "return only the most recent events"
+// @Success      200  {array}  blockchain.IndexedEvent
+// @Router       /events/withdrawals [get]
+func EarningsWithdrawals(o *models.Operator) gin.HandlerFunc {
+	fn := func(c *gin.Context) {
+		listEvents(c, o, []string{"Profit"})
+	}
+
+	return gin.HandlerFunc(fn)
+}
+
+func listEvents(c *gin.Context, o *models.Operator, names []string, contract ...ethcommon.Address) {
+	if o.Indexer == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": blockchain.ErrIndexerDisabled.Error()})
+		return
+	}
+
+	filter := blockchain.EventFilter{Names: names}
+	if len(contract) > 0 {
+		filter.Contract = contract[0]
+	}
+
+	var err error
+	filter.From, err = parseTimeQuery(c, "from")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	filter.To, err = parseTimeQuery(c, "to")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if c.Query("limit") != "" {
+		filter.Limit, err = strconv.Atoi(c.Query("limit"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit"})
+			return
+		}
+	}
+
+	events, err := o.Indexer.Events(filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, events)
+}
+
+// parseTimeQuery reads a time query parameter given as RFC3339, YYYY-MM-DD or unix seconds.
+// A missing parameter yields the zero time.
+func parseTimeQuery(c *gin.Context, key string) (time.Time, error) {
+	value := c.Query(key)
+	if value == "" {
+		return time.Time{}, nil
+	}
+	t, err := common.ParseTime(value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid %s: %s", key, value)
+	}
+	return t, nil
+}
@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"net/http"
+
+	"streamr_api/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ExitPlan godoc
+// @Summary      Evaluate the exit policy.
+// @Description  Responds with the decision for every staked sponsorship (keep, exit, or defer while leaving would cost a penalty) and the metrics it is based on. Nothing is sent.
+// @Tags         Exit
+// @Produce      json
+// @Success      200  {object}  models.ExitReport
+// @Router       /exit/plan [get]
+func ExitPlan(o *models.Operator) gin.HandlerFunc {
+	fn := func(c *gin.Context) {
+		if o.Exits == nil {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "exit policy is not running"})
+			return
+		}
+
+		result, err := o.Exits.Check(true)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, result)
+	}
+
+	return gin.HandlerFunc(fn)
+}
+
+// ExitCheck godoc
+// @Summary      Run the exit policy.
+// @Description  Leaves the sponsorships whose runway or yield is under the configured minimum and that can be left without a penalty, withdrawing their earnings first, then stakes the freed DATA into the remaining sponsorships by the configured allocation. Responds once all transactions are mined.
+// @Tags         Exit
+// @Produce      json
+// @Success      200  {object}  models.ExitReport
+// @Router       /exit/check [get]
+func ExitCheck(o *models.Operator) gin.HandlerFunc {
+	fn := func(c *gin.Context) {
+		if o.Exits == nil {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "exit policy is not running"})
+			return
+		}
+
+		result, err := o.Exits.Check(false)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error(), "report": result})
+			return
+		}
+
+		c.JSON(http.StatusOK, result)
+	}
+
+	return gin.HandlerFunc(fn)
+}
+
+// ExitReports godoc
+// @Summary      List the exit policy runs.
+// @Description  Responds with every run that left or deferred leaving a sponsorship, with the metrics behind each decision and the transactions sent.
+// @Tags         Exit
+// @Produce      json
+// @Param        from    query     string  false  "start time, RFC3339, YYYY-MM-DD or unix seconds"
+// @Param        to      query     string  false  "end time, RFC3339, YYYY-MM-DD or unix seconds"
+// @Success      200  {array}  models.ExitReport
+// @Router       /exit/reports [get]
+func ExitReports(o *models.Operator) gin.HandlerFunc {
+	fn := func(c *gin.Context) {
+		if o.Exits == nil {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "exit policy is not running"})
+			return
+		}
+
+		from, err := parseTimeQuery(c, "from")
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		to, err := parseTimeQuery(c, "to")
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		result, err := o.Exits.Reports(from, to)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, result)
+	}
+
+	return gin.HandlerFunc(fn)
+}
@@ -0,0 +1,63 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"streamr_api/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ExportLedger godoc
+// @Summary      Export the operator's ledger.
+// @Description  Responds with every stake, unstake, earnings withdrawal and queue payout of the operator, with amounts in DATA and gas costs in POL. The CSV layout suits common crypto tax tools.
+// @Tags         Export
+// @Produce      json
+// @Produce      text/csv
+// @Param        format  query     string  false  "csv (default) or json"
+// @Param        from    query     string  false  "start time, RFC3339, YYYY-MM-DD or unix seconds"
+// @Param        to      query     string  false  "end time, RFC3339, YYYY-MM-DD or unix seconds"
+// @Success      200  {array}  models.LedgerRow
+// @Router       /export/ledger [get]
+func ExportLedger(o *models.Operator) gin.HandlerFunc {
+	fn := func(c *gin.Context) {
+		format := c.DefaultQuery("format", "csv")
+		if format != "csv" && format != "json" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid format"})
+			return
+		}
+		from, err := parseTimeQuery(c, "from")
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		to, err := parseTimeQuery(c, "to")
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		rows, err := o.Ledger(from, to)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		if format == "json" {
+			c.JSON(http.StatusOK, rows)
+			return
+		}
+
+		filename := fmt.Sprintf("ledger-%s.csv", strings.ToLower(o.ContractAddr.Hex()))
+		c.Header("Content-Type", "text/csv")
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+		c.Status(http.StatusOK)
+		if err := models.WriteLedgerCSV(c.Writer, rows); err != nil {
+			c.Error(err)
+		}
+	}
+
+	return gin.HandlerFunc(fn)
+}
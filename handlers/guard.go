@@ -0,0 +1,102 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"streamr_api/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// EarningsGuardStatus godoc
+// @Summary      Get the earnings guard status.
+// @Description  Responds with the threshold and the unwithdrawn earnings against maxAllowedEarnings at the last check.
+// @Tags         Guard
+// @Produce      json
+// @Success      200  {object}  models.EarningsGuardStatus
+// @Router       /guard/earnings [get]
+func EarningsGuardStatus(o *models.Operator) gin.HandlerFunc {
+	fn := func(c *gin.Context) {
+		if o.Guard == nil {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "earnings guard is not running"})
+			return
+		}
+		c.JSON(http.StatusOK, o.Guard.Status())
+	}
+
+	return gin.HandlerFunc(fn)
+}
+
+// EarningsGuardCheck godoc
+// @Summary      Run the earnings guard now.
+// @Description  Compares the unwithdrawn earnings against maxAllowedEarnings and withdraws from the sponsorships over the threshold. Responds with the action taken, or null if the earnings are below the threshold. Refused with 409 while the guard is disabled.
+// @Tags         Guard
+// @Produce      json
+// @Success      200  {object}  models.EarningsGuardAction
+// @Router       /guard/earnings/check [get]
+func EarningsGuardCheck(o *models.Operator) gin.HandlerFunc {
+	fn := func(c *gin.Context) {
+		if o.Guard == nil {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "earnings guard is not running"})
+			return
+		}
+		if !o.Guard.Status().Enabled {
+			c.JSON(http.StatusConflict, gin.H{"error": models.ErrGuardDisabled.Error()})
+			return
+		}
+
+		result, err := o.Guard.Check()
+		if errors.Is(err, models.ErrGuardDisabled) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error(), "action": result})
+			return
+		}
+
+		c.JSON(http.StatusOK, result)
+	}
+
+	return gin.HandlerFunc(fn)
+}
+
+// EarningsGuardActions godoc
+// @Summary      List the withdrawals sent by the earnings guard.
+// @Description  Responds with every automatic withdrawal, the sponsorships it withdrew from and its outcome.
+// @Tags         Guard
+// @Produce      json
+// @Param        from    query     string  false  "start time, RFC3339, YYYY-MM-DD or unix seconds"
+// @Param        to      query     string  false  "end time, RFC3339, YYYY-MM-DD or unix seconds"
+// @Success      200  {array}  models.EarningsGuardAction
+// @Router       /guard/earnings/actions [get]
+func EarningsGuardActions(o *models.Operator) gin.HandlerFunc {
+	fn := func(c *gin.Context) {
+		if o.Guard == nil {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "earnings guard is not running"})
+			return
+		}
+
+		from, err := parseTimeQuery(c, "from")
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		to, err := parseTimeQuery(c, "to")
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		result, err := o.Guard.Actions(from, to)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, result)
+	}
+
+	return gin.HandlerFunc(fn)
+}
@@ -0,0 +1,63 @@
+package handlers
+
+import (
+	"net/http"
+
+	"streamr_api/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Healthz responds as long as the process serves requests. It is served outside /api/v1 and
+// without an API key, for liveness probes.
+func Healthz() gin.HandlerFunc {
+	fn := func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"status": "ok"})
+	}
+
+	return gin.HandlerFunc(fn)
+}
+
+// Readyz responds with the readiness checks, with 503 if any of them failed. It is served outside
+// /api/v1 and without an API key, for readiness probes.
+func Readyz(r *models.Registry, s *models.Scheduler, l *models.Lifecycle) gin.HandlerFunc {
+	fn := func(c *gin.Context) {
+		readiness := models.CheckReadiness(r, s, l)
+		status := http.StatusOK
+		if !readiness.Ready {
+			status = http.StatusServiceUnavailable
+		}
+		c.JSON(status, readiness)
+	}
+
+	return gin.HandlerFunc(fn)
+}
+
+// Diagnostics godoc
+// @Summary      Get diagnostics.
+// @Description  Responds with the build version, every operator's latest block and how far it lags behind the wall clock, the state of the signer's nonce sequence, the number of pending transactions in the journal, and the last successful and failed run of each cron job since the service started.
+// @Tags         Diagnostics
+// @Produce      json
+// @Success      200  {object}  models.Diagnostics
+// @Router       /diagnostics [get]
+func Diagnostics(r *models.Registry, s *models.Scheduler, version string) gin.HandlerFunc {
+	fn := func(c *gin.Context) {
+		c.JSON(http.StatusOK, models.Diagnose(r, s, version))
+	}
+
+	return gin.HandlerFunc(fn)
+}
+
+// RejectWhileDraining refuses mutating requests with 503 once the service is shutting down, so no
+// new transactions are sent. Dry runs send nothing and are let through.
+func RejectWhileDraining(l *models.Lifecycle) gin.HandlerFunc {
+	fn := func(c *gin.Context) {
+		if l.Draining() && c.Query("dryRun") != "true" {
+			c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{"error": "the service is shutting down"})
+			return
+		}
+		c.Next()
+	}
+
+	return gin.HandlerFunc(fn)
+}
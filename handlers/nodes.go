@@ -0,0 +1,149 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"streamr_api/blockchain"
+	"streamr_api/models"
+
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/gin-gonic/gin"
+)
+
+// NodeAddressesRequest is the new set of node addresses.
+type NodeAddressesRequest struct {
+	Addresses []string `json:"addresses"`
+}
+
+// NodeAddresses godoc
+// @Summary      Get the node addresses.
+// @Description  Responds with the node addresses set in the operator contract and, when events are indexed, the last heartbeat each node published.
+// @Tags         Nodes
+// @Produce      json
+// @Success      200  {array}   models.NodeInfo
+// @Router       /operator/nodes [get]
+func NodeAddresses(o *models.Operator) gin.HandlerFunc {
+	fn := func(c *gin.Context) {
+		result, err := o.GetNodeAddresses()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, result)
+	}
+
+	return gin.HandlerFunc(fn)
+}
+
+// SetNodeAddresses godoc
+// @Summary      Set the node addresses.
+// @Description  Replaces the node addresses in the operator contract. Responds with the current and proposed addresses and which are added and removed, once the transaction is mined. Add dryRun=true to only see the change.
+// @Tags         Nodes
+// @Accept       json
+// @Produce      json
+// @Param        addresses  body      NodeAddressesRequest  true   "the complete list of node addresses"
+// @Param        dryRun     query     bool                  false  "only show the change"
+// @Success      200  {object}  models.NodeAddressChange
+// @Router       /operator/nodes [post]
+func SetNodeAddresses(o *models.Operator) gin.HandlerFunc {
+	fn := func(c *gin.Context) {
+		var request NodeAddressesRequest
+		if err := c.BindJSON(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+			return
+		}
+		addresses := make([]ethcommon.Address, 0, len(request.Addresses))
+		for _, addr := range request.Addresses {
+			if !ethcommon.IsHexAddress(addr) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid node address " + addr})
+				return
+			}
+			addresses = append(addresses, ethcommon.HexToAddress(addr))
+		}
+		if err := o.ValidateNodeAddresses(addresses); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		result, err := o.SetNodeAddresses(addresses, c.Query("dryRun") == "true")
+		if err != nil {
+			status := http.StatusInternalServerError
+			if errors.Is(err, models.ErrNoChange) {
+				status = http.StatusConflict
+			}
+			c.JSON(status, gin.H{"error": err.Error(), "change": result})
+			return
+		}
+
+		c.JSON(http.StatusOK, result)
+	}
+
+	return gin.HandlerFunc(fn)
+}
+
+// Metadata godoc
+// @Summary      Get the operator metadata.
+// @Description  Responds with the metadata JSON last published with updateMetadata (name, description, image URL, redundancy factor and any other fields), when and in which transaction. Read from the indexed events.
+// @Tags         Nodes
+// @Produce      json
+// @Success      200  {object}  models.OperatorMetadata
+// @Router       /operator/metadata [get]
+func Metadata(o *models.Operator) gin.HandlerFunc {
+	fn := func(c *gin.Context) {
+		result, err := o.GetMetadata()
+		if err != nil {
+			status := http.StatusInternalServerError
+			if errors.Is(err, blockchain.ErrIndexerDisabled) {
+				status = http.StatusServiceUnavailable
+			}
+			c.JSON(status, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, result)
+	}
+
+	return gin.HandlerFunc(fn)
+}
+
+// UpdateMetadata godoc
+// @Summary      Update the operator metadata.
+// @Description  Sets the given fields on the current metadata and publishes it with updateMetadata. Fields left out are kept, an empty description or imageUrl removes it. As the whole JSON is published, the update is refused while the current metadata isn't known for sure: with 503 while the event indexer is catching up, with 409 if no MetadataUpdated event was indexed or the metadata on chain isn't valid JSON. Responds with the field by field diff and the exact JSON sent, once the transaction is mined. Add dryRun=true to only see the change.
+// @Tags         Nodes
+// @Accept       json
+// @Produce      json
+// @Param        metadata  body      models.MetadataUpdate  true   "fields to set"
+// @Param        dryRun    query     bool                   false  "only show the change"
+// @Success      200  {object}  models.MetadataChange
+// @Router       /operator/metadata [post]
+func UpdateMetadata(o *models.Operator) gin.HandlerFunc {
+	fn := func(c *gin.Context) {
+		var update models.MetadataUpdate
+		if err := c.BindJSON(&update); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+			return
+		}
+		if err := update.Validate(); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		result, err := o.UpdateMetadata(update, c.Query("dryRun") == "true")
+		if err != nil {
+			status := http.StatusInternalServerError
+			if errors.Is(err, models.ErrNoChange) || errors.Is(err, models.ErrMetadataUnknown) {
+				status = http.StatusConflict
+			} else if errors.Is(err, blockchain.ErrIndexerDisabled) || errors.Is(err, models.ErrIndexerBehind) {
+				status = http.StatusServiceUnavailable
+			}
+			c.JSON(status, gin.H{"error": err.Error(), "change": result})
+			return
+		}
+
+		c.JSON(http.StatusOK, result)
+	}
+
+	return gin.HandlerFunc(fn)
+}
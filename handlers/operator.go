@@ -1,9 +1,11 @@
 package handlers
 
 import (
-	"math/big"
+	"fmt"
 	"net/http"
+	"strconv"
 
+	"streamr_api/common"
 	"streamr_api/models"
 
 	ethcommon "github.com/ethereum/go-ethereum/common"
@@ -26,11 +28,11 @@ func GetOperator(o *models.Operator) gin.HandlerFunc {
 }
 
 // OperatorValue             godoc
-// @Summary      Get the Streamr Operator details.
-// @Description  Responds with the Operator attributes.
+// @Summary      Get the Streamr Operator value without earnings.
+// @Description  Responds with the Operator value without unwithdrawn earnings, in wei and DATA.
 // @Tags         Operator
 // @Produce      json
-// @Success      200  {array}  uint64
+// @Success      200  {object}  common.Amount
 // @Router       /operator/valuewithoutearnings [get]
 func OperatorValueWithoutEarnings(o *models.Operator) gin.HandlerFunc {
 	fn := func(c *gin.Context) {
@@ -39,7 +41,7 @@ func OperatorValueWithoutEarnings(o *models.Operator) gin.HandlerFunc {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
 		}
-		c.JSON(http.StatusOK, result)
+		c.JSON(http.StatusOK, common.NewAmount(result))
 	}
 
 	return gin.HandlerFunc(fn)
@@ -51,7 +53,7 @@ func OperatorValueWithoutEarnings(o *models.Operator) gin.HandlerFunc {
 // @Tags         Operator
 // @Produce      json
 // @Param        address  path      string  true  "get deployed stake by sponsorship"
-// @Success      200  {array}  models.StakedIntoResponse
+// @Success      200  {object}  models.StakedIntoResponse
 // @Router       /operator/stakedinto/{address} [get]
 func StakedInto(o *models.Operator) gin.HandlerFunc {
 	fn := func(c *gin.Context) {
@@ -73,7 +75,7 @@ func StakedInto(o *models.Operator) gin.HandlerFunc {
 // @Description  Responds with the Operator stake deployed in all sponsorships.
 // @Tags         Operator
 // @Produce      json
-// @Success      200  {array}  models.DeployedStakeResponse
+// @Success      200  {object}  models.DeployedStakeResponse
 // @Router       /operator/deployedstake/ [get]
 func DeployedStake(o *models.Operator) gin.HandlerFunc {
 	fn := func(c *gin.Context) {
@@ -95,16 +97,15 @@ func DeployedStake(o *models.Operator) gin.HandlerFunc {
 // @Tags         Operator
 // @Produce      json
 // @Param        sponsorship  path      string  true  "sponsorship address"
-// @Param        amount  path      int64  true  "amount in wei"
+// @Param        amount  path      string  true  "amount, e.g. 1500.25DATA or 1500250000000000000000wei; plain integers are wei"
 // @Success      200  {array}  string
 // @Router       /operator/stake/{sponsorship}/{amount} [get]
 func Stake(o *models.Operator) gin.HandlerFunc {
 	fn := func(c *gin.Context) {
 		addr := ethcommon.HexToAddress(c.Param("sponsorship"))
-		amount := new(big.Int)
-		_, ok := amount.SetString(c.Param("amount"), 10)
-		if !ok {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid amount"})
+		amount, err := common.ParseAmount(c.Param("amount"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 			return
 		}
 
@@ -126,16 +127,15 @@ func Stake(o *models.Operator) gin.HandlerFunc {
 // @Tags         Operator
 // @Produce      json
 // @Param        sponsorship  path      string  true  "sponsorship address"
-// @Param        amount  path      int64  true  "amount in wei"
+// @Param        amount  path      string  true  "amount, e.g. 1500.25DATA or 1500250000000000000000wei; plain integers are wei"
 // @Success      200  {array}  string
 // @Router       /operator/reducestaketo/{sponsorship}/{amount} [get]
 func ReduceStakeTo(o *models.Operator) gin.HandlerFunc {
 	fn := func(c *gin.Context) {
 		addr := ethcommon.HexToAddress(c.Param("sponsorship"))
-		amount := new(big.Int)
-		_, ok := amount.SetString(c.Param("amount"), 10)
-		if !ok {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid amount"})
+		amount, err := common.ParseAmount(c.Param("amount"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 			return
 		}
 
@@ -151,12 +151,106 @@ func ReduceStakeTo(o *models.Operator) gin.HandlerFunc {
 	return gin.HandlerFunc(fn)
 }
 
+// Unstake godoc
+// @Summary      Leave a sponsorship.
+// @Description  Previews what leaving returns (stake, locked stake, earnings, leave penalty, end of the minimum staking period) and, unless dryRun is set, unstakes. Refused with 409 while stake is locked in flags or leaving would cost a penalty; use forceunstake for that.
+// @Tags         Operator
+// @Produce      json
+// @Param        sponsorship  path      string  true   "sponsorship address"
+// @Param        dryRun       query     bool    false  "only preview"
+// @Success      200  {object}  models.UnstakePreview
+// @Router       /operator/unstake/{sponsorship} [get]
+func Unstake(o *models.Operator) gin.HandlerFunc {
+	fn := func(c *gin.Context) {
+		if !ethcommon.IsHexAddress(c.Param("sponsorship")) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid sponsorship address"})
+			return
+		}
+		addr := ethcommon.HexToAddress(c.Param("sponsorship"))
+
+		preview, err := o.PreviewUnstake(addr, false)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if c.Query("dryRun") == "true" {
+			c.JSON(http.StatusOK, preview)
+			return
+		}
+		if !preview.CanUnstake {
+			c.JSON(http.StatusConflict, gin.H{"error": "unstake would be refused, see the warnings or use forceunstake", "preview": preview})
+			return
+		}
+
+		preview.TxHash, err = o.Unstake(addr)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, preview)
+	}
+
+	return gin.HandlerFunc(fn)
+}
+
+// ForceUnstake godoc
+// @Summary      Force leaving a sponsorship.
+// @Description  Previews what a forced unstake returns and forfeits (locked stake and leave penalty) and, unless dryRun is set, sends it. If anything would be forfeited, confirm=true is required and the preview is returned with 409 otherwise.
+// @Tags         Operator
+// @Produce      json
+// @Param        sponsorship      path      string  true   "sponsorship address"
+// @Param        dryRun           query     bool    false  "only preview"
+// @Param        confirm          query     bool    false  "accept forfeiting stake"
+// @Param        maxQueuePayouts  query     int     false  "undelegation queue entries to pay out afterwards, 0 (default) for all"
+// @Success      200  {object}  models.UnstakePreview
+// @Router       /operator/forceunstake/{sponsorship} [get]
+func ForceUnstake(o *models.Operator) gin.HandlerFunc {
+	fn := func(c *gin.Context) {
+		if !ethcommon.IsHexAddress(c.Param("sponsorship")) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid sponsorship address"})
+			return
+		}
+		addr := ethcommon.HexToAddress(c.Param("sponsorship"))
+		maxQueuePayouts, err := strconv.ParseInt(c.DefaultQuery("maxQueuePayouts", "0"), 10, 64)
+		if err != nil || maxQueuePayouts < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid maxQueuePayouts"})
+			return
+		}
+
+		preview, err := o.PreviewUnstake(addr, true)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		preview.MaxQueuePayouts = maxQueuePayouts
+		if c.Query("dryRun") == "true" {
+			c.JSON(http.StatusOK, preview)
+			return
+		}
+		if preview.NeedsConfirm && c.Query("confirm") != "true" {
+			c.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("forced unstake forfeits %s DATA, add confirm=true to proceed", preview.Forfeit.DATA()), "preview": preview})
+			return
+		}
+
+		preview.TxHash, err = o.ForceUnstake(addr, maxQueuePayouts)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, preview)
+	}
+
+	return gin.HandlerFunc(fn)
+}
+
 // OperatorValue             godoc
 // @Summary      Get the sponsorships and earnings.
 // @Description  Responds with the list of sponsorships and uncollected earnings.
 // @Tags         Operator
 // @Produce      json
-// @Success      200  {array}  models.GetSponsorshipsAndEarningsResponse
+// @Success      200  {object}  models.GetSponsorshipsAndEarningsResponse
 // @Router       /operator/sponsorshipsandearnings [get]
 func SponsorshipsAndEarnings(o *models.Operator) gin.HandlerFunc {
 	fn := func(c *gin.Context) {
@@ -193,20 +287,25 @@ func OperatorWithdrawEarnings(o *models.Operator) gin.HandlerFunc {
 	return gin.HandlerFunc(fn)
 }
 
-// CURRENTLY NOT IMPLEMENTED as this cannot currently handle the undelegation queue, if it exists.
 // WithdrawEarningsAndCompound            godoc
 // @Summary      Withdraw earnings from sponsorship and restake.
-// @Description  Withdraws earnings from all sponsorships and restake to compound.
+// @Description  Withdraws earnings from all sponsorships and restakes the DATA the operator then holds, minus what the undelegation queue needs, according to the allocation. Responds once all transactions are mined.
 // @Tags         Operator
 // @Produce      json
-// @Success      200  {array}  []string
+// @Param        allocation  query     string  false  "prorata (default, by current stake), equal or earnings (back where the earnings came from)"
+// @Success      200  {object}  models.CompoundResult
 // @Router       /operator/withdrawearningsandcompound [get]
 func WithdrawEarningsAndCompound(o *models.Operator) gin.HandlerFunc {
 	fn := func(c *gin.Context) {
+		allocation := c.DefaultQuery("allocation", models.AllocationProRata)
+		if err := models.ValidAllocation(allocation); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 
-		result, err := o.WithdrawEarningsAndCompound()
+		result, err := o.WithdrawEarningsAndCompound(allocation)
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error(), "result": result})
 			return
 		}
 		c.JSON(http.StatusOK, result)
@@ -241,7 +340,7 @@ func StakeProRata(o *models.Operator) gin.HandlerFunc {
 // @Description  Responds with the undelegation queue.
 // @Tags         Operator
 // @Produce      json
-// @Success      200  {array}  []uint8
+// @Success      200  {array}  []models.UndelegationRecordResponse
 // @Router       /operator/undelegationqueue [get]
 func UndelegationQueue(o *models.Operator) gin.HandlerFunc {
 	fn := func(c *gin.Context) {
@@ -0,0 +1,39 @@
+package handlers
+
+import (
+	"net/http"
+
+	"streamr_api/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Operators godoc
+// @Summary      List the operators.
+// @Description  Responds with the operators run by the service. Every operator's routes are under /operators/{name}, e.g. /operators/{name}/operator/deployedstake; the routes without a name act on the first one.
+// @Tags         Operators
+// @Produce      json
+// @Success      200  {array}  models.OperatorInfo
+// @Router       /operators [get]
+func Operators(r *models.Registry) gin.HandlerFunc {
+	fn := func(c *gin.Context) {
+		c.JSON(http.StatusOK, r.Infos())
+	}
+
+	return gin.HandlerFunc(fn)
+}
+
+// Portfolio godoc
+// @Summary      Get the portfolio of all operators.
+// @Description  Responds with the value, deployed stake and unwithdrawn earnings of every operator, their totals and the stake per sponsorship across operators. Operators that couldn't be read have an error and are left out of the totals.
+// @Tags         Operators
+// @Produce      json
+// @Success      200  {object}  models.Portfolio
+// @Router       /portfolio [get]
+func Portfolio(r *models.Registry) gin.HandlerFunc {
+	fn := func(c *gin.Context) {
+		c.JSON(http.StatusOK, r.Portfolio())
+	}
+
+	return gin.HandlerFunc(fn)
+}
@@ -0,0 +1,114 @@
+package handlers
+
+import (
+	"net/http"
+
+	"streamr_api/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// QueuePlan godoc
+// @Summary      Plan the payout of the undelegation queue.
+// @Description  Responds with the DATA the queue needs, what the operator holds, the earnings that would be withdrawn and the stake reductions the policy would make. Nothing is sent.
+// @Tags         Operator
+// @Produce      json
+// @Param        policy  query     string  false  "prorata (default), lowest-yield or largest"
+// @Success      200  {object}  models.QueuePlan
+// @Router       /operator/undelegationqueue/plan [get]
+func QueuePlan(o *models.Operator) gin.HandlerFunc {
+	fn := func(c *gin.Context) {
+		if o.Queue == nil {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "undelegation queue servicing is not running"})
+			return
+		}
+
+		policy := c.DefaultQuery("policy", models.PolicyProRata)
+		if err := models.ValidPolicy(policy); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		result, err := o.Queue.Plan(policy)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, result)
+	}
+
+	return gin.HandlerFunc(fn)
+}
+
+// ServiceQueue godoc
+// @Summary      Pay out the undelegation queue.
+// @Description  Withdraws earnings and reduces stake by the policy as far as needed to pay out the undelegation queue, then pays it out. Responds once all transactions are mined.
+// @Tags         Operator
+// @Produce      json
+// @Param        policy  query     string  false  "prorata (default), lowest-yield or largest"
+// @Success      200  {object}  models.QueueServiceReport
+// @Router       /operator/undelegationqueue/service [get]
+func ServiceQueue(o *models.Operator) gin.HandlerFunc {
+	fn := func(c *gin.Context) {
+		if o.Queue == nil {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "undelegation queue servicing is not running"})
+			return
+		}
+
+		policy := c.DefaultQuery("policy", models.PolicyProRata)
+		if err := models.ValidPolicy(policy); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		result, err := o.Queue.Service(policy)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error(), "report": result})
+			return
+		}
+
+		c.JSON(http.StatusOK, result)
+	}
+
+	return gin.HandlerFunc(fn)
+}
+
+// QueueReports godoc
+// @Summary      List undelegation queue payouts.
+// @Description  Responds with every run of the queue servicing workflow and the transactions it sent.
+// @Tags         Operator
+// @Produce      json
+// @Param        from    query     string  false  "start time, RFC3339, YYYY-MM-DD or unix seconds"
+// @Param        to      query     string  false  "end time, RFC3339, YYYY-MM-DD or unix seconds"
+// @Success      200  {array}  models.QueueServiceReport
+// @Router       /operator/undelegationqueue/reports [get]
+func QueueReports(o *models.Operator) gin.HandlerFunc {
+	fn := func(c *gin.Context) {
+		if o.Queue == nil {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "undelegation queue servicing is not running"})
+			return
+		}
+
+		from, err := parseTimeQuery(c, "from")
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		to, err := parseTimeQuery(c, "to")
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		result, err := o.Queue.Reports(from, to)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, result)
+	}
+
+	return gin.HandlerFunc(fn)
+}
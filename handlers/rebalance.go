@@ -0,0 +1,124 @@
+package handlers
+
+import (
+	"net/http"
+
+	"streamr_api/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetRebalanceTargets godoc
+// @Summary      Get the rebalancing targets.
+// @Description  Responds with the target allocation, the drift tolerance and the move limit the rebalancer works with.
+// @Tags         Rebalance
+// @Produce      json
+// @Success      200  {object}  models.RebalanceTargets
+// @Router       /rebalance/targets [get]
+func GetRebalanceTargets(o *models.Operator) gin.HandlerFunc {
+	fn := func(c *gin.Context) {
+		if o.Rebalancer == nil {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "rebalancer is not running"})
+			return
+		}
+
+		result, err := o.Rebalancer.Targets()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, result)
+	}
+
+	return gin.HandlerFunc(fn)
+}
+
+// SetRebalanceTargets godoc
+// @Summary      Set the rebalancing targets.
+// @Description  Declares the target allocation: mode "weights" with relative weights per sponsorship (e.g. 40/30/30), "equal", or "yield" (proportional to each sponsorship's yield over the last 30 days).
+// @Tags         Rebalance
+// @Accept       json
+// @Produce      json
+// @Param        targets  body      models.RebalanceTargets  true  "Rebalancing targets"
+// @Success      200  {object}  models.RebalanceTargets
+// @Router       /rebalance/targets [post]
+func SetRebalanceTargets(o *models.Operator) gin.HandlerFunc {
+	fn := func(c *gin.Context) {
+		if o.Rebalancer == nil {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "rebalancer is not running"})
+			return
+		}
+
+		var targets models.RebalanceTargets
+		if err := c.BindJSON(&targets); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+			return
+		}
+		if err := targets.Validate(); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		if err := o.Rebalancer.SetTargets(targets); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, targets)
+	}
+
+	return gin.HandlerFunc(fn)
+}
+
+// RebalancePlan godoc
+// @Summary      Plan a rebalance.
+// @Description  Responds with the ordered moves towards the targets, reductions first, and the moves skipped for being within the tolerance or over the move limit. Nothing is sent.
+// @Tags         Rebalance
+// @Produce      json
+// @Success      200  {object}  models.RebalancePlan
+// @Router       /rebalance/plan [get]
+func RebalancePlan(o *models.Operator) gin.HandlerFunc {
+	fn := func(c *gin.Context) {
+		if o.Rebalancer == nil {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "rebalancer is not running"})
+			return
+		}
+
+		result, err := o.Rebalancer.Plan()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, result)
+	}
+
+	return gin.HandlerFunc(fn)
+}
+
+// Rebalance godoc
+// @Summary      Rebalance stake towards the targets.
+// @Description  Sends the planned reduceStakeTo transactions, waits for them to be mined, then sends the stake transactions. Responds with the moves and their tx hashes.
+// @Tags         Rebalance
+// @Produce      json
+// @Success      200  {object}  models.RebalancePlan
+// @Router       /rebalance/execute [get]
+func Rebalance(o *models.Operator) gin.HandlerFunc {
+	fn := func(c *gin.Context) {
+		if o.Rebalancer == nil {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "rebalancer is not running"})
+			return
+		}
+
+		result, err := o.Rebalancer.Rebalance()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error(), "plan": result})
+			return
+		}
+
+		c.JSON(http.StatusOK, result)
+	}
+
+	return gin.HandlerFunc(fn)
+}
@@ -0,0 +1,130 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"streamr_api/blockchain"
+	"streamr_api/models"
+
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/gin-gonic/gin"
+)
+
+// Reviews godoc
+// @Summary      List the flag reviews.
+// @Description  Responds with the review requests addressed to our operator, the most recent first: the sponsorship, the flagged operator, the voting period and our vote. Missing a vote risks getting slashed. Built from the indexed events.
+// @Tags         Reviews
+// @Produce      json
+// @Param        all  query     bool  false  "also list voted and expired reviews"
+// @Success      200  {array}   models.Review
+// @Router       /reviews [get]
+func Reviews(o *models.Operator) gin.HandlerFunc {
+	fn := func(c *gin.Context) {
+		if o.Reviews == nil {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "review service is not running"})
+			return
+		}
+
+		result, err := o.Reviews.Reviews(c.Query("all") == "true")
+		if err != nil {
+			status := http.StatusInternalServerError
+			if errors.Is(err, blockchain.ErrIndexerDisabled) {
+				status = http.StatusServiceUnavailable
+			}
+			c.JSON(status, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, result)
+	}
+
+	return gin.HandlerFunc(fn)
+}
+
+// VoteOnFlag godoc
+// @Summary      Vote on a flag review.
+// @Description  Casts our vote on the pending review of the flagged operator in the sponsorship. Responds once the transaction is mined.
+// @Tags         Reviews
+// @Produce      json
+// @Param        sponsorship  path      string  true  "sponsorship address"
+// @Param        target       path      string  true  "flagged operator address"
+// @Param        vote         path      string  true  "kick or no-kick"
+// @Success      200  {object}  models.Review
+// @Router       /reviews/vote/{sponsorship}/{target}/{vote} [get]
+func VoteOnFlag(o *models.Operator) gin.HandlerFunc {
+	fn := func(c *gin.Context) {
+		if o.Reviews == nil {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "review service is not running"})
+			return
+		}
+		if !ethcommon.IsHexAddress(c.Param("sponsorship")) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid sponsorship address"})
+			return
+		}
+		if !ethcommon.IsHexAddress(c.Param("target")) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid target address"})
+			return
+		}
+		var kick bool
+		switch c.Param("vote") {
+		case "kick":
+			kick = true
+		case "no-kick":
+		default:
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid vote, expected kick or no-kick"})
+			return
+		}
+
+		result, err := o.Reviews.Vote(ethcommon.HexToAddress(c.Param("sponsorship")), ethcommon.HexToAddress(c.Param("target")), kick, "api")
+		if err != nil {
+			status := http.StatusInternalServerError
+			if errors.Is(err, blockchain.ErrIndexerDisabled) {
+				status = http.StatusServiceUnavailable
+			} else if errors.Is(err, models.ErrUnknownReview) {
+				status = http.StatusNotFound
+			} else if errors.Is(err, models.ErrAlreadyVoted) {
+				status = http.StatusConflict
+			}
+			c.JSON(status, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, result)
+	}
+
+	return gin.HandlerFunc(fn)
+}
+
+// Flag godoc
+// @Summary      Flag an operator.
+// @Description  Flags an operator for misbehaving in a sponsorship our operator is staked in. Part of our stake is locked until the flag is resolved and is lost if the reviewers don't kick the target.
+// @Tags         Reviews
+// @Produce      json
+// @Param        sponsorship  path      string  true   "sponsorship address"
+// @Param        target       path      string  true   "operator address to flag"
+// @Param        metadata     query     string  false  "flag metadata, e.g. the partition that wasn't served"
+// @Success      200  {string}  string
+// @Router       /flag/{sponsorship}/{target} [get]
+func Flag(o *models.Operator) gin.HandlerFunc {
+	fn := func(c *gin.Context) {
+		if !ethcommon.IsHexAddress(c.Param("sponsorship")) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid sponsorship address"})
+			return
+		}
+		if !ethcommon.IsHexAddress(c.Param("target")) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid target address"})
+			return
+		}
+
+		tx, err := o.Flag(ethcommon.HexToAddress(c.Param("sponsorship")), ethcommon.HexToAddress(c.Param("target")), c.Query("metadata"))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, tx)
+	}
+
+	return gin.HandlerFunc(fn)
+}
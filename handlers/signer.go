@@ -0,0 +1,63 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"streamr_api/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Signer godoc
+// @Summary      Verify the signing key.
+// @Description  Compares the address of the signing key with the configured owner and the owner of the operator contract, reads the roles it holds and responds with the actions it can take (stake, reduceStakeTo, withdraw, ...). Requests for actions it can't take are refused with 403. If the contract can't be read, the roles last read are used, and without any requests needing a role are refused with 503. The result is reused for a few minutes unless refresh is set.
+// @Tags         Operator
+// @Produce      json
+// @Param        refresh  query     bool  false  "read the contract again"
+// @Success      200  {object}  models.SignerStatus
+// @Router       /operator/signer [get]
+func Signer(o *models.Operator) gin.HandlerFunc {
+	fn := func(c *gin.Context) {
+		if o.Signer == nil {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "signer verification is not running"})
+			return
+		}
+
+		var result models.SignerStatus
+		if c.Query("refresh") == "true" {
+			result = o.Signer.Check()
+		} else {
+			result = o.Signer.Status()
+		}
+
+		c.JSON(http.StatusOK, result)
+	}
+
+	return gin.HandlerFunc(fn)
+}
+
+// RequireSigner refuses the request with 403 if the signing key can't take the action, and with 503
+// if its roles have never been read. Dry runs send nothing and are let through.
+func RequireSigner(o *models.Operator, action string) gin.HandlerFunc {
+	fn := func(c *gin.Context) {
+		if o.Signer == nil || c.Query("dryRun") == "true" {
+			c.Next()
+			return
+		}
+
+		if err := o.Signer.Allowed(action); err != nil {
+			status := http.StatusInternalServerError
+			if errors.Is(err, models.ErrSignerNotPermitted) {
+				status = http.StatusForbidden
+			} else if errors.Is(err, models.ErrSignerUnverified) {
+				status = http.StatusServiceUnavailable
+			}
+			c.AbortWithStatusJSON(status, gin.H{"error": err.Error()})
+			return
+		}
+		c.Next()
+	}
+
+	return gin.HandlerFunc(fn)
+}
@@ -0,0 +1,149 @@
+package handlers
+
+import (
+	"net/http"
+
+	"streamr_api/common"
+	"streamr_api/models"
+
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/gin-gonic/gin"
+)
+
+// TokenInfo godoc
+// @Summary      Get the DATA token.
+// @Description  Responds with the address of the DATA token, whether it came from DATA_TOKEN_ADDRESS or the operator contract, and its symbol, decimals and total supply.
+// @Tags         Token
+// @Produce      json
+// @Success      200  {object}  models.TokenInfo
+// @Router       /token [get]
+func TokenInfo(o *models.Operator) gin.HandlerFunc {
+	fn := func(c *gin.Context) {
+		result, err := o.GetTokenInfo()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, result)
+	}
+
+	return gin.HandlerFunc(fn)
+}
+
+// TokenBalances godoc
+// @Summary      Get the DATA balances.
+// @Description  Responds with the DATA held by the operator contract, the owner wallet and the signing wallet.
+// @Tags         Token
+// @Produce      json
+// @Success      200  {object}  models.TokenBalances
+// @Router       /token/balances [get]
+func TokenBalances(o *models.Operator) gin.HandlerFunc {
+	fn := func(c *gin.Context) {
+		result, err := o.GetTokenBalances()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, result)
+	}
+
+	return gin.HandlerFunc(fn)
+}
+
+// TokenBalance godoc
+// @Summary      Get the DATA balance of an account.
+// @Description  Responds with the DATA held by any account.
+// @Tags         Token
+// @Produce      json
+// @Param        address  path      string  true  "account address"
+// @Success      200  {object}  common.Amount
+// @Router       /token/balance/{address} [get]
+func TokenBalance(o *models.Operator) gin.HandlerFunc {
+	fn := func(c *gin.Context) {
+		if !ethcommon.IsHexAddress(c.Param("address")) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid address"})
+			return
+		}
+
+		result, err := o.GetWalletDataBalance(ethcommon.HexToAddress(c.Param("address")))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, common.NewAmount(result))
+	}
+
+	return gin.HandlerFunc(fn)
+}
+
+// TokenAllowance godoc
+// @Summary      Get a DATA allowance.
+// @Description  Responds with how much DATA the spender may transfer from the owner, by default the signing wallet.
+// @Tags         Token
+// @Produce      json
+// @Param        spender  path      string  true   "spender address"
+// @Param        owner    query     string  false  "owner address, the signing wallet by default"
+// @Success      200  {object}  models.Allowance
+// @Router       /token/allowance/{spender} [get]
+func TokenAllowance(o *models.Operator) gin.HandlerFunc {
+	fn := func(c *gin.Context) {
+		if !ethcommon.IsHexAddress(c.Param("spender")) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid spender address"})
+			return
+		}
+		owner := o.TxManager.SignerAddress()
+		if c.Query("owner") != "" {
+			if !ethcommon.IsHexAddress(c.Query("owner")) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid owner address"})
+				return
+			}
+			owner = ethcommon.HexToAddress(c.Query("owner"))
+		}
+
+		result, err := o.GetAllowance(owner, ethcommon.HexToAddress(c.Param("spender")))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, result)
+	}
+
+	return gin.HandlerFunc(fn)
+}
+
+// TokenApprove godoc
+// @Summary      Approve a DATA spender.
+// @Description  Sets how much DATA the spender may transfer from the signing wallet. 0 revokes the approval. Responds with the allowance before and after once the transaction is mined; nothing is sent when the allowance already is the amount.
+// @Tags         Token
+// @Produce      json
+// @Param        spender  path      string  true  "spender address"
+// @Param        amount   path      string  true  "amount, e.g. 1500.25DATA or 1500250000000000000000wei; plain integers are wei"
+// @Success      200  {object}  models.ApprovalResult
+// @Router       /token/approve/{spender}/{amount} [get]
+func TokenApprove(o *models.Operator) gin.HandlerFunc {
+	fn := func(c *gin.Context) {
+		if !ethcommon.IsHexAddress(c.Param("spender")) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid spender address"})
+			return
+		}
+		amount, err := common.ParseAmount(c.Param("amount"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		result, err := o.Approve(ethcommon.HexToAddress(c.Param("spender")), amount)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error(), "result": result})
+			return
+		}
+
+		c.JSON(http.StatusOK, result)
+	}
+
+	return gin.HandlerFunc(fn)
+}
@@ -0,0 +1,54 @@
+package handlers
+
+import (
+	"net/http"
+
+	"streamr_api/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Wallet godoc
+// @Summary      Get the signing wallet status.
+// @Description  Responds with the POL and DATA balances of the wallet that signs and pays for transactions at the last check, the average daily gas spend from the transaction journal and how many days of gas the POL covers.
+// @Tags         Wallet
+// @Produce      json
+// @Success      200  {object}  models.WalletStatus
+// @Router       /wallet [get]
+func Wallet(o *models.Operator) gin.HandlerFunc {
+	fn := func(c *gin.Context) {
+		if o.Wallet == nil {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "wallet monitor is not running"})
+			return
+		}
+		c.JSON(http.StatusOK, o.Wallet.Status())
+	}
+
+	return gin.HandlerFunc(fn)
+}
+
+// WalletCheck godoc
+// @Summary      Check the signing wallet now.
+// @Description  Reads the balances and the gas spend fresh, updates the metrics and alerts if the POL runs low.
+// @Tags         Wallet
+// @Produce      json
+// @Success      200  {object}  models.WalletStatus
+// @Router       /wallet/check [get]
+func WalletCheck(o *models.Operator) gin.HandlerFunc {
+	fn := func(c *gin.Context) {
+		if o.Wallet == nil {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "wallet monitor is not running"})
+			return
+		}
+
+		result, err := o.Wallet.Check()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error(), "status": result})
+			return
+		}
+
+		c.JSON(http.StatusOK, result)
+	}
+
+	return gin.HandlerFunc(fn)
+}
@@ -0,0 +1,104 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"streamr_api/common"
+	"streamr_api/models"
+
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/gin-gonic/gin"
+)
+
+// SelectiveWithdraw godoc
+// @Summary      Withdraw earnings from chosen sponsorships.
+// @Description  Withdraws earnings only from the sponsorships matching the filters, which combine: an explicit list, a minimum of earnings per sponsorship and the top N by earnings. Sponsorships without earnings are always skipped, and a sponsorship listed twice is withdrawn from once. Refused with 400 when a listed sponsorship isn't staked in or nothing with earnings matches. Responds with the expected earnings per sponsorship and, once the transaction is mined, the amounts actually withdrawn.
+// @Tags         Operator
+// @Produce      json
+// @Param        sponsorships  query     string  false  "comma separated sponsorship addresses (default all)"
+// @Param        minEarnings   query     string  false  "minimum earnings per sponsorship, e.g. 10DATA"
+// @Param        top           query     int     false  "only the N sponsorships with the most earnings"
+// @Param        dryRun        query     bool    false  "only show what would be withdrawn"
+// @Param        wait          query     int     false  "seconds to wait for the transaction before responding (default 120, 0 responds right away)"
+// @Success      200  {object}  models.WithdrawalResult
+// @Router       /operator/withdrawearnings/selective [get]
+func SelectiveWithdraw(o *models.Operator) gin.HandlerFunc {
+	fn := func(c *gin.Context) {
+		var selection models.WithdrawalSelection
+		var err error
+
+		selection.Sponsorships, err = parseAddressList(c.Query("sponsorships"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if c.Query("minEarnings") != "" {
+			selection.MinEarnings, err = common.ParseAmount(c.Query("minEarnings"))
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+		}
+		if c.Query("top") != "" {
+			selection.TopN, err = strconv.Atoi(c.Query("top"))
+			if err != nil || selection.TopN < 0 {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid top"})
+				return
+			}
+		}
+		wait, err := strconv.Atoi(c.DefaultQuery("wait", "120"))
+		if err != nil || wait < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid wait"})
+			return
+		}
+
+		if c.Query("dryRun") == "true" {
+			result, err := o.PlanWithdrawal(selection)
+			if errors.Is(err, models.ErrInvalidSelection) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
+			c.JSON(http.StatusOK, result)
+			return
+		}
+
+		result, err := o.WithdrawSelected(selection, time.Duration(wait)*time.Second)
+		if errors.Is(err, models.ErrInvalidSelection) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "result": result})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error(), "result": result})
+			return
+		}
+
+		c.JSON(http.StatusOK, result)
+	}
+
+	return gin.HandlerFunc(fn)
+}
+
+// parseAddressList parses a comma separated list of addresses. An empty value yields an empty list.
+func parseAddressList(value string) ([]ethcommon.Address, error) {
+	addresses := []ethcommon.Address{}
+	if value == "" {
+		return addresses, nil
+	}
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if !ethcommon.IsHexAddress(item) {
+			return nil, fmt.Errorf("invalid address: %s", item)
+		}
+		addresses = append(addresses, ethcommon.HexToAddress(item))
+	}
+	return addresses, nil
+}
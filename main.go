@@ -1,13 +1,27 @@
 package main
 
 import (
+	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"streamr_api/blockchain"
 	"streamr_api/common"
+	"streamr_api/config"
 	"streamr_api/models"
 	"streamr_api/routes"
 
 	_ "streamr_api/docs"
 
+	ethcommon "github.com/ethereum/go-ethereum/common"
 	swaggerFiles "github.com/swaggo/files"
 	ginSwagger "github.com/swaggo/gin-swagger"
 )
@@ -21,23 +35,172 @@ import (
 
 // @BasePath  /api/v1
 
+// version is set at build time by the makefile with -X main.version.
+var version = "dev"
+
 func init() {
 
 }
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "export" {
+		if err := runExport(os.Args[2:]); err != nil {
+			log.Fatalf("Export failed: %v", err)
+		}
+		return
+	}
+
+	log.Printf("Starting streamr_api %s", version)
+	configPath := config.Path()
+	cfg, err := config.Load(configPath)
+	if err == nil {
+		err = models.CheckPolicies(cfg)
+	}
+	if err != nil {
+		log.Fatalf("Failed to load configuration: %v", err)
+	}
+
+	registry, err := models.NewRegistry(cfg)
+	if err != nil {
+		log.Fatalf("Failed to create operators: %v", err)
+	}
+
+	store, err := blockchain.OpenStore(cfg.Server.DBPath)
+	if err != nil {
+		log.Fatalf("Failed to open database: %v", err)
+	}
 
-	operator := models.NewOperator(
-		common.GetStringEnvWithDefault("CONTRACT_ADDR", "0x1234567890"),
-		common.GetStringEnvWithDefault("OWNER_ADDR", "0x1234567890"),
-		common.GetStringEnvWithDefault("PRIVATE_KEY", "0x1234567890"),
-	)
+	err = registry.StartServices(store)
+	if err != nil {
+		log.Fatalf("Failed to start operator services: %v", err)
+	}
 
-	scheduler := models.NewScheduler()
+	scheduler := models.NewScheduler(cfg)
 
-	router := routes.SetupRouter(operator, scheduler)
+	lifecycle := models.NewLifecycle(registry, scheduler, store, time.Duration(cfg.Server.ShutdownSeconds)*time.Second)
+	go watchReload(configPath, cfg, registry, lifecycle)
+
+	router := routes.SetupRouter(registry, scheduler, lifecycle, cfg.Auth, version)
 
 	router.GET("/docs/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
 
-	router.Run(fmt.Sprintf(":%d", common.GetIntEnvWithDefault("PORT", 8080)))
+	if err := lifecycle.Serve(router, fmt.Sprintf(":%d", cfg.Server.Port)); err != nil {
+		log.Printf("Server failed: %v", err)
+	}
+}
+
+// watchReload applies the policies and notifications of the configuration file on SIGHUP. A
+// configuration that doesn't validate is ignored, and changes to the other sections only take
+// effect on restart. Nothing is reloaded once the service is shutting down; the registry refuses
+// reloads once its services are stopped, so a reload racing the shutdown can't restart them.
+func watchReload(path string, current *config.Config, registry *models.Registry, lifecycle *models.Lifecycle) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	for range hup {
+		if lifecycle.Draining() {
+			continue
+		}
+		next, err := config.Load(path)
+		if err == nil {
+			err = models.CheckPolicies(next)
+		}
+		if err != nil {
+			log.Printf("Configuration not reloaded: %v", err)
+			continue
+		}
+		if sections := current.RestartRequired(next); len(sections) > 0 {
+			log.Printf("Configuration changes to %s need a restart and were not applied", strings.Join(sections, ", "))
+		}
+
+		if err := registry.Reload(next); errors.Is(err, models.ErrServicesStopped) {
+			continue
+		} else if err != nil {
+			log.Printf("Failed to reload configuration: %v", err)
+			continue
+		}
+		applied := *current
+		applied.Policies, applied.Notifications = next.Policies, next.Notifications
+		current = &applied
+		log.Printf("Configuration reloaded from %s", path)
+	}
+}
+
+// runExport implements the "export" command, which writes the ledger of a configured operator
+// straight from the database in the configuration. The database can only be opened by one process,
+// so stop the service first or use the /export/ledger endpoint instead.
+func runExport(args []string) error {
+	flags := flag.NewFlagSet("export", flag.ExitOnError)
+	format := flags.String("format", "csv", "output format, csv or json")
+	from := flags.String("from", "", "start time, RFC3339, YYYY-MM-DD or unix seconds")
+	to := flags.String("to", "", "end time, RFC3339, YYYY-MM-DD or unix seconds")
+	out := flags.String("out", "", "output file (default stdout)")
+	name := flags.String("operator", "", "operator name, required when several are configured")
+	flags.Parse(args)
+
+	cfg, err := config.Load(config.Path())
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %v", err)
+	}
+	operator, err := exportOperator(cfg, *name)
+	if err != nil {
+		return err
+	}
+	if *format != "csv" && *format != "json" {
+		return fmt.Errorf("unknown format %q", *format)
+	}
+
+	var fromTime, toTime time.Time
+	if *from != "" {
+		if fromTime, err = common.ParseTime(*from); err != nil {
+			return err
+		}
+	}
+	if *to != "" {
+		if toTime, err = common.ParseTime(*to); err != nil {
+			return err
+		}
+	}
+
+	store, err := blockchain.OpenStore(cfg.Server.DBPath)
+	if err != nil {
+		return fmt.Errorf("failed to open database %s (is the service running?): %v", cfg.Server.DBPath, err)
+	}
+	defer store.Close()
+
+	rows, err := models.BuildLedger(store, ethcommon.HexToAddress(operator.Contract), fromTime, toTime)
+	if err != nil {
+		return err
+	}
+
+	var w io.Writer = os.Stdout
+	if *out != "" {
+		file, err := os.Create(*out)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		w = file
+	}
+
+	if *format == "json" {
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(rows)
+	}
+	return models.WriteLedgerCSV(w, rows)
+}
+
+// exportOperator picks the operator named name, or the only one configured if name is empty.
+func exportOperator(cfg *config.Config, name string) (config.OperatorConfig, error) {
+	names := []string{}
+	for _, o := range cfg.Operators {
+		if o.Name == name || (name == "" && len(cfg.Operators) == 1) {
+			return o, nil
+		}
+		names = append(names, o.Name)
+	}
+	if name == "" {
+		return config.OperatorConfig{}, fmt.Errorf("several operators are configured, pick one with -operator: %s", strings.Join(names, ", "))
+	}
+	return config.OperatorConfig{}, fmt.Errorf("no operator %q is configured, expected one of %s", name, strings.Join(names, ", "))
 }
@@ -0,0 +1,142 @@
+package metrics
+
+import (
+	"math/big"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const namespace = "streamr_operator"
+
+// On-chain state, refreshed by the background collector so scrapes never hit the RPC. The series
+// are labelled with the name of the operator they belong to.
+var (
+	ValueWithoutEarnings = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "value_without_earnings_data",
+		Help:      "Operator value without unwithdrawn earnings, in DATA.",
+	}, []string{"operator"})
+	TotalDeployedStake = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "deployed_stake_data",
+		Help:      "Total stake deployed into sponsorships, in DATA.",
+	}, []string{"operator"})
+	SponsorshipStake = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "sponsorship_stake_data",
+		Help:      "Stake deployed into a sponsorship, in DATA.",
+	}, []string{"operator", "sponsorship"})
+	SponsorshipEarnings = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "sponsorship_earnings_data",
+		Help:      "Unwithdrawn earnings in a sponsorship, in DATA.",
+	}, []string{"operator", "sponsorship"})
+	EarningsRatio = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "earnings_max_allowed_ratio",
+		Help:      "Total unwithdrawn earnings divided by maxAllowedEarnings. The operator can be slashed above 1.",
+	}, []string{"operator"})
+	UndelegationQueueLength = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "undelegation_queue_length",
+		Help:      "Number of entries in the undelegation queue.",
+	}, []string{"operator"})
+	UndelegationQueueTotal = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "undelegation_queue_data",
+		Help:      "Total amount in the undelegation queue, in DATA.",
+	}, []string{"operator"})
+	OwnerBalance = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "owner_balance_pol",
+		Help:      "POL balance of the owner account paying for gas.",
+	}, []string{"operator"})
+	WalletPOLBalance = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "wallet_balance_pol",
+		Help:      "POL balance of the signing wallet, which pays for gas.",
+	}, []string{"operator", "signer"})
+	WalletDataBalance = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "wallet_balance_data",
+		Help:      "DATA balance of the signing wallet.",
+	}, []string{"operator"})
+	GasDailySpend = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "gas_daily_spend_pol",
+		Help:      "Average POL spent on gas per day over the recent transactions in the journal.",
+	}, []string{"operator"})
+	GasDaysCovered = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "gas_days_covered",
+		Help:      "Days of gas the POL balance of the signing wallet covers at the recent spend, -1 without recent spend.",
+	}, []string{"operator"})
+	CollectorLastSuccess = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "collector_last_success_timestamp_seconds",
+		Help:      "Unix time of the last complete refresh of the on-chain gauges.",
+	}, []string{"operator"})
+	CollectorErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "collector_errors_total",
+		Help:      "Failed reads while refreshing the on-chain gauges.",
+	}, []string{"operator"})
+)
+
+// Service activity.
+var (
+	RPCCalls = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "rpc_calls_total",
+		Help:      "JSON-RPC requests sent to the node, by method and outcome (ok or error).",
+	}, []string{"method", "outcome"})
+	RPCDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "rpc_duration_seconds",
+		Help:      "Duration of JSON-RPC requests to the node, by method.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method"})
+	Transactions = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "transactions_total",
+		Help:      "Transactions by contract method and outcome (rejected, sent, mined, reverted or dropped).",
+	}, []string{"method", "outcome"})
+	GasSpent = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "gas_spent_pol_total",
+		Help:      "Gas paid for mined transactions, in POL, by contract method.",
+	}, []string{"method"})
+	GasUsed = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "gas_used",
+		Help:      "Gas used by mined transactions, by contract method.",
+		Buckets:   prometheus.ExponentialBuckets(25000, 2, 8),
+	}, []string{"method"})
+	CronRuns = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "cron_runs_total",
+		Help:      "Cron job runs by job name.",
+	}, []string{"job"})
+	CronFailures = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "cron_failures_total",
+		Help:      "Cron job runs that failed to reach their endpoint or got an error status, by job name.",
+	}, []string{"job"})
+	CronDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "cron_duration_seconds",
+		Help:      "Duration of cron job runs, by job name.",
+		Buckets:   []float64{0.1, 0.5, 1, 5, 15, 60, 300},
+	}, []string{"job"})
+)
+
+// Float converts an 18 decimals token amount to a float for use in a gauge. Precision beyond
+// float64 is lost, which doesn't matter for monitoring.
+func Float(wei *big.Int) float64 {
+	if wei == nil {
+		return 0
+	}
+	value, _ := new(big.Float).Quo(new(big.Float).SetInt(wei), big.NewFloat(1e18)).Float64()
+	return value
+}
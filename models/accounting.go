@@ -0,0 +1,586 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"math/big"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"streamr_api/blockchain"
+	"streamr_api/common"
+	"streamr_api/config"
+
+	ethcommon "github.com/ethereum/go-ethereum/common"
+)
+
+const (
+	PeriodDaily   = "daily"
+	PeriodWeekly  = "weekly"
+	PeriodMonthly = "monthly"
+)
+
+type AccountingConfig struct {
+	SnapshotInterval time.Duration
+}
+
+// EarningsSnapshot records the unwithdrawn earnings and the stake of every sponsorship at a point in time.
+type EarningsSnapshot struct {
+	Timestamp time.Time                            `json:"timestamp"`
+	Earnings  map[ethcommon.Address]*common.Amount `json:"earnings"`
+	Stakes    map[ethcommon.Address]*common.Amount `json:"stakes"`
+}
+
+// WithdrawalRecord is an earnings withdrawal. Earnings holds the unwithdrawn earnings of each
+// sponsorship right before the withdrawal. Withdrawals sent outside the service are detected from
+// the earnings dropping between two snapshots; they have no tx hash, their earnings are the ones
+// of the earlier snapshot and their timestamp is the one of the later.
+type WithdrawalRecord struct {
+	Timestamp    time.Time                            `json:"timestamp"`
+	TxHash       string                               `json:"txHash"`
+	Earnings     map[ethcommon.Address]*common.Amount `json:"earnings"`
+	Total        *common.Amount                       `json:"total"`
+	ProtocolFee  *common.Amount                       `json:"protocolFee"`
+	OperatorCut  *common.Amount                       `json:"operatorCut"`
+	FeeEstimated bool                                 `json:"feeEstimated"` // true until the Profit event of the tx is indexed
+	External     bool                                 `json:"external"`     // not sent by the service
+}
+
+type AccountingPeriod struct {
+	Start           time.Time                            `json:"start"`
+	End             time.Time                            `json:"end"`
+	Accrued         map[ethcommon.Address]*common.Amount `json:"accrued"`
+	AccruedTotal    *common.Amount                       `json:"accruedTotal"`
+	Withdrawn       map[ethcommon.Address]*common.Amount `json:"withdrawn"`
+	WithdrawnTotal  *common.Amount                       `json:"withdrawnTotal"`
+	ProtocolFee     *common.Amount                       `json:"protocolFee"`
+	OperatorCut     *common.Amount                       `json:"operatorCut"`
+	DelegatorsShare *common.Amount                       `json:"delegatorsShare"`
+	GasSpent        *common.Amount                       `json:"gasSpent"` // in POL
+	Transactions    int                                  `json:"transactions"`
+}
+
+type SponsorshipYield struct {
+	Sponsorship   ethcommon.Address `json:"sponsorship"`
+	AverageStake  *common.Amount    `json:"averageStake"`
+	Accrued       *common.Amount    `json:"accrued"`
+	Withdrawn     *common.Amount    `json:"withdrawn"`
+	AccruedYield  float64           `json:"accruedYield"`  // annualized accrued earnings / average stake
+	RealizedYield float64           `json:"realizedYield"` // annualized withdrawn earnings / average stake
+}
+
+// Accounting periodically snapshots the operator's earnings and stake, records the withdrawals sent
+// by the service, and derives earnings, fee and gas reports from them and the tx journal.
+type Accounting struct {
+	o      *Operator
+	store  *blockchain.Store
+	config atomic.Pointer[AccountingConfig]
+	prefix string
+
+	mu   sync.Mutex
+	quit chan struct{}
+	wg   sync.WaitGroup
+}
+
+type accrual struct {
+	timestamp   time.Time
+	sponsorship ethcommon.Address
+	amount      *big.Int
+}
+
+func NewAccountingConfig(p config.AccountingPolicy) AccountingConfig {
+	return AccountingConfig{
+		SnapshotInterval: time.Duration(p.SnapshotMinutes) * time.Minute,
+	}
+}
+
+func NewAccounting(o *Operator, store *blockchain.Store, config AccountingConfig) *Accounting {
+	a := &Accounting{
+		o:      o,
+		store:  store,
+		prefix: fmt.Sprintf("acct/%s/", strings.ToLower(o.ContractAddr.Hex())),
+		quit:   make(chan struct{}),
+	}
+	a.config.Store(&config)
+	return a
+}
+
+func (a *Accounting) Start() {
+	a.wg.Add(1)
+	go func() {
+		defer a.wg.Done()
+
+		ticker := time.NewTicker(a.config.Load().SnapshotInterval)
+		defer ticker.Stop()
+
+		for {
+			if _, err := a.Snapshot(); err != nil {
+				log.Printf("Failed to take earnings snapshot: %v", err)
+			}
+			if err := a.resolveFees(); err != nil {
+				log.Printf("Failed to resolve withdrawal fees: %v", err)
+			}
+
+			select {
+			case <-a.quit:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+func (a *Accounting) Stop() {
+	close(a.quit)
+	a.wg.Wait()
+}
+
+// Reload restarts the service with a new configuration once the snapshot in progress is done.
+func (a *Accounting) Reload(config AccountingConfig) {
+	a.Stop()
+	a.config.Store(&config)
+	a.quit = make(chan struct{})
+	a.Start()
+}
+
+// Snapshot reads the current earnings and stake of every sponsorship and stores them.
+func (a *Accounting) Snapshot() (EarningsSnapshot, error) {
+	sponsors, err := a.o.GetSponsorshipsAndEarnings()
+	if err != nil {
+		return EarningsSnapshot{}, err
+	}
+
+	snapshot := EarningsSnapshot{
+		Timestamp: time.Now().UTC(),
+		Earnings:  make(map[ethcommon.Address]*common.Amount),
+		Stakes:    make(map[ethcommon.Address]*common.Amount),
+	}
+	for i, addr := range sponsors.Addresses {
+		staked, err := a.o.StakedInto(addr)
+		if err != nil {
+			return EarningsSnapshot{}, err
+		}
+		snapshot.Earnings[addr] = sponsors.Earnings[i]
+		snapshot.Stakes[addr] = staked.StakedInto
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	err = a.store.Put(fmt.Sprintf("%ssnap/%020d", a.prefix, snapshot.Timestamp.UnixNano()), snapshot)
+	if err != nil {
+		return EarningsSnapshot{}, err
+	}
+	return snapshot, nil
+}
+
+// RecordWithdrawal stores a withdrawal of the given sponsorships' earnings. before must be a
+// snapshot taken right before the withdrawal was sent. The fee split is estimated from the
+// current fractions until the Profit event of the transaction has been indexed.
+func (a *Accounting) RecordWithdrawal(txHash string, before EarningsSnapshot, sponsorships []ethcommon.Address) error {
+	record := WithdrawalRecord{
+		Timestamp:    time.Now().UTC(),
+		TxHash:       txHash,
+		Earnings:     make(map[ethcommon.Address]*common.Amount),
+		FeeEstimated: true,
+	}
+	for _, addr := range sponsorships {
+		if earnings, ok := before.Earnings[addr]; ok {
+			record.Earnings[addr] = earnings
+		}
+	}
+	record.estimateFees(a.feeFractions())
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.store.Put(fmt.Sprintf("%swd/%020d", a.prefix, record.Timestamp.UnixNano()), record)
+}
+
+// Withdrawals returns the withdrawals in [from, to], oldest first, both the ones sent by the service
+// and the ones detected from the snapshots. Zero times mean no bound.
+func (a *Accounting) Withdrawals(from time.Time, to time.Time) ([]WithdrawalRecord, error) {
+	_, withdrawals, err := a.history(from, to)
+	return withdrawals, err
+}
+
+// recordedWithdrawals returns the withdrawals sent by the service in [from, to], oldest first.
+// Withdrawals whose transaction reverted or was dropped withdrew nothing and are left out.
+func (a *Accounting) recordedWithdrawals(from time.Time, to time.Time) ([]WithdrawalRecord, error) {
+	journal := a.o.TxManager.Journal()
+	records := []WithdrawalRecord{}
+	err := a.store.ForEach(a.prefix+"wd/", func(key string, value []byte) error {
+		var record WithdrawalRecord
+		if err := json.Unmarshal(value, &record); err != nil {
+			return err
+		}
+		if !inRange(record.Timestamp, from, to) {
+			return nil
+		}
+		if journal != nil {
+			entry, found, err := journal.Get(record.TxHash)
+			if err != nil {
+				return err
+			}
+			if found && (entry.Status == blockchain.TxFailed || entry.Status == blockchain.TxDropped) {
+				return nil
+			}
+		}
+		records = append(records, record)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return records, nil
+}
+
+// Summaries aggregates accrued and withdrawn earnings, fees and gas spent into daily, weekly or monthly periods.
+func (a *Accounting) Summaries(period string, from time.Time, to time.Time) ([]AccountingPeriod, error) {
+	if period != PeriodDaily && period != PeriodWeekly && period != PeriodMonthly {
+		return nil, fmt.Errorf("unknown period %q, expected %s, %s or %s", period, PeriodDaily, PeriodWeekly, PeriodMonthly)
+	}
+
+	accruals, withdrawals, err := a.history(from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	periods := make(map[time.Time]*AccountingPeriod)
+	periodFor := func(t time.Time) *AccountingPeriod {
+		start := periodStart(t, period)
+		p, ok := periods[start]
+		if !ok {
+			p = &AccountingPeriod{
+				Start:           start,
+				End:             periodEnd(start, period),
+				Accrued:         make(map[ethcommon.Address]*common.Amount),
+				AccruedTotal:    common.NewAmount(nil),
+				Withdrawn:       make(map[ethcommon.Address]*common.Amount),
+				WithdrawnTotal:  common.NewAmount(nil),
+				ProtocolFee:     common.NewAmount(nil),
+				OperatorCut:     common.NewAmount(nil),
+				DelegatorsShare: common.NewAmount(nil),
+				GasSpent:        common.NewAmount(nil),
+			}
+			periods[start] = p
+		}
+		return p
+	}
+
+	for _, acc := range accruals {
+		p := periodFor(acc.timestamp)
+		addTo(p.Accrued, acc.sponsorship, acc.amount)
+		p.AccruedTotal.Int().Add(p.AccruedTotal.Int(), acc.amount)
+	}
+
+	for _, w := range withdrawals {
+		p := periodFor(w.Timestamp)
+		for addr, amount := range w.Earnings {
+			addTo(p.Withdrawn, addr, amount.Int())
+		}
+		p.WithdrawnTotal.Int().Add(p.WithdrawnTotal.Int(), w.Total.Int())
+		p.ProtocolFee.Int().Add(p.ProtocolFee.Int(), w.ProtocolFee.Int())
+		p.OperatorCut.Int().Add(p.OperatorCut.Int(), w.OperatorCut.Int())
+		delegators := new(big.Int).Sub(w.Total.Int(), w.ProtocolFee.Int())
+		p.DelegatorsShare.Int().Add(p.DelegatorsShare.Int(), delegators.Sub(delegators, w.OperatorCut.Int()))
+	}
+
+	if journal := a.o.TxManager.Journal(); journal != nil {
+		entries, err := journal.List(time.Time{}, time.Time{})
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range entries {
+			if entry.GasCostWei == nil || !inRange(entry.MinedAt, from, to) {
+				continue
+			}
+			p := periodFor(entry.MinedAt)
+			p.GasSpent.Int().Add(p.GasSpent.Int(), entry.GasCostWei)
+			p.Transactions++
+		}
+	}
+
+	summaries := []AccountingPeriod{}
+	for _, p := range periods {
+		summaries = append(summaries, *p)
+	}
+	sort.Slice(summaries, func(i, j int) bool { return summaries[i].Start.Before(summaries[j].Start) })
+	return summaries, nil
+}
+
+// Yield returns the annualized accrued and realized (withdrawn) yield of each sponsorship in [from, to]
+// relative to its average stake in that time.
+func (a *Accounting) Yield(from time.Time, to time.Time) ([]SponsorshipYield, error) {
+	snapshots, err := a.snapshots()
+	if err != nil {
+		return nil, err
+	}
+	accruals, withdrawals, err := a.history(from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	start, end := from, to
+	if end.IsZero() {
+		end = time.Now().UTC()
+	}
+	stakeSums := make(map[ethcommon.Address]*common.Amount)
+	stakeCounts := make(map[ethcommon.Address]int64)
+	for _, snapshot := range snapshots {
+		if !inRange(snapshot.Timestamp, from, to) {
+			continue
+		}
+		if start.IsZero() {
+			start = snapshot.Timestamp
+		}
+		for addr, stake := range snapshot.Stakes {
+			addTo(stakeSums, addr, stake.Int())
+			stakeCounts[addr]++
+		}
+	}
+	years := end.Sub(start).Hours() / (24 * 365)
+
+	accrued := make(map[ethcommon.Address]*common.Amount)
+	for _, acc := range accruals {
+		addTo(accrued, acc.sponsorship, acc.amount)
+	}
+	withdrawn := make(map[ethcommon.Address]*common.Amount)
+	for _, w := range withdrawals {
+		for addr, amount := range w.Earnings {
+			addTo(withdrawn, addr, amount.Int())
+		}
+	}
+
+	yields := []SponsorshipYield{}
+	for addr, sum := range stakeSums {
+		y := SponsorshipYield{
+			Sponsorship:  addr,
+			AverageStake: common.NewAmount(new(big.Int).Div(sum.Int(), big.NewInt(stakeCounts[addr]))),
+			Accrued:      common.NewAmount(nil),
+			Withdrawn:    common.NewAmount(nil),
+		}
+		if amount, ok := accrued[addr]; ok {
+			y.Accrued = amount
+		}
+		if amount, ok := withdrawn[addr]; ok {
+			y.Withdrawn = amount
+		}
+		if y.AverageStake.Int().Sign() > 0 && years > 0 {
+			y.AccruedYield = ratio(y.Accrued.Int(), y.AverageStake.Int()) / years
+			y.RealizedYield = ratio(y.Withdrawn.Int(), y.AverageStake.Int()) / years
+		}
+		yields = append(yields, y)
+	}
+	sort.Slice(yields, func(i, j int) bool { return yields[i].Sponsorship.Hex() < yields[j].Sponsorship.Hex() })
+	return yields, nil
+}
+
+func (a *Accounting) snapshots() ([]EarningsSnapshot, error) {
+	snapshots := []EarningsSnapshot{}
+	err := a.store.ForEach(a.prefix+"snap/", func(key string, value []byte) error {
+		var snapshot EarningsSnapshot
+		if err := json.Unmarshal(value, &snapshot); err != nil {
+			return err
+		}
+		snapshots = append(snapshots, snapshot)
+		return nil
+	})
+	return snapshots, err
+}
+
+// history derives the earnings accrued by each sponsorship between consecutive snapshots and
+// returns them in [from, to] together with the withdrawals. If the sponsorship's earnings were
+// withdrawn by the service in between, everything in the later snapshot accrued after the
+// withdrawal; the earnings before it are covered by the pre-withdrawal snapshot. If they dropped
+// without such a withdrawal, they were withdrawn outside the service, which withdraws all of them:
+// the earlier snapshot's earnings are an external withdrawal and the later ones accrued after it.
+func (a *Accounting) history(from time.Time, to time.Time) ([]accrual, []WithdrawalRecord, error) {
+	snapshots, err := a.snapshots()
+	if err != nil {
+		return nil, nil, err
+	}
+	recorded, err := a.recordedWithdrawals(time.Time{}, time.Time{})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	accruals := []accrual{}
+	withdrawals := []WithdrawalRecord{}
+	var protocolFeeFraction, operatorsCutFraction *big.Int
+	for i := 1; i < len(snapshots); i++ {
+		prev, cur := snapshots[i-1], snapshots[i]
+		inPeriod := inRange(cur.Timestamp, from, to)
+		external := WithdrawalRecord{
+			Timestamp:    cur.Timestamp,
+			Earnings:     make(map[ethcommon.Address]*common.Amount),
+			FeeEstimated: true,
+			External:     true,
+		}
+		for addr, earnings := range cur.Earnings {
+			amount := new(big.Int).Set(earnings.Int())
+			if previous, ok := prev.Earnings[addr]; ok && !withdrawnBetween(recorded, addr, prev.Timestamp, cur.Timestamp) {
+				if amount.Cmp(previous.Int()) < 0 {
+					external.Earnings[addr] = previous
+				} else {
+					amount.Sub(amount, previous.Int())
+				}
+			}
+			if amount.Sign() > 0 && inPeriod {
+				accruals = append(accruals, accrual{timestamp: cur.Timestamp, sponsorship: addr, amount: amount})
+			}
+		}
+		if len(external.Earnings) > 0 && inPeriod {
+			if protocolFeeFraction == nil {
+				protocolFeeFraction, operatorsCutFraction = a.feeFractions()
+			}
+			external.estimateFees(protocolFeeFraction, operatorsCutFraction)
+			withdrawals = append(withdrawals, external)
+		}
+	}
+
+	for _, w := range recorded {
+		if inRange(w.Timestamp, from, to) {
+			withdrawals = append(withdrawals, w)
+		}
+	}
+	sort.SliceStable(withdrawals, func(i, j int) bool { return withdrawals[i].Timestamp.Before(withdrawals[j].Timestamp) })
+	return accruals, withdrawals, nil
+}
+
+// feeFractions returns the current protocol fee and operator's cut fractions to estimate the fee
+// split of withdrawals with.
+func (a *Accounting) feeFractions() (*big.Int, *big.Int) {
+	protocolFeeFraction, err := a.o.GetProtocolFeeFraction()
+	if err != nil {
+		// fall back to the protocol fee at the time of writing
+		log.Printf("Failed to get protocol fee fraction, assuming 5%%: %v", err)
+		protocolFeeFraction = new(big.Int).Div(fractionOne, big.NewInt(20))
+	}
+	operatorsCutFraction, err := a.o.GetOperatorsCutFraction()
+	if err != nil {
+		log.Printf("Failed to get operator's cut fraction: %v", err)
+		operatorsCutFraction = big.NewInt(0)
+	}
+	return protocolFeeFraction, operatorsCutFraction
+}
+
+// estimateFees sets the total of the withdrawal and estimates its fee split from the given fractions.
+func (w *WithdrawalRecord) estimateFees(protocolFeeFraction *big.Int, operatorsCutFraction *big.Int) {
+	total := big.NewInt(0)
+	for _, earnings := range w.Earnings {
+		total.Add(total, earnings.Int())
+	}
+	protocolFee := applyFraction(total, protocolFeeFraction)
+	w.Total = common.NewAmount(total)
+	w.ProtocolFee = common.NewAmount(protocolFee)
+	w.OperatorCut = common.NewAmount(applyFraction(new(big.Int).Sub(total, protocolFee), operatorsCutFraction))
+}
+
+// resolveFees replaces the estimated fee split of the recorded withdrawals with the values of their
+// Profit events once indexed. It runs with the snapshots, so reading the reports never writes.
+func (a *Accounting) resolveFees() error {
+	if a.o.Indexer == nil {
+		return nil
+	}
+	estimated := make(map[string]WithdrawalRecord)
+	err := a.store.ForEach(a.prefix+"wd/", func(key string, value []byte) error {
+		var record WithdrawalRecord
+		if err := json.Unmarshal(value, &record); err != nil {
+			return err
+		}
+		if record.FeeEstimated {
+			estimated[key] = record
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for key, record := range estimated {
+		a.resolveFee(key, &record)
+	}
+	return nil
+}
+
+func (a *Accounting) resolveFee(key string, record *WithdrawalRecord) {
+	events, err := a.o.Indexer.Events(blockchain.EventFilter{
+		Names:  []string{"Profit"},
+		TxHash: ethcommon.HexToHash(record.TxHash),
+	})
+	if err != nil || len(events) == 0 {
+		return
+	}
+
+	operatorCut, protocolFee := big.NewInt(0), big.NewInt(0)
+	for _, event := range events {
+		cut, ok1 := new(big.Int).SetString(event.Args["operatorsCutDataWei"], 10)
+		fee, ok2 := new(big.Int).SetString(event.Args["protocolFeeDataWei"], 10)
+		if !ok1 || !ok2 {
+			return
+		}
+		operatorCut.Add(operatorCut, cut)
+		protocolFee.Add(protocolFee, fee)
+	}
+	record.OperatorCut = common.NewAmount(operatorCut)
+	record.ProtocolFee = common.NewAmount(protocolFee)
+	record.FeeEstimated = false
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if err := a.store.Put(key, record); err != nil {
+		log.Printf("Failed to update withdrawal %s: %v", record.TxHash, err)
+	}
+}
+
+func withdrawnBetween(withdrawals []WithdrawalRecord, addr ethcommon.Address, from time.Time, to time.Time) bool {
+	for _, w := range withdrawals {
+		if _, ok := w.Earnings[addr]; ok && w.Timestamp.After(from) && !w.Timestamp.After(to) {
+			return true
+		}
+	}
+	return false
+}
+
+func periodStart(t time.Time, period string) time.Time {
+	t = t.UTC()
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	switch period {
+	case PeriodWeekly:
+		// weeks start on Monday
+		return day.AddDate(0, 0, -((int(day.Weekday()) + 6) % 7))
+	case PeriodMonthly:
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+	default:
+		return day
+	}
+}
+
+func periodEnd(start time.Time, period string) time.Time {
+	switch period {
+	case PeriodWeekly:
+		return start.AddDate(0, 0, 7)
+	case PeriodMonthly:
+		return start.AddDate(0, 1, 0)
+	default:
+		return start.AddDate(0, 0, 1)
+	}
+}
+
+func inRange(t time.Time, from time.Time, to time.Time) bool {
+	return (from.IsZero() || !t.Before(from)) && (to.IsZero() || !t.After(to))
+}
+
+func addTo(m map[ethcommon.Address]*common.Amount, addr ethcommon.Address, amount *big.Int) {
+	if _, ok := m[addr]; !ok {
+		m[addr] = common.NewAmount(nil)
+	}
+	m[addr].Int().Add(m[addr].Int(), amount)
+}
+
+func ratio(numerator *big.Int, denominator *big.Int) float64 {
+	r, _ := new(big.Float).Quo(new(big.Float).SetInt(numerator), new(big.Float).SetInt(denominator)).Float64()
+	return r
+}
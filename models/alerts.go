@@ -0,0 +1,127 @@
+package models
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"streamr_api/blockchain"
+	"streamr_api/config"
+)
+
+const (
+	AlertInfo     = "info"
+	AlertWarning  = "warning"
+	AlertCritical = "critical"
+)
+
+type AlertConfig struct {
+	WebhookURL string
+}
+
+// Alert is a notable event raised by one of the background services, e.g. an automatic withdrawal.
+type Alert struct {
+	Timestamp time.Time `json:"timestamp"`
+	Operator  string    `json:"operator"`
+	Level     string    `json:"level" example:"warning"`
+	Source    string    `json:"source" example:"earnings-guard"`
+	Message   string    `json:"message"`
+}
+
+// Alerter logs alerts, keeps them in the store and posts them to a webhook if one is configured.
+type Alerter struct {
+	o      *Operator
+	store  *blockchain.Store
+	config atomic.Pointer[AlertConfig]
+	prefix string
+	client *http.Client
+
+	mu sync.Mutex
+}
+
+func NewAlertConfig(n config.NotificationsConfig) AlertConfig {
+	return AlertConfig{
+		WebhookURL: n.AlertWebhookURL,
+	}
+}
+
+func NewAlerter(o *Operator, store *blockchain.Store, config AlertConfig) *Alerter {
+	a := &Alerter{
+		o:      o,
+		store:  store,
+		prefix: fmt.Sprintf("alerts/%s/", strings.ToLower(o.ContractAddr.Hex())),
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+	a.config.Store(&config)
+	return a
+}
+
+func (a *Alerter) SetConfig(config AlertConfig) {
+	a.config.Store(&config)
+}
+
+// Alert raises an alert. Delivery to the webhook happens in the background and failures are only logged.
+func (a *Alerter) Alert(level string, source string, format string, args ...interface{}) {
+	alert := Alert{
+		Timestamp: time.Now().UTC(),
+		Operator:  a.o.ContractAddr.Hex(),
+		Level:     level,
+		Source:    source,
+		Message:   fmt.Sprintf(format, args...),
+	}
+	log.Printf("[%s] %s: %s", alert.Level, alert.Source, alert.Message)
+
+	a.mu.Lock()
+	err := a.store.Put(fmt.Sprintf("%s%020d", a.prefix, alert.Timestamp.UnixNano()), alert)
+	a.mu.Unlock()
+	if err != nil {
+		log.Printf("Failed to store alert: %v", err)
+	}
+
+	if a.config.Load().WebhookURL != "" {
+		go a.post(alert)
+	}
+}
+
+func (a *Alerter) post(alert Alert) {
+	body, err := json.Marshal(alert)
+	if err != nil {
+		log.Printf("Failed to encode alert: %v", err)
+		return
+	}
+
+	resp, err := a.client.Post(a.config.Load().WebhookURL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		log.Printf("Failed to post alert to webhook: %v", err)
+		return
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 400 {
+		log.Printf("Alert webhook responded with status %d", resp.StatusCode)
+	}
+}
+
+// Alerts returns the alerts raised in [from, to], oldest first. Zero times mean no bound.
+func (a *Alerter) Alerts(from time.Time, to time.Time) ([]Alert, error) {
+	alerts := []Alert{}
+	err := a.store.ForEach(a.prefix, func(key string, value []byte) error {
+		var alert Alert
+		if err := json.Unmarshal(value, &alert); err != nil {
+			return err
+		}
+		if inRange(alert.Timestamp, from, to) {
+			alerts = append(alerts, alert)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return alerts, nil
+}
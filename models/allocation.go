@@ -0,0 +1,219 @@
+package models
+
+import (
+	"fmt"
+	"math/big"
+	"sort"
+	"time"
+
+	"streamr_api/common"
+
+	ethcommon "github.com/ethereum/go-ethereum/common"
+)
+
+// Policies for choosing which sponsorships to take stake out of.
+const (
+	PolicyProRata     = "prorata"      // in proportion to the stake in each sponsorship
+	PolicyLowestYield = "lowest-yield" // from the sponsorships with the lowest yield first
+	PolicyLargest     = "largest"      // from the sponsorships with the largest stake first
+)
+
+// Allocations for distributing new stake among the sponsorships.
+const (
+	AllocationProRata  = "prorata"  // in proportion to the current stake
+	AllocationEqual    = "equal"    // the same amount to every sponsorship
+	AllocationEarnings = "earnings" // back into the sponsorships the earnings came from
+)
+
+// StakeReduction is a planned reduceStakeTo of a sponsorship.
+type StakeReduction struct {
+	Sponsorship ethcommon.Address `json:"sponsorship"`
+	Stake       *common.Amount    `json:"stake"`
+	Reduce      *common.Amount    `json:"reduce"`
+	Target      *common.Amount    `json:"target"`
+	Yield       float64           `json:"yield"` // annualized accrued yield over the last 30 days, if known
+}
+
+// StakeAddition is a planned stake of additional DATA into a sponsorship.
+type StakeAddition struct {
+	Sponsorship ethcommon.Address `json:"sponsorship"`
+	Amount      *common.Amount    `json:"amount"`
+}
+
+// stakePosition is the stake of the operator in a sponsorship.
+type stakePosition struct {
+	sponsorship ethcommon.Address
+	stake       *big.Int
+	yield       float64
+}
+
+func ValidPolicy(policy string) error {
+	switch policy {
+	case PolicyProRata, PolicyLowestYield, PolicyLargest:
+		return nil
+	}
+	return fmt.Errorf("invalid policy %q, expected %s, %s or %s", policy, PolicyProRata, PolicyLowestYield, PolicyLargest)
+}
+
+func ValidAllocation(allocation string) error {
+	switch allocation {
+	case AllocationProRata, AllocationEqual, AllocationEarnings:
+		return nil
+	}
+	return fmt.Errorf("invalid allocation %q, expected %s, %s or %s", allocation, AllocationProRata, AllocationEqual, AllocationEarnings)
+}
+
+// splitByWeight splits total in proportion to weights. The rounding remainder goes to the largest
+// weight so the shares always add up to total. If all weights are zero nothing is split.
+func splitByWeight(total *big.Int, weights []*big.Int) []*big.Int {
+	shares := make([]*big.Int, len(weights))
+	sum := big.NewInt(0)
+	largest := -1
+	for i, weight := range weights {
+		shares[i] = big.NewInt(0)
+		sum.Add(sum, weight)
+		if weight.Sign() > 0 && (largest < 0 || weight.Cmp(weights[largest]) > 0) {
+			largest = i
+		}
+	}
+	if sum.Sign() == 0 {
+		return shares
+	}
+
+	assigned := big.NewInt(0)
+	for i, weight := range weights {
+		shares[i].Mul(total, weight)
+		shares[i].Div(shares[i], sum)
+		assigned.Add(assigned, shares[i])
+	}
+	shares[largest].Add(shares[largest], new(big.Int).Sub(total, assigned))
+	return shares
+}
+
+// stakePositions returns the current stake in every sponsorship along with its recent yield.
+func (o *Operator) stakePositions() ([]stakePosition, error) {
+	deployed, err := o.GetDeployedStake()
+	if err != nil {
+		return nil, err
+	}
+
+	yields := make(map[ethcommon.Address]float64)
+	if o.Accounting != nil {
+		now := time.Now().UTC()
+		if recent, err := o.Accounting.Yield(now.AddDate(0, 0, -30), now); err == nil {
+			for _, y := range recent {
+				yields[y.Sponsorship] = y.AccruedYield
+			}
+		}
+	}
+
+	positions := []stakePosition{}
+	for addr, stake := range deployed.DeployedBySponsorship {
+		positions = append(positions, stakePosition{sponsorship: addr, stake: stake.Int(), yield: yields[addr]})
+	}
+	// map order is random, keep plans reproducible
+	sort.Slice(positions, func(i, j int) bool {
+		return positions[i].sponsorship.Hex() < positions[j].sponsorship.Hex()
+	})
+	return positions, nil
+}
+
+// planReductions takes needed out of the positions according to the policy. Each sponsorship keeps
+// at least minStake, as reducing below it would mean leaving the sponsorship. It returns the
+// reductions and the part of needed that could not be freed.
+func planReductions(positions []stakePosition, minStake *big.Int, needed *big.Int, policy string) ([]StakeReduction, *big.Int) {
+	reducible := make([]*big.Int, len(positions))
+	for i, p := range positions {
+		reducible[i] = new(big.Int).Sub(p.stake, minStake)
+		if reducible[i].Sign() < 0 {
+			reducible[i].SetInt64(0)
+		}
+	}
+
+	order := make([]int, len(positions))
+	for i := range order {
+		order[i] = i
+	}
+	switch policy {
+	case PolicyLowestYield:
+		sort.SliceStable(order, func(a, b int) bool {
+			return positions[order[a]].yield < positions[order[b]].yield
+		})
+	case PolicyLargest:
+		sort.SliceStable(order, func(a, b int) bool {
+			return positions[order[a]].stake.Cmp(positions[order[b]].stake) > 0
+		})
+	}
+
+	reduce := make([]*big.Int, len(positions))
+	for i := range reduce {
+		reduce[i] = big.NewInt(0)
+	}
+	remaining := new(big.Int).Set(needed)
+
+	if policy == PolicyProRata {
+		// split the remainder by stake among the sponsorships that still have room, until it is
+		// taken or every sponsorship is down to the minimum
+		for remaining.Sign() > 0 {
+			weight := big.NewInt(0)
+			for i, p := range positions {
+				if reducible[i].Sign() > 0 {
+					weight.Add(weight, p.stake)
+				}
+			}
+			if weight.Sign() == 0 {
+				break
+			}
+
+			taken := big.NewInt(0)
+			for i, p := range positions {
+				if reducible[i].Sign() == 0 {
+					continue
+				}
+				share := new(big.Int).Mul(remaining, p.stake)
+				share.Div(share, weight)
+				if share.Cmp(reducible[i]) > 0 {
+					share.Set(reducible[i])
+				}
+				reduce[i].Add(reduce[i], share)
+				reducible[i].Sub(reducible[i], share)
+				taken.Add(taken, share)
+			}
+			remaining.Sub(remaining, taken)
+
+			// rounding leaves a few wei, which the ordered pass below picks up
+			if taken.Sign() == 0 {
+				break
+			}
+		}
+	}
+
+	for _, i := range order {
+		if remaining.Sign() == 0 {
+			break
+		}
+		share := new(big.Int).Set(remaining)
+		if share.Cmp(reducible[i]) > 0 {
+			share.Set(reducible[i])
+		}
+		reduce[i].Add(reduce[i], share)
+		reducible[i].Sub(reducible[i], share)
+		remaining.Sub(remaining, share)
+	}
+
+	reductions := []StakeReduction{}
+	for _, i := range order {
+		if reduce[i].Sign() == 0 {
+			continue
+		}
+		p := positions[i]
+		reductions = append(reductions, StakeReduction{
+			Sponsorship: p.sponsorship,
+			Stake:       common.NewAmount(p.stake),
+			Reduce:      common.NewAmount(reduce[i]),
+			Target:      common.NewAmount(new(big.Int).Sub(p.stake, reduce[i])),
+			Yield:       p.yield,
+		})
+	}
+	return reductions, remaining
+}
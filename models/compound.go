@@ -0,0 +1,159 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"time"
+
+	"streamr_api/common"
+
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// CompoundResult shows what a compounding run withdrew, kept back for the undelegation queue and
+// restaked.
+type CompoundResult struct {
+	Allocation   string          `json:"allocation"`
+	Withdrawal   string          `json:"withdrawal,omitempty"` // tx hash, empty if there were no earnings
+	Balance      *common.Amount  `json:"balance"`              // DATA held by the operator after the withdrawal
+	Reserved     *common.Amount  `json:"reserved"`             // DATA kept back for the undelegation queue
+	Restaked     *common.Amount  `json:"restaked"`
+	Stakes       []StakeAddition `json:"stakes"`
+	Transactions []string        `json:"transactions"`
+}
+
+// WithdrawEarningsAndCompound withdraws the earnings of every sponsorship and restakes the DATA the
+// operator then holds, minus what the undelegation queue needs, according to the allocation.
+func (o *Operator) WithdrawEarningsAndCompound(allocation string) (CompoundResult, error) {
+	result := CompoundResult{Allocation: allocation, Stakes: []StakeAddition{}, Transactions: []string{}}
+	if err := ValidAllocation(allocation); err != nil {
+		return result, err
+	}
+
+	sponsors, err := o.GetSponsorshipsAndEarnings()
+	if err != nil {
+		log.Printf("Failed to get sponsorships and earnings: %v", err)
+		return result, err
+	}
+	withdrawn := make(map[ethcommon.Address]*big.Int)
+	withdrawFrom := []ethcommon.Address{}
+	for i, addr := range sponsors.Addresses {
+		if sponsors.Earnings[i].Int().Sign() > 0 {
+			withdrawFrom = append(withdrawFrom, addr)
+			withdrawn[addr] = new(big.Int).Set(sponsors.Earnings[i].Int())
+		}
+	}
+
+	if len(withdrawFrom) > 0 {
+		tx, err := o.WithdrawEarningsFrom(withdrawFrom)
+		if err != nil {
+			return result, err
+		}
+		result.Withdrawal = tx
+		result.Transactions = append(result.Transactions, tx)
+		if err := o.waitMined(tx, 2*time.Minute); err != nil {
+			return result, err
+		}
+	}
+
+	// the withdrawal pays out the queue as far as it can, so whatever is still queued needs to stay
+	balance, err := o.GetDataBalance()
+	if err != nil {
+		return result, err
+	}
+	reserved, _, err := o.pendingUndelegations()
+	if err != nil {
+		return result, err
+	}
+	result.Balance = common.NewAmount(balance)
+	result.Reserved = common.NewAmount(reserved)
+
+	available := new(big.Int).Sub(balance, reserved)
+	if available.Sign() <= 0 {
+		result.Restaked = common.NewAmount(nil)
+		return result, nil
+	}
+
+	stakes, stakeTxs, restaked, err := o.distributeStake(available, allocation, withdrawn, nil)
+	result.Stakes = stakes
+	result.Transactions = append(result.Transactions, stakeTxs...)
+	result.Restaked = common.NewAmount(restaked)
+	if err != nil {
+		return result, err
+	}
+
+	for _, tx := range stakeTxs {
+		if err := o.waitMined(tx, 2*time.Minute); err != nil {
+			return result, err
+		}
+	}
+	return result, nil
+}
+
+// distributeStake stakes amount into the sponsorships the operator is in according to the
+// allocation, leaving out the sponsorships in skip. For the earnings allocation, earnings holds the
+// weight of each sponsorship. It returns the stakes and transactions sent so far and how much was
+// staked, also on error.
+func (o *Operator) distributeStake(amount *big.Int, allocation string, earnings map[ethcommon.Address]*big.Int, skip map[ethcommon.Address]bool) ([]StakeAddition, []string, *big.Int, error) {
+	stakes := []StakeAddition{}
+	txs := []string{}
+	staked := big.NewInt(0)
+
+	positions, err := o.stakePositions()
+	if err != nil {
+		return stakes, txs, staked, err
+	}
+	weights := make([]*big.Int, len(positions))
+	for i, p := range positions {
+		if skip[p.sponsorship] {
+			weights[i] = big.NewInt(0)
+			continue
+		}
+		switch allocation {
+		case AllocationProRata:
+			weights[i] = p.stake
+		case AllocationEqual:
+			weights[i] = big.NewInt(1)
+		case AllocationEarnings:
+			weights[i] = big.NewInt(0)
+			if weight, ok := earnings[p.sponsorship]; ok {
+				weights[i] = weight
+			}
+		}
+	}
+
+	for i, share := range splitByWeight(amount, weights) {
+		if share.Sign() == 0 {
+			continue
+		}
+		tx, err := o.Stake(positions[i].sponsorship, share)
+		if err != nil {
+			return stakes, txs, staked, err
+		}
+		staked.Add(staked, share)
+		stakes = append(stakes, StakeAddition{Sponsorship: positions[i].sponsorship, Amount: common.NewAmount(share)})
+		txs = append(txs, tx)
+	}
+	return stakes, txs, staked, nil
+}
+
+// ErrTxReverted is returned by waitMined for transactions that were mined but reverted.
+var ErrTxReverted = errors.New("reverted")
+
+// waitMined waits for a transaction to be mined and fails if it reverted.
+func (o *Operator) waitMined(txHash string, timeout time.Duration) error {
+	if _, err := o.TxManager.PolygonWaitForTx(txHash, timeout); err != nil {
+		return fmt.Errorf("transaction %s: %w", txHash, err)
+	}
+	receipt, err := o.TxManager.Receipt(txHash)
+	if err != nil {
+		return fmt.Errorf("transaction %s: %w", txHash, err)
+	}
+	if receipt.Status == types.ReceiptStatusFailed {
+		return fmt.Errorf("transaction %s: %w", txHash, ErrTxReverted)
+	}
+	return nil
+}
@@ -1,12 +1,17 @@
 package models
 
 import (
+	"context"
 	"encoding/json"
+	"fmt"
 	"log"
 	"net/http"
 	"os"
 	"streamr_api/common"
+	"streamr_api/config"
+	"streamr_api/metrics"
 	"sync"
+	"time"
 
 	"github.com/robfig/cron/v3"
 	"github.com/syndtr/goleveldb/leveldb/errors"
@@ -17,6 +22,13 @@ type Scheduler struct {
 	Jobs        map[string]*CronJob `json:"jobs"`
 	Cron        *cron.Cron          `json:"-"`
 	cronJobFile string
+	baseURL     string
+	apiKey      string
+	client      *http.Client
+
+	runsMu  sync.Mutex
+	runs    map[string]*CronJobRun // by job name
+	running bool
 }
 
 type CronJob struct {
@@ -28,13 +40,28 @@ type CronJob struct {
 	EntryID  cron.EntryID `json:"-"`
 }
 
-func NewScheduler() *Scheduler {
+// CronJobRun is the outcome of the last runs of a job since the service started.
+type CronJobRun struct {
+	Name        string     `json:"name"`
+	LastSuccess *time.Time `json:"lastSuccess,omitempty"`
+	LastFailure *time.Time `json:"lastFailure,omitempty"`
+	LastError   string     `json:"lastError,omitempty"`
+}
+
+func NewScheduler(c *config.Config) *Scheduler {
 	cron.WithSeconds()
 	scheduler := Scheduler{
 		mu:          sync.Mutex{},
 		Jobs:        make(map[string]*CronJob),
 		Cron:        cron.New(cron.WithSeconds(), cron.WithChain(cron.Recover(cron.DefaultLogger))),
-		cronJobFile: common.GetStringEnvWithDefault("CRON_JOB_FILE", "cron_jobs.json"),
+		cronJobFile: c.Scheduler.JobsFile,
+		baseURL:     c.SchedulerBaseURL(),
+		client:      &http.Client{},
+		runs:        make(map[string]*CronJobRun),
+	}
+	if len(c.Auth.APIKeys) > 0 {
+		// jobs call the API like any other client
+		scheduler.apiKey = c.Auth.APIKeys[0]
 	}
 	// Load existing jobs
 	err := scheduler.LoadCronJobs()
@@ -43,10 +70,67 @@ func NewScheduler() *Scheduler {
 	}
 
 	scheduler.Cron.Start()
+	scheduler.running = true
 
 	return &scheduler
 }
 
+// Stop stops scheduling jobs and waits until the running ones are done or ctx is done.
+func (s *Scheduler) Stop(ctx context.Context) error {
+	s.runsMu.Lock()
+	s.running = false
+	s.runsMu.Unlock()
+
+	select {
+	case <-s.Cron.Stop().Done():
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Running reports whether the cron scheduler runs the jobs.
+func (s *Scheduler) Running() bool {
+	s.runsMu.Lock()
+	defer s.runsMu.Unlock()
+	return s.running
+}
+
+// LastRuns returns the outcome of the last runs of every job, by job ID.
+func (s *Scheduler) LastRuns() map[string]CronJobRun {
+	jobs := s.GetCronJobsCopy()
+	s.runsMu.Lock()
+	defer s.runsMu.Unlock()
+
+	runs := make(map[string]CronJobRun, len(jobs))
+	for id, job := range jobs {
+		run := CronJobRun{Name: job.Name}
+		if last, ok := s.runs[job.Name]; ok {
+			run = *last
+		}
+		runs[id] = run
+	}
+	return runs
+}
+
+func (s *Scheduler) recordRun(name string, err error) {
+	s.runsMu.Lock()
+	defer s.runsMu.Unlock()
+
+	run, ok := s.runs[name]
+	if !ok {
+		run = &CronJobRun{Name: name}
+		s.runs[name] = run
+	}
+	now := time.Now().UTC()
+	if err != nil {
+		run.LastFailure = &now
+		run.LastError = err.Error()
+		return
+	}
+	run.LastSuccess = &now
+}
+
 func (s *Scheduler) LoadCronJobs() error {
 	var jobs map[string]*CronJob
 
@@ -148,17 +232,46 @@ func (s *Scheduler) GetCronJob(id string) *CronJob {
 
 func (s *Scheduler) ScheduleJob(job *CronJob) error {
 	entryID, err := s.Cron.AddFunc(job.Schedule, func() {
+		start := time.Now()
+		metrics.CronRuns.WithLabelValues(job.Name).Inc()
+		defer func() {
+			metrics.CronDuration.WithLabelValues(job.Name).Observe(time.Since(start).Seconds())
+		}()
+
+		var resp *http.Response
 		var err error
 		switch job.Method {
-		case "GET":
-			_, err = http.Get("http://localhost:8080" + job.Endpoint)
-		case "POST":
-			_, err = http.Post("http://localhost:8080"+job.Endpoint, "application/json", nil)
+		case "GET", "POST":
+			var req *http.Request
+			req, err = http.NewRequest(job.Method, s.baseURL+job.Endpoint, nil)
+			if err != nil {
+				break
+			}
+			if job.Method == "POST" {
+				req.Header.Set("Content-Type", "application/json")
+			}
+			if s.apiKey != "" {
+				req.Header.Set(config.APIKeyHeader, s.apiKey)
+			}
+			resp, err = s.client.Do(req)
 		}
 		if err != nil {
+			metrics.CronFailures.WithLabelValues(job.Name).Inc()
 			log.Printf("cron failed to make request to %s: %v", job.Endpoint, err)
+			s.recordRun(job.Name, err)
+			return
+		}
+		if resp == nil {
+			return
+		}
+		resp.Body.Close()
+		if resp.StatusCode >= 400 {
+			metrics.CronFailures.WithLabelValues(job.Name).Inc()
+			log.Printf("cron request to %s failed with status %d", job.Endpoint, resp.StatusCode)
+			s.recordRun(job.Name, fmt.Errorf("status %d", resp.StatusCode))
 			return
 		}
+		s.recordRun(job.Name, nil)
 	})
 	if err != nil {
 		return err
@@ -0,0 +1,297 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"math/big"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"streamr_api/blockchain"
+	"streamr_api/common"
+	"streamr_api/config"
+)
+
+const cutAlertSource = "operator-cut"
+
+// ErrCutChangeLimit is returned when a cut change would go over the maximum change per period.
+var ErrCutChangeLimit = errors.New("operator cut change is over the limit for the period")
+
+// ErrStakedInSponsorships is returned for cut changes while stake is deployed, which the operator
+// contract reverts.
+var ErrStakedInSponsorships = errors.New("the operator cut can only change while nothing is staked in sponsorships, unstake first")
+
+type CutConfig struct {
+	MaxChangePercent float64       // percentage points the cut may move in total within Period, 0 for no limit
+	Period           time.Duration // window the changes are summed over
+}
+
+// FeeParameters shows the operator cut and the protocol parameters that decide what delegators earn.
+// Fractions are e.g. 0.1 for 10%.
+type FeeParameters struct {
+	OperatorsCut          float64        `json:"operatorsCut"`          // share of the earnings after the protocol fee the owner takes
+	ProtocolFee           float64        `json:"protocolFee"`           // share of the earnings the protocol takes on withdrawal
+	MinimumSelfDelegation float64        `json:"minimumSelfDelegation"` // share of operator tokens the owner must hold
+	MinimumStake          *common.Amount `json:"minimumStake"`          // per sponsorship
+	MaxChangePercent      float64        `json:"maxChangePercent"`      // 0 means no limit
+	PeriodDays            float64        `json:"periodDays"`
+	ChangedInPeriod       float64        `json:"changedInPeriod"` // percentage points changed in the current period
+}
+
+// CutChange is a change of the operator cut made through the API.
+type CutChange struct {
+	From      float64   `json:"from"`
+	To        float64   `json:"to"`
+	TxHash    string    `json:"txHash"`
+	ChangedAt time.Time `json:"changedAt"`
+}
+
+// CutChangePreview shows what a new operator cut means for delegators at the current earnings rate:
+// the earnings of the staked sponsorships for a year, after the protocol fee, split between the
+// owner and the delegators. Delegator yield is over the operator value.
+type CutChangePreview struct {
+	Current               float64        `json:"current"`
+	Proposed              float64        `json:"proposed"`
+	ChangedInPeriod       float64        `json:"changedInPeriod"` // percentage points, before this change
+	MaxChangePercent      float64        `json:"maxChangePercent"`
+	WithinLimit           bool           `json:"withinLimit"`
+	YearlyEarnings        *common.Amount `json:"yearlyEarnings"`
+	OperatorValue         *common.Amount `json:"operatorValue"`
+	CurrentOwnerEarnings  *common.Amount `json:"currentOwnerEarnings"`
+	ProposedOwnerEarnings *common.Amount `json:"proposedOwnerEarnings"`
+	CurrentDelegatorAPY   float64        `json:"currentDelegatorApy"`
+	ProposedDelegatorAPY  float64        `json:"proposedDelegatorApy"`
+	Warnings              []string       `json:"warnings"`
+	TxHash                string         `json:"txHash,omitempty"`
+
+	current, proposed *big.Int // the fractions, to compare exactly
+	staked            bool     // stake is deployed, so the contract refuses the change
+}
+
+// CutManager changes the operator cut, keeping the changes within the configured limit.
+type CutManager struct {
+	o      *Operator
+	store  *blockchain.Store
+	alerts *Alerter
+	config atomic.Pointer[CutConfig]
+	prefix string
+
+	changing sync.Mutex // one change at a time, so two can't both pass the limit
+}
+
+func NewCutConfig(p config.CutPolicy) CutConfig {
+	return CutConfig{
+		MaxChangePercent: p.MaxChangePercent,
+		Period:           time.Duration(p.ChangePeriodDays) * 24 * time.Hour,
+	}
+}
+
+func NewCutManager(o *Operator, store *blockchain.Store, alerts *Alerter, config CutConfig) *CutManager {
+	m := &CutManager{
+		o:      o,
+		store:  store,
+		alerts: alerts,
+		prefix: fmt.Sprintf("cut/%s/", strings.ToLower(o.ContractAddr.Hex())),
+	}
+	m.config.Store(&config)
+	return m
+}
+
+func (m *CutManager) SetConfig(config CutConfig) {
+	m.config.Store(&config)
+}
+
+// Fees reads the operator cut and the protocol fee parameters.
+func (m *CutManager) Fees() (FeeParameters, error) {
+	fees := FeeParameters{MaxChangePercent: m.config.Load().MaxChangePercent, PeriodDays: m.config.Load().Period.Hours() / 24}
+
+	cut, err := m.o.GetOperatorsCutFraction()
+	if err != nil {
+		return fees, err
+	}
+	protocolFee, err := m.o.GetProtocolFeeFraction()
+	if err != nil {
+		return fees, err
+	}
+	minSelfDelegation, err := m.o.GetMinimumSelfDelegationFraction()
+	if err != nil {
+		return fees, err
+	}
+	minStake, err := m.o.GetMinimumStake()
+	if err != nil {
+		return fees, err
+	}
+	changed, err := m.changedInPeriod()
+	if err != nil {
+		return fees, err
+	}
+
+	fees.OperatorsCut = ratio(cut, fractionOne)
+	fees.ProtocolFee = ratio(protocolFee, fractionOne)
+	fees.MinimumSelfDelegation = ratio(minSelfDelegation, fractionOne)
+	fees.MinimumStake = common.NewAmount(minStake)
+	fees.ChangedInPeriod = changed
+	return fees, nil
+}
+
+// Preview works out the impact of changing the cut to proposed (a 1e18 fraction) without sending anything.
+func (m *CutManager) Preview(proposed *big.Int) (CutChangePreview, error) {
+	preview := CutChangePreview{Proposed: ratio(proposed, fractionOne), MaxChangePercent: m.config.Load().MaxChangePercent, Warnings: []string{}, proposed: proposed}
+	if proposed.Sign() < 0 || proposed.Cmp(fractionOne) > 0 {
+		return preview, fmt.Errorf("invalid operator cut %.4f%%, must be between 0%% and 100%%", preview.Proposed*100)
+	}
+
+	current, err := m.o.GetOperatorsCutFraction()
+	if err != nil {
+		return preview, err
+	}
+	preview.Current = ratio(current, fractionOne)
+	preview.current = current
+
+	changed, err := m.changedInPeriod()
+	if err != nil {
+		return preview, err
+	}
+	preview.ChangedInPeriod = changed
+	change := math.Abs(preview.Proposed-preview.Current) * 100
+	preview.WithinLimit = m.config.Load().MaxChangePercent <= 0 || changed+change <= m.config.Load().MaxChangePercent+1e-9
+
+	if m.o.Discovery == nil {
+		return preview, errors.New("sponsorship discovery is not running")
+	}
+	staked, err := m.o.Discovery.Staked()
+	if err != nil {
+		return preview, err
+	}
+	value, err := m.o.GetValueWithoutEarnings()
+	if err != nil {
+		return preview, err
+	}
+
+	// every staked sponsorship pays out in proportion to stake, so its current yield applies to our stake
+	yearly := big.NewInt(0)
+	for _, s := range staked {
+		if s.OwnStake == nil || !s.IsRunning {
+			continue
+		}
+		earned, _ := new(big.Float).Mul(new(big.Float).SetInt(s.OwnStake.Int()), big.NewFloat(s.CurrentAPY)).Int(nil)
+		yearly.Add(yearly, earned)
+	}
+	preview.YearlyEarnings = common.NewAmount(yearly)
+	preview.OperatorValue = common.NewAmount(value)
+	preview.CurrentOwnerEarnings = common.NewAmount(applyFraction(yearly, current))
+	preview.ProposedOwnerEarnings = common.NewAmount(applyFraction(yearly, proposed))
+	if value.Sign() > 0 {
+		preview.CurrentDelegatorAPY = ratio(new(big.Int).Sub(yearly, preview.CurrentOwnerEarnings.Int()), value)
+		preview.ProposedDelegatorAPY = ratio(new(big.Int).Sub(yearly, preview.ProposedOwnerEarnings.Int()), value)
+	}
+
+	if !preview.WithinLimit {
+		preview.Warnings = append(preview.Warnings, fmt.Sprintf("changing the cut by %.2f percentage points would make %.2f in the last %.0f days, the limit is %.2f",
+			change, changed+change, m.config.Load().Period.Hours()/24, m.config.Load().MaxChangePercent))
+	}
+	preview.staked = len(staked) > 0
+	if preview.staked {
+		preview.Warnings = append(preview.Warnings, "the operator contract only accepts a new cut while nothing is staked in sponsorships, unstake first or the transaction reverts")
+	}
+	if preview.Proposed > preview.Current {
+		preview.Warnings = append(preview.Warnings, fmt.Sprintf("delegators' projected yield drops from %.2f%% to %.2f%%",
+			preview.CurrentDelegatorAPY*100, preview.ProposedDelegatorAPY*100))
+	}
+	return preview, nil
+}
+
+// Change sets the operator cut to proposed (a 1e18 fraction) and waits for it to be mined. The
+// configured key must be the owner's, the change must be within the limit for the period and nothing
+// may be staked in sponsorships.
+func (m *CutManager) Change(proposed *big.Int) (CutChangePreview, error) {
+	if err := m.o.requireOwnerSigner(); err != nil {
+		return CutChangePreview{}, err
+	}
+	m.changing.Lock()
+	defer m.changing.Unlock()
+
+	preview, err := m.Preview(proposed)
+	if err != nil {
+		return preview, err
+	}
+	if !preview.WithinLimit {
+		return preview, ErrCutChangeLimit
+	}
+	if preview.current.Cmp(preview.proposed) == 0 {
+		return preview, ErrNoChange
+	}
+	if preview.staked {
+		return preview, ErrStakedInSponsorships
+	}
+
+	tx, err := m.o.TxManager.ContractSendTx("updateOperatorsCutFraction", []interface{}{proposed})
+	if err != nil {
+		return preview, err
+	}
+	preview.TxHash = tx
+	if err := m.o.waitMined(tx, 2*time.Minute); err != nil {
+		return preview, err
+	}
+
+	record := CutChange{From: preview.Current, To: preview.Proposed, TxHash: tx, ChangedAt: time.Now().UTC()}
+	if err := m.store.Put(fmt.Sprintf("%s%020d", m.prefix, record.ChangedAt.UnixNano()), record); err != nil {
+		// the cut did change on chain, only the limit misses this change
+		log.Printf("Failed to record operator cut change %s: %v", tx, err)
+	}
+	m.alerts.Alert(AlertInfo, cutAlertSource, "operator cut changed from %.2f%% to %.2f%% in %s", preview.Current*100, preview.Proposed*100, tx)
+	return preview, nil
+}
+
+// Changes returns the cut changes made through the API in [from, to], oldest first. Zero times mean no bound.
+func (m *CutManager) Changes(from time.Time, to time.Time) ([]CutChange, error) {
+	changes := []CutChange{}
+	err := m.store.ForEach(m.prefix, func(key string, value []byte) error {
+		var change CutChange
+		if err := json.Unmarshal(value, &change); err != nil {
+			return err
+		}
+		if inRange(change.ChangedAt, from, to) {
+			changes = append(changes, change)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return changes, nil
+}
+
+// changedInPeriod sums the percentage points the cut moved within the period.
+func (m *CutManager) changedInPeriod() (float64, error) {
+	changes, err := m.Changes(time.Now().Add(-m.config.Load().Period), time.Time{})
+	if err != nil {
+		return 0, err
+	}
+	total := 0.0
+	for _, change := range changes {
+		total += math.Abs(change.To-change.From) * 100
+	}
+	return total, nil
+}
+
+// ParseCutPercent parses a cut in percent, e.g. "12.5", to a 1e18 fraction.
+func ParseCutPercent(s string) (*big.Int, error) {
+	percent, ok := new(big.Rat).SetString(strings.TrimSuffix(strings.TrimSpace(s), "%"))
+	if !ok {
+		return nil, fmt.Errorf("invalid percentage %q", s)
+	}
+	fraction := new(big.Rat).Mul(percent, new(big.Rat).SetInt(new(big.Int).Div(fractionOne, big.NewInt(100))))
+	if !fraction.IsInt() {
+		return nil, fmt.Errorf("percentage %q has too many decimals", s)
+	}
+	if fraction.Sign() < 0 || fraction.Num().Cmp(fractionOne) > 0 {
+		return nil, fmt.Errorf("invalid percentage %q, must be between 0 and 100", s)
+	}
+	return fraction.Num(), nil
+}
@@ -0,0 +1,245 @@
+package models
+
+import (
+	"fmt"
+	"math/big"
+	"time"
+
+	"streamr_api/common"
+
+	ethcommon "github.com/ethereum/go-ethereum/common"
+)
+
+// Ways of delegating DATA from the owner wallet.
+const (
+	DelegateTransferAndCall = "transferAndCall" // one DATA transferAndCall to the operator
+	DelegateApprove         = "approve"         // approve the operator, then delegate
+)
+
+// SelfDelegation shows the owner's delegation. Operator tokens have 18 decimals like DATA, so they
+// are shown in the same format.
+type SelfDelegation struct {
+	Owner           ethcommon.Address `json:"owner"`
+	OperatorTokens  *common.Amount    `json:"operatorTokens"`
+	Value           *common.Amount    `json:"value"` // DATA value of the operator tokens
+	TotalSupply     *common.Amount    `json:"totalSupply"`
+	Fraction        float64           `json:"fraction"`        // owner's share of the operator tokens
+	MinimumFraction float64           `json:"minimumFraction"` // minimumSelfDelegationFraction of the protocol
+	WalletBalance   *common.Amount    `json:"walletBalance"`   // DATA in the owner wallet
+	Queued          *common.Amount    `json:"queued"`          // DATA of the owner's undelegations waiting in the queue
+	MaxUndelegation *common.Amount    `json:"maxUndelegation"` // DATA the owner can undelegate and stay above the minimum
+}
+
+// DelegationResult shows a delegation or undelegation by the owner and the transactions it took.
+type DelegationResult struct {
+	Method       string         `json:"method"`
+	Amount       *common.Amount `json:"amount"`
+	Before       SelfDelegation `json:"before"`
+	Transactions []string       `json:"transactions"`
+}
+
+func ValidDelegateMethod(method string) error {
+	switch method {
+	case DelegateTransferAndCall, DelegateApprove:
+		return nil
+	}
+	return fmt.Errorf("invalid method %q, expected %s or %s", method, DelegateTransferAndCall, DelegateApprove)
+}
+
+// GetSelfDelegation reads the owner's delegation and how much of it can be undelegated. The
+// owner's undelegations already in the queue will be paid out too, so they count against the limit.
+func (o *Operator) GetSelfDelegation() (SelfDelegation, error) {
+	d := SelfDelegation{Owner: o.OwnerAddr}
+
+	tokens, err := o.operatorUint("balanceOf", o.OwnerAddr)
+	if err != nil {
+		return d, err
+	}
+	supply, err := o.operatorUint("totalSupply")
+	if err != nil {
+		return d, err
+	}
+	value, err := o.GetDelegatorBalance(o.OwnerAddr)
+	if err != nil {
+		return d, err
+	}
+	minFraction, err := o.GetMinimumSelfDelegationFraction()
+	if err != nil {
+		return d, err
+	}
+	wallet, err := o.GetWalletDataBalance(o.OwnerAddr)
+	if err != nil {
+		return d, err
+	}
+	pending, err := o.pendingByDelegator()
+	if err != nil {
+		return d, err
+	}
+	queued := big.NewInt(0)
+	for _, entry := range pending[o.OwnerAddr] {
+		queued.Add(queued, entry.Amount.Int())
+	}
+
+	d.OperatorTokens = common.NewAmount(tokens)
+	d.Value = common.NewAmount(value)
+	d.TotalSupply = common.NewAmount(supply)
+	d.MinimumFraction = ratio(minFraction, fractionOne)
+	d.WalletBalance = common.NewAmount(wallet)
+	d.Queued = common.NewAmount(queued)
+	if supply.Sign() > 0 {
+		d.Fraction = ratio(tokens, supply)
+	}
+
+	maxData := big.NewInt(0)
+	if removable := maxUndelegatableTokens(tokens, supply, minFraction); removable.Sign() > 0 && tokens.Sign() > 0 {
+		maxData.Mul(removable, value)
+		maxData.Div(maxData, tokens)
+	}
+	maxData.Sub(maxData, queued)
+	if maxData.Sign() < 0 {
+		maxData.SetInt64(0)
+	}
+	d.MaxUndelegation = common.NewAmount(maxData)
+	return d, nil
+}
+
+// maxUndelegatableTokens returns how many operator tokens the owner can give up while keeping
+// (tokens - x) / (supply - x) >= minFraction, the check the operator contract makes, i.e.
+// x <= (tokens - minFraction * supply) / (1 - minFraction).
+func maxUndelegatableTokens(tokens *big.Int, supply *big.Int, minFraction *big.Int) *big.Int {
+	if minFraction.Sign() == 0 {
+		return new(big.Int).Set(tokens)
+	}
+	rest := new(big.Int).Sub(fractionOne, minFraction)
+	if rest.Sign() <= 0 {
+		return big.NewInt(0)
+	}
+	x := new(big.Int).Mul(tokens, fractionOne)
+	x.Sub(x, new(big.Int).Mul(minFraction, supply))
+	if x.Sign() <= 0 {
+		return big.NewInt(0)
+	}
+	x.Div(x, rest)
+	if x.Cmp(tokens) > 0 {
+		x.Set(tokens)
+	}
+	return x
+}
+
+// SelfDelegate delegates DATA from the owner wallet into the operator and waits for it to be mined.
+// The configured key must be the owner's.
+func (o *Operator) SelfDelegate(amount *big.Int, method string) (DelegationResult, error) {
+	result := DelegationResult{Method: method, Amount: common.NewAmount(amount), Transactions: []string{}}
+	if err := ValidDelegateMethod(method); err != nil {
+		return result, err
+	}
+	if err := o.requireOwnerSigner(); err != nil {
+		return result, err
+	}
+	if amount.Sign() <= 0 {
+		return result, fmt.Errorf("amount must be positive")
+	}
+
+	before, err := o.GetSelfDelegation()
+	if err != nil {
+		return result, err
+	}
+	result.Before = before
+	if amount.Cmp(before.WalletBalance.Int()) > 0 {
+		return result, fmt.Errorf("owner wallet holds %s DATA, less than %s DATA", before.WalletBalance.DATA(), result.Amount.DATA())
+	}
+
+	token, err := o.DataToken()
+	if err != nil {
+		return result, err
+	}
+	switch method {
+	case DelegateTransferAndCall:
+		// the operator's onTokenTransfer credits the sender as the delegator
+		tx, err := token.TransferAndCall(o.ContractAddr, amount, nil)
+		if err != nil {
+			return result, err
+		}
+		result.Transactions = append(result.Transactions, tx)
+		return result, o.waitMined(tx, 2*time.Minute)
+	default:
+		tx, err := token.Approve(o.ContractAddr, amount)
+		if err != nil {
+			return result, err
+		}
+		result.Transactions = append(result.Transactions, tx)
+		if err := o.waitMined(tx, 2*time.Minute); err != nil {
+			return result, err
+		}
+		tx, err = o.TxManager.ContractSendTx("delegate", []interface{}{amount})
+		if err != nil {
+			return result, err
+		}
+		result.Transactions = append(result.Transactions, tx)
+		return result, o.waitMined(tx, 2*time.Minute)
+	}
+}
+
+// SelfUndelegate queues an undelegation of DATA for the owner and waits for it to be mined. It
+// refuses amounts that would take the owner under the minimum self-delegation fraction, counting
+// the owner's undelegations already in the queue.
+func (o *Operator) SelfUndelegate(amount *big.Int) (DelegationResult, error) {
+	result := DelegationResult{Method: "undelegate", Amount: common.NewAmount(amount), Transactions: []string{}}
+	if err := o.requireOwnerSigner(); err != nil {
+		return result, err
+	}
+	if amount.Sign() <= 0 {
+		return result, fmt.Errorf("amount must be positive")
+	}
+	// held until the undelegation is mined and in the queue the next one is checked against
+	o.undelegating.Lock()
+	defer o.undelegating.Unlock()
+
+	before, err := o.GetSelfDelegation()
+	if err != nil {
+		return result, err
+	}
+	result.Before = before
+	if amount.Cmp(before.MaxUndelegation.Int()) > 0 {
+		return result, fmt.Errorf("undelegating %s DATA would take the owner under the minimum self-delegation of %.2f%%, at most %s DATA can be undelegated",
+			result.Amount.DATA(), before.MinimumFraction*100, before.MaxUndelegation.DATA())
+	}
+
+	tx, err := o.TxManager.ContractSendTx("undelegate", []interface{}{amount})
+	if err != nil {
+		return result, err
+	}
+	result.Transactions = append(result.Transactions, tx)
+	return result, o.waitMined(tx, 2*time.Minute)
+}
+
+// GetWalletDataBalance returns the DATA held by an account.
+func (o *Operator) GetWalletDataBalance(account ethcommon.Address) (*big.Int, error) {
+	token, err := o.DataToken()
+	if err != nil {
+		return nil, err
+	}
+	return token.BalanceOf(account)
+}
+
+// requireOwnerSigner fails unless transactions are signed by the owner wallet.
+func (o *Operator) requireOwnerSigner() error {
+	if signer := o.TxManager.SignerAddress(); signer != o.OwnerAddr {
+		return fmt.Errorf("transactions are signed by %s, not the owner %s", signer.Hex(), o.OwnerAddr.Hex())
+	}
+	return nil
+}
+
+// operatorUint calls a view method of the operator contract that returns a uint.
+func (o *Operator) operatorUint(method string, params ...interface{}) (*big.Int, error) {
+	result, err := o.TxManager.ContractCall(method, params)
+	if err != nil {
+		return nil, err
+	}
+
+	value, ok := result[0].(*big.Int)
+	if !ok {
+		return nil, fmt.Errorf("unexpected %s result: %v", method, result[0])
+	}
+	return value, nil
+}
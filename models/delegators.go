@@ -0,0 +1,357 @@
+package models
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"sort"
+	"time"
+
+	"streamr_api/blockchain"
+	"streamr_api/common"
+
+	ethcommon "github.com/ethereum/go-ethereum/common"
+)
+
+// Statement entry types.
+const (
+	StatementDelegation   = "delegation"
+	StatementUndelegation = "undelegation" // paid out from the queue
+	StatementTransferIn   = "transfer-in"  // operator tokens received from another account
+	StatementTransferOut  = "transfer-out" // operator tokens sent to another account
+)
+
+var ErrUnknownDelegator = errors.New("address has never delegated to the operator")
+
+var statementCSVHeader = []string{"Date", "Type", "Amount", "Currency", "Counterparty", "TxHash", "Block"}
+
+// PendingUndelegation is an entry of the undelegation queue.
+type PendingUndelegation struct {
+	Amount   *common.Amount `json:"amount"` // as requested, the payout is capped at the delegator's balance
+	QueuedAt time.Time      `json:"queuedAt"`
+}
+
+// DelegatorSummary is a delegator of the operator. Earnings are the DATA value now plus everything
+// paid out, minus everything delegated, so slashing shows up as negative earnings. Operator tokens
+// moved between accounts by transfer aren't priced, so earnings are only exact for delegators who
+// never transferred any.
+type DelegatorSummary struct {
+	Delegator       ethcommon.Address     `json:"delegator"`
+	OperatorTokens  *common.Amount        `json:"operatorTokens"`
+	Value           *common.Amount        `json:"value"` // DATA value of the operator tokens
+	Share           float64               `json:"share"` // of all operator tokens
+	FirstDelegation *time.Time            `json:"firstDelegation,omitempty"`
+	Delegated       *common.Amount        `json:"delegated"`
+	Undelegated     *common.Amount        `json:"undelegated"`
+	Earnings        *common.Amount        `json:"earnings"`
+	Transferred     bool                  `json:"transferred"` // operator tokens were transferred in or out
+	Pending         []PendingUndelegation `json:"pending"`
+	PendingTotal    *common.Amount        `json:"pendingTotal"`
+}
+
+// StatementEntry is a delegator's transaction with the operator.
+type StatementEntry struct {
+	Timestamp    time.Time      `json:"timestamp"`
+	Type         string         `json:"type"`
+	Amount       *common.Amount `json:"amount"` // DATA for delegations and undelegations, operator tokens for transfers
+	Counterparty string         `json:"counterparty,omitempty"`
+	TxHash       string         `json:"txHash"`
+	Block        uint64         `json:"block"`
+}
+
+// DelegatorStatement is a report for a delegator of its position in the operator and its
+// transactions in [From, To].
+type DelegatorStatement struct {
+	Operator    ethcommon.Address `json:"operator"`
+	Generated   time.Time         `json:"generated"`
+	From        *time.Time        `json:"from,omitempty"`
+	To          *time.Time        `json:"to,omitempty"`
+	Summary     DelegatorSummary  `json:"summary"`
+	Entries     []StatementEntry  `json:"entries"`
+	Delegated   *common.Amount    `json:"delegated"`   // in the period
+	Undelegated *common.Amount    `json:"undelegated"` // in the period
+}
+
+type delegatorHistory struct {
+	entries     []StatementEntry
+	delegated   *big.Int
+	undelegated *big.Int
+	first       *time.Time
+	transferred bool
+}
+
+// Delegators returns every delegator with operator tokens or queued undelegations, or with all set
+// also those who have left, largest value first.
+func (o *Operator) Delegators(all bool) ([]DelegatorSummary, error) {
+	histories, err := o.delegatorHistories()
+	if err != nil {
+		return nil, err
+	}
+	pending, err := o.pendingByDelegator()
+	if err != nil {
+		return nil, err
+	}
+	supply, err := o.operatorUint("totalSupply")
+	if err != nil {
+		return nil, err
+	}
+	for delegator := range pending {
+		if _, ok := histories[delegator]; !ok {
+			histories[delegator] = newDelegatorHistory()
+		}
+	}
+
+	delegators := []DelegatorSummary{}
+	for delegator, history := range histories {
+		summary, err := o.delegatorSummary(delegator, history, pending[delegator], supply)
+		if err != nil {
+			return nil, err
+		}
+		if !all && summary.OperatorTokens.Int().Sign() == 0 && len(summary.Pending) == 0 {
+			continue
+		}
+		delegators = append(delegators, summary)
+	}
+	sort.Slice(delegators, func(i, j int) bool {
+		if c := delegators[i].Value.Int().Cmp(delegators[j].Value.Int()); c != 0 {
+			return c > 0
+		}
+		return delegators[i].Delegator.Hex() < delegators[j].Delegator.Hex()
+	})
+	return delegators, nil
+}
+
+// DelegatorStatement builds the statement of a delegator for [from, to]. Zero times mean no bound.
+func (o *Operator) DelegatorStatement(delegator ethcommon.Address, from time.Time, to time.Time) (DelegatorStatement, error) {
+	statement := DelegatorStatement{Operator: o.ContractAddr, Generated: time.Now().UTC(), Entries: []StatementEntry{}}
+	if !from.IsZero() {
+		statement.From = &from
+	}
+	if !to.IsZero() {
+		statement.To = &to
+	}
+
+	histories, err := o.delegatorHistories()
+	if err != nil {
+		return statement, err
+	}
+	pending, err := o.pendingByDelegator()
+	if err != nil {
+		return statement, err
+	}
+	history, ok := histories[delegator]
+	if !ok {
+		if _, queued := pending[delegator]; !queued {
+			return statement, ErrUnknownDelegator
+		}
+		history = newDelegatorHistory()
+	}
+	supply, err := o.operatorUint("totalSupply")
+	if err != nil {
+		return statement, err
+	}
+
+	statement.Summary, err = o.delegatorSummary(delegator, history, pending[delegator], supply)
+	if err != nil {
+		return statement, err
+	}
+
+	delegated, undelegated := big.NewInt(0), big.NewInt(0)
+	for _, entry := range history.entries {
+		if !inRange(entry.Timestamp, from, to) {
+			continue
+		}
+		statement.Entries = append(statement.Entries, entry)
+		switch entry.Type {
+		case StatementDelegation:
+			delegated.Add(delegated, entry.Amount.Int())
+		case StatementUndelegation:
+			undelegated.Add(undelegated, entry.Amount.Int())
+		}
+	}
+	statement.Delegated = common.NewAmount(delegated)
+	statement.Undelegated = common.NewAmount(undelegated)
+	return statement, nil
+}
+
+// WriteStatementCSV writes the entries of a statement, preceded by the delegator's position.
+func WriteStatementCSV(w io.Writer, statement DelegatorStatement) error {
+	writer := csv.NewWriter(w)
+	summary := statement.Summary
+	rows := [][]string{
+		{"Operator", statement.Operator.Hex()},
+		{"Delegator", summary.Delegator.Hex()},
+		{"Generated", statement.Generated.Format("2006-01-02 15:04:05 UTC")},
+		{"Operator tokens", summary.OperatorTokens.DATA()},
+		{"Value (DATA)", summary.Value.DATA()},
+		{"Delegated (DATA)", summary.Delegated.DATA()},
+		{"Undelegated (DATA)", summary.Undelegated.DATA()},
+		{"Earnings (DATA)", summary.Earnings.DATA()},
+		{"Pending undelegations (DATA)", summary.PendingTotal.DATA()},
+		{},
+		statementCSVHeader,
+	}
+	for _, row := range rows {
+		if err := writer.Write(row); err != nil {
+			return err
+		}
+	}
+
+	for _, entry := range statement.Entries {
+		currency := "DATA"
+		if entry.Type == StatementTransferIn || entry.Type == StatementTransferOut {
+			currency = "operator token"
+		}
+		err := writer.Write([]string{
+			entry.Timestamp.UTC().Format("2006-01-02 15:04:05 UTC"),
+			entry.Type,
+			entry.Amount.DATA(),
+			currency,
+			entry.Counterparty,
+			entry.TxHash,
+			fmt.Sprintf("%d", entry.Block),
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+func newDelegatorHistory() *delegatorHistory {
+	return &delegatorHistory{entries: []StatementEntry{}, delegated: big.NewInt(0), undelegated: big.NewInt(0)}
+}
+
+// delegatorHistories replays the indexed Delegated, Undelegated and operator token Transfer events.
+// Delegations and undelegations also mint and burn operator tokens, those transfers are skipped.
+func (o *Operator) delegatorHistories() (map[ethcommon.Address]*delegatorHistory, error) {
+	if o.Indexer == nil {
+		return nil, blockchain.ErrIndexerDisabled
+	}
+	events, err := o.Indexer.Events(blockchain.EventFilter{Names: []string{"Delegated", "Undelegated", "Transfer"}})
+	if err != nil {
+		return nil, err
+	}
+
+	histories := make(map[ethcommon.Address]*delegatorHistory)
+	get := func(addr ethcommon.Address) *delegatorHistory {
+		if _, ok := histories[addr]; !ok {
+			histories[addr] = newDelegatorHistory()
+		}
+		return histories[addr]
+	}
+	zero := ethcommon.Address{}
+
+	for _, event := range events {
+		if event.Contract != o.ContractAddr {
+			continue
+		}
+		timestamp := time.Unix(int64(event.Timestamp), 0).UTC()
+		entry := StatementEntry{Timestamp: timestamp, TxHash: event.TxHash.Hex(), Block: event.BlockNumber}
+
+		switch event.Name {
+		case "Delegated":
+			h := get(ethcommon.HexToAddress(event.Args["delegator"]))
+			amount := eventAmount(event, "amountDataWei", "amountWei")
+			entry.Type, entry.Amount = StatementDelegation, common.NewAmount(amount)
+			h.delegated.Add(h.delegated, amount)
+			if h.first == nil {
+				h.first = &timestamp
+			}
+			h.entries = append(h.entries, entry)
+		case "Undelegated":
+			h := get(ethcommon.HexToAddress(event.Args["delegator"]))
+			amount := eventAmount(event, "amountDataWei", "amountWei")
+			entry.Type, entry.Amount = StatementUndelegation, common.NewAmount(amount)
+			h.undelegated.Add(h.undelegated, amount)
+			h.entries = append(h.entries, entry)
+		case "Transfer":
+			from := ethcommon.HexToAddress(event.Args["from"])
+			to := ethcommon.HexToAddress(event.Args["to"])
+			if from == zero || to == zero {
+				continue
+			}
+			amount := common.NewAmount(eventAmount(event, "value", "amount"))
+
+			out := entry
+			out.Type, out.Amount, out.Counterparty = StatementTransferOut, amount, to.Hex()
+			sender := get(from)
+			sender.transferred = true
+			sender.entries = append(sender.entries, out)
+
+			in := entry
+			in.Type, in.Amount, in.Counterparty = StatementTransferIn, amount, from.Hex()
+			receiver := get(to)
+			receiver.transferred = true
+			if receiver.first == nil {
+				receiver.first = &timestamp
+			}
+			receiver.entries = append(receiver.entries, in)
+		}
+	}
+	return histories, nil
+}
+
+// pendingByDelegator groups the undelegation queue by delegator.
+func (o *Operator) pendingByDelegator() (map[ethcommon.Address][]PendingUndelegation, error) {
+	queue, err := o.GetUndelegationQueue()
+	if err != nil {
+		return nil, err
+	}
+
+	pending := make(map[ethcommon.Address][]PendingUndelegation)
+	for _, records := range queue {
+		for _, record := range records {
+			if record.Amount == nil {
+				continue
+			}
+			entry := PendingUndelegation{Amount: record.Amount}
+			if record.Timestamp != nil {
+				entry.QueuedAt = time.Unix(record.Timestamp.Int64(), 0).UTC()
+			}
+			pending[record.Delegator] = append(pending[record.Delegator], entry)
+		}
+	}
+	return pending, nil
+}
+
+func (o *Operator) delegatorSummary(delegator ethcommon.Address, history *delegatorHistory, pending []PendingUndelegation, supply *big.Int) (DelegatorSummary, error) {
+	tokens, err := o.operatorUint("balanceOf", delegator)
+	if err != nil {
+		return DelegatorSummary{}, err
+	}
+	value, err := o.GetDelegatorBalance(delegator)
+	if err != nil {
+		return DelegatorSummary{}, err
+	}
+
+	summary := DelegatorSummary{
+		Delegator:       delegator,
+		OperatorTokens:  common.NewAmount(tokens),
+		Value:           common.NewAmount(value),
+		FirstDelegation: history.first,
+		Delegated:       common.NewAmount(history.delegated),
+		Undelegated:     common.NewAmount(history.undelegated),
+		Transferred:     history.transferred,
+		Pending:         []PendingUndelegation{},
+	}
+	if supply.Sign() > 0 {
+		summary.Share = ratio(tokens, supply)
+	}
+
+	earnings := new(big.Int).Add(value, history.undelegated)
+	earnings.Sub(earnings, history.delegated)
+	summary.Earnings = common.NewAmount(earnings)
+
+	total := big.NewInt(0)
+	for _, entry := range pending {
+		summary.Pending = append(summary.Pending, entry)
+		total.Add(total, entry.Amount.Int())
+	}
+	summary.PendingTotal = common.NewAmount(total)
+	return summary, nil
+}
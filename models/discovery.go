@@ -0,0 +1,446 @@
+package models
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math/big"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"streamr_api/blockchain"
+	"streamr_api/common"
+	"streamr_api/config"
+
+	ethcommon "github.com/ethereum/go-ethereum/common"
+)
+
+const secondsPerYear = 365 * 24 * 60 * 60
+
+// Sort orders for the discovered sponsorships.
+const (
+	SortAPY       = "apy"       // highest projected APY first
+	SortRunway    = "runway"    // longest funded first
+	SortRemaining = "remaining" // most remaining funding first
+	SortPayout    = "payout"    // highest payout rate first
+)
+
+type DiscoveryConfig struct {
+	SubgraphURL string        // empty to only evaluate the sponsorships the operator is staked in
+	Limit       int           // how many sponsorships to fetch from the subgraph
+	CacheTTL    time.Duration // how long the fetched state is reused
+	Timeout     time.Duration
+}
+
+// SponsorshipMetrics describes a sponsorship as a place to stake. The state comes from the subgraph
+// and is refreshed from the Sponsorship contract; the projected APY is for adding AdditionalStake.
+type SponsorshipMetrics struct {
+	Sponsorship     ethcommon.Address `json:"sponsorship"`
+	Stream          string            `json:"stream,omitempty"`
+	IsRunning       bool              `json:"isRunning"`
+	Remaining       *common.Amount    `json:"remaining"`       // unallocated sponsorship funding
+	PayoutPerSecond *common.Amount    `json:"payoutPerSecond"` // paid out to all operators together
+	TotalStake      *common.Amount    `json:"totalStake"`
+	OperatorCount   int               `json:"operatorCount"`
+	MaxOperators    int               `json:"maxOperators,omitempty"` // 0 if the subgraph doesn't say
+
+	MinimumStakingPeriodSeconds int64 `json:"minimumStakingPeriodSeconds"`
+
+	OwnStake        *common.Amount `json:"ownStake"` // the operator's current stake
+	AdditionalStake *common.Amount `json:"additionalStake"`
+	ProjectedAPY    float64        `json:"projectedApy"` // annualized, after the protocol fee, e.g. 0.15 for 15%
+	CurrentAPY      float64        `json:"currentApy"`   // what the stake already in the sponsorship earns, same units
+	FundedUntil     *time.Time     `json:"fundedUntil,omitempty"`
+	RunwaySeconds   int64          `json:"runwaySeconds"` // until the funding runs out, -1 if it is not being paid out
+	Source          string         `json:"source"`        // chain, subgraph or subgraph+chain
+	Updated         time.Time      `json:"updated"`
+}
+
+// Discovery lists sponsorships the operator could stake in, along with the ones it is staked in,
+// and evaluates them.
+type Discovery struct {
+	o        *Operator
+	config   atomic.Pointer[DiscoveryConfig]
+	subgraph atomic.Pointer[blockchain.SubgraphClient]
+
+	mu       sync.Mutex
+	cached   []SponsorshipMetrics
+	cachedAt time.Time
+}
+
+func NewDiscoveryConfig(p config.DiscoveryPolicy) DiscoveryConfig {
+	return DiscoveryConfig{
+		SubgraphURL: p.SubgraphURL,
+		Limit:       p.Limit,
+		CacheTTL:    time.Duration(p.CacheSeconds) * time.Second,
+		Timeout:     30 * time.Second,
+	}
+}
+
+func NewDiscovery(o *Operator, config DiscoveryConfig) *Discovery {
+	d := &Discovery{o: o}
+	d.SetConfig(config)
+	return d
+}
+
+// SetConfig replaces the configuration and drops the cached state.
+func (d *Discovery) SetConfig(config DiscoveryConfig) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.config.Store(&config)
+	d.cached = nil
+	if config.SubgraphURL != "" {
+		d.subgraph.Store(blockchain.NewSubgraphClient(config.SubgraphURL, config.Timeout))
+	} else {
+		d.subgraph.Store(nil)
+		log.Printf("No subgraph URL is set, sponsorship discovery only covers the sponsorships the operator is staked in")
+	}
+}
+
+// Evaluate returns the metrics of every known sponsorship for adding additional DATA to it, sorted
+// by the given order. A nil additional evaluates a stake of the protocol minimum. The state is
+// cached for the configured time unless refresh is set.
+func (d *Discovery) Evaluate(additional *big.Int, sortBy string, refresh bool) ([]SponsorshipMetrics, error) {
+	if err := ValidSort(sortBy); err != nil {
+		return nil, err
+	}
+	states, err := d.states(refresh)
+	if err != nil {
+		return nil, err
+	}
+	result, err := d.evaluate(states, additional)
+	if err != nil {
+		return nil, err
+	}
+	sortMetrics(result, sortBy)
+	return result, nil
+}
+
+// EvaluateOne returns the metrics of a single sponsorship, read fresh.
+func (d *Discovery) EvaluateOne(addr ethcommon.Address, additional *big.Int) (SponsorshipMetrics, error) {
+	deployed, err := d.o.GetDeployedStake()
+	if err != nil {
+		return SponsorshipMetrics{}, err
+	}
+	m, err := d.read(addr)
+	if err != nil {
+		return m, err
+	}
+	if stake, ok := deployed.DeployedBySponsorship[addr]; ok {
+		m.OwnStake = stake
+	}
+
+	result, err := d.evaluate([]SponsorshipMetrics{m}, additional)
+	if err != nil {
+		return m, err
+	}
+	return result[0], nil
+}
+
+// Staked returns the metrics of the sponsorships the operator is staked in, read fresh, sorted by
+// address. The projected APY is for adding nothing, i.e. the marginal yield.
+func (d *Discovery) Staked() ([]SponsorshipMetrics, error) {
+	deployed, err := d.o.GetDeployedStake()
+	if err != nil {
+		return nil, err
+	}
+
+	states := []SponsorshipMetrics{}
+	for addr, stake := range deployed.DeployedBySponsorship {
+		m, err := d.read(addr)
+		if err != nil {
+			return nil, err
+		}
+		m.OwnStake = stake
+		states = append(states, m)
+	}
+	sort.Slice(states, func(i, j int) bool {
+		return states[i].Sponsorship.Hex() < states[j].Sponsorship.Hex()
+	})
+	return d.evaluate(states, big.NewInt(0))
+}
+
+// read returns the state of a sponsorship from the subgraph, if it knows it, and the contract.
+func (d *Discovery) read(addr ethcommon.Address) (SponsorshipMetrics, error) {
+	m := SponsorshipMetrics{Sponsorship: addr}
+	if subgraph := d.subgraph.Load(); subgraph != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), d.config.Load().Timeout)
+		s, err := subgraph.Sponsorship(ctx, addr.Hex())
+		cancel()
+		if err != nil {
+			log.Printf("Failed to query sponsorship %s from the subgraph: %v", addr.Hex(), err)
+		} else if s != nil {
+			m = fromSubgraph(*s)
+		}
+	}
+	if err := d.o.readSponsorship(&m); err != nil {
+		if m.Source == "" {
+			return m, err
+		}
+		log.Printf("Failed to read sponsorship %s, using the subgraph state: %v", addr.Hex(), err)
+	}
+	return m, nil
+}
+
+func ValidSort(sortBy string) error {
+	switch sortBy {
+	case SortAPY, SortRunway, SortRemaining, SortPayout:
+		return nil
+	}
+	return fmt.Errorf("invalid sort %q, expected %s, %s, %s or %s", sortBy, SortAPY, SortRunway, SortRemaining, SortPayout)
+}
+
+// states returns the state of the subgraph's sponsorships and of those the operator is staked in.
+func (d *Discovery) states(refresh bool) ([]SponsorshipMetrics, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if !refresh && d.cached != nil && time.Since(d.cachedAt) < d.config.Load().CacheTTL {
+		return append([]SponsorshipMetrics{}, d.cached...), nil
+	}
+
+	states := []SponsorshipMetrics{}
+	index := make(map[ethcommon.Address]int)
+	if subgraph := d.subgraph.Load(); subgraph != nil {
+		config := d.config.Load()
+		ctx, cancel := context.WithTimeout(context.Background(), config.Timeout)
+		sponsorships, err := subgraph.Sponsorships(ctx, config.Limit)
+		cancel()
+		if err != nil {
+			// the contracts still cover the sponsorships the operator is in
+			log.Printf("Failed to query sponsorships from the subgraph: %v", err)
+		}
+		for _, s := range sponsorships {
+			m := fromSubgraph(s)
+			index[m.Sponsorship] = len(states)
+			states = append(states, m)
+		}
+	}
+
+	deployed, err := d.o.GetDeployedStake()
+	if err != nil {
+		return nil, err
+	}
+	for addr, stake := range deployed.DeployedBySponsorship {
+		i, ok := index[addr]
+		if !ok {
+			i = len(states)
+			index[addr] = i
+			states = append(states, SponsorshipMetrics{Sponsorship: addr})
+		}
+		states[i].OwnStake = stake
+	}
+
+	// each sponsorship takes a handful of calls, so read them a few at a time
+	errs := make([]error, len(states))
+	sem := make(chan struct{}, 8)
+	var wg sync.WaitGroup
+	for i := range states {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			errs[i] = d.o.readSponsorship(&states[i])
+		}(i)
+	}
+	wg.Wait()
+
+	result := []SponsorshipMetrics{}
+	for i, m := range states {
+		if errs[i] != nil {
+			log.Printf("Failed to read sponsorship %s: %v", m.Sponsorship.Hex(), errs[i])
+			if m.Source == "" {
+				continue
+			}
+		}
+		result = append(result, m)
+	}
+
+	d.cached = result
+	d.cachedAt = time.Now()
+	return append([]SponsorshipMetrics{}, result...), nil
+}
+
+// evaluate fills in the metrics that depend on the additional stake and the current time.
+func (d *Discovery) evaluate(states []SponsorshipMetrics, additional *big.Int) ([]SponsorshipMetrics, error) {
+	if additional == nil {
+		minStake, err := d.o.GetMinimumStake()
+		if err != nil {
+			return nil, err
+		}
+		additional = minStake
+	}
+	protocolFee, err := d.o.GetProtocolFeeFraction()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	result := make([]SponsorshipMetrics, len(states))
+	for i, m := range states {
+		if m.OwnStake == nil {
+			m.OwnStake = common.NewAmount(nil)
+		}
+		m.AdditionalStake = common.NewAmount(additional)
+		m.ProjectedAPY = projectedAPY(m.PayoutPerSecond.Int(), m.TotalStake.Int(), m.OwnStake.Int(), additional, protocolFee)
+		m.CurrentAPY = currentAPY(m.PayoutPerSecond.Int(), m.TotalStake.Int(), protocolFee)
+		m.RunwaySeconds = -1
+		if m.FundedUntil != nil {
+			m.RunwaySeconds = int64(m.FundedUntil.Sub(now).Seconds())
+			if m.RunwaySeconds < 0 {
+				m.RunwaySeconds = 0
+			}
+		}
+		result[i] = m
+	}
+	return result, nil
+}
+
+// projectedAPY is the annual yield on additional DATA staked into a sponsorship: the share of the
+// payout it adds for the operator, after the protocol fee, over the amount added. Stake the operator
+// already has in the sponsorship gets diluted by the addition, so the extra share is
+// additional * (total - own) / (total * (total + additional)).
+func projectedAPY(payoutPerSecond *big.Int, totalStake *big.Int, ownStake *big.Int, additional *big.Int, protocolFee *big.Int) float64 {
+	yearly := new(big.Int).Mul(payoutPerSecond, big.NewInt(secondsPerYear))
+	yearly.Sub(yearly, applyFraction(yearly, protocolFee))
+
+	if totalStake.Sign() == 0 {
+		if additional.Sign() == 0 {
+			return 0
+		}
+		return ratio(yearly, additional)
+	}
+	numerator := new(big.Int).Mul(yearly, new(big.Int).Sub(totalStake, ownStake))
+	denominator := new(big.Int).Mul(totalStake, new(big.Int).Add(totalStake, additional))
+	return ratio(numerator, denominator)
+}
+
+// currentAPY is the annual yield of the stake in a sponsorship, which is the same for every operator
+// in it as the payout is split by stake.
+func currentAPY(payoutPerSecond *big.Int, totalStake *big.Int, protocolFee *big.Int) float64 {
+	if totalStake.Sign() == 0 {
+		return 0
+	}
+	yearly := new(big.Int).Mul(payoutPerSecond, big.NewInt(secondsPerYear))
+	yearly.Sub(yearly, applyFraction(yearly, protocolFee))
+	return ratio(yearly, totalStake)
+}
+
+func sortMetrics(metrics []SponsorshipMetrics, sortBy string) {
+	sort.SliceStable(metrics, func(i, j int) bool {
+		a, b := metrics[i], metrics[j]
+		switch sortBy {
+		case SortRunway:
+			// funding that isn't being paid out lasts, but earns nothing, so it goes last
+			if (a.RunwaySeconds < 0) != (b.RunwaySeconds < 0) {
+				return b.RunwaySeconds < 0
+			}
+			return a.RunwaySeconds > b.RunwaySeconds
+		case SortRemaining:
+			return a.Remaining.Int().Cmp(b.Remaining.Int()) > 0
+		case SortPayout:
+			return a.PayoutPerSecond.Int().Cmp(b.PayoutPerSecond.Int()) > 0
+		}
+		return a.ProjectedAPY > b.ProjectedAPY
+	})
+}
+
+func fromSubgraph(s blockchain.SubgraphSponsorship) SponsorshipMetrics {
+	m := SponsorshipMetrics{
+		Sponsorship:     ethcommon.HexToAddress(s.ID),
+		IsRunning:       s.IsRunning,
+		Remaining:       common.NewAmount(parseBigInt(s.RemainingWei)),
+		PayoutPerSecond: common.NewAmount(parseBigInt(s.TotalPayoutWeiPerSec)),
+		TotalStake:      common.NewAmount(parseBigInt(s.TotalStakedWei)),
+		OperatorCount:   s.OperatorCount,
+		Source:          "subgraph",
+		Updated:         time.Now().UTC(),
+	}
+	if s.Stream != nil {
+		m.Stream = s.Stream.ID
+	}
+	if s.MaxOperators != nil {
+		m.MaxOperators = *s.MaxOperators
+	}
+	if period := parseBigInt(s.MinimumStakingPeriodSeconds); period.IsInt64() {
+		m.MinimumStakingPeriodSeconds = period.Int64()
+	}
+	m.FundedUntil = fundedUntil(parseBigInt(s.ProjectedInsolvency))
+	return m
+}
+
+// readSponsorship refreshes the state from the Sponsorship contract. The payout rate isn't readable
+// from the contract, so unless the subgraph provided it, it is worked out from the remaining funding
+// and when it runs out.
+func (o *Operator) readSponsorship(m *SponsorshipMetrics) error {
+	uints := make(map[string]*big.Int)
+	for _, method := range []string{"totalStakedWei", "remainingWei", "operatorCount", "solventUntilTimestamp"} {
+		result, err := o.TxManager.ContractCallAt(m.Sponsorship, blockchain.SponsorshipAbi, method, []interface{}{})
+		if err != nil {
+			return err
+		}
+		value, ok := result[0].(*big.Int)
+		if !ok {
+			return fmt.Errorf("unexpected %s result: %v", method, result[0])
+		}
+		uints[method] = value
+	}
+	result, err := o.TxManager.ContractCallAt(m.Sponsorship, blockchain.SponsorshipAbi, "isRunning", []interface{}{})
+	if err != nil {
+		return err
+	}
+	running, ok := result[0].(bool)
+	if !ok {
+		return fmt.Errorf("unexpected isRunning result: %v", result[0])
+	}
+	// older sponsorships have the period in their leave policy, keep what the subgraph said
+	if result, err := o.TxManager.ContractCallAt(m.Sponsorship, blockchain.SponsorshipAbi, "minimumStakingPeriodSeconds", []interface{}{}); err == nil {
+		if period, ok := result[0].(*big.Int); ok && period.IsInt64() {
+			m.MinimumStakingPeriodSeconds = period.Int64()
+		}
+	}
+
+	now := time.Now().UTC()
+	m.IsRunning = running
+	m.TotalStake = common.NewAmount(uints["totalStakedWei"])
+	m.Remaining = common.NewAmount(uints["remainingWei"])
+	m.OperatorCount = int(uints["operatorCount"].Int64())
+	m.FundedUntil = fundedUntil(uints["solventUntilTimestamp"])
+	if m.PayoutPerSecond == nil {
+		rate := big.NewInt(0)
+		if running && m.FundedUntil != nil && m.FundedUntil.After(now) {
+			rate.Div(m.Remaining.Int(), big.NewInt(int64(m.FundedUntil.Sub(now).Seconds())+1))
+		}
+		m.PayoutPerSecond = common.NewAmount(rate)
+	}
+
+	if m.Source == "" {
+		m.Source = "chain"
+	} else {
+		m.Source += "+chain"
+	}
+	m.Updated = now
+	return nil
+}
+
+// fundedUntil converts an insolvency timestamp. Sponsorships that aren't paying out report a
+// timestamp far in the future (or none), which means the funding doesn't run out.
+func fundedUntil(timestamp *big.Int) *time.Time {
+	if timestamp.Sign() == 0 || timestamp.Cmp(big.NewInt(1<<40)) > 0 {
+		return nil
+	}
+	t := time.Unix(timestamp.Int64(), 0).UTC()
+	return &t
+}
+
+func parseBigInt(s string) *big.Int {
+	value, ok := new(big.Int).SetString(s, 10)
+	if !ok {
+		return big.NewInt(0)
+	}
+	return value
+}
@@ -0,0 +1,380 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"streamr_api/blockchain"
+	"streamr_api/common"
+	"streamr_api/config"
+
+	ethcommon "github.com/ethereum/go-ethereum/common"
+)
+
+const exitAlertSource = "exit-policy"
+
+// Exit decisions for a staked sponsorship.
+const (
+	ExitKeep  = "keep"
+	ExitLeave = "exit"
+	ExitDefer = "defer" // a trigger fired, but leaving now would forfeit a leave penalty
+)
+
+type ExitPolicyConfig struct {
+	MinRunway     time.Duration // 0 disables the runway trigger
+	MinAPYPercent int64         // 0 disables the yield trigger
+	Allocation    string        // how the freed DATA is staked into the remaining sponsorships
+	Interval      time.Duration // 0 disables checking in the background
+	TxTimeout     time.Duration
+}
+
+// ExitDecision records what the policy decided for a sponsorship and the metrics it decided on.
+type ExitDecision struct {
+	Sponsorship   ethcommon.Address  `json:"sponsorship"`
+	Action        string             `json:"action"`
+	Reasons       []string           `json:"reasons"`
+	Metrics       SponsorshipMetrics `json:"metrics"`
+	LeavePenalty  *common.Amount     `json:"leavePenalty,omitempty"`
+	PenaltyFreeAt *time.Time         `json:"penaltyFreeAt,omitempty"` // end of the minimum staking period
+	Withdrawal    string             `json:"withdrawal,omitempty"`    // tx hash
+	Unstake       string             `json:"unstake,omitempty"`       // tx hash
+	Error         string             `json:"error,omitempty"`
+}
+
+// ExitReport records a run of the exit policy.
+type ExitReport struct {
+	Timestamp    time.Time       `json:"timestamp"`
+	DryRun       bool            `json:"dryRun"`
+	Decisions    []ExitDecision  `json:"decisions"`
+	Freed        *common.Amount  `json:"freed"`    // DATA the exits returned to the operator
+	Reserved     *common.Amount  `json:"reserved"` // DATA kept back for the undelegation queue
+	Redeployed   []StakeAddition `json:"redeployed"`
+	Transactions []string        `json:"transactions"`
+	Error        string          `json:"error,omitempty"`
+}
+
+// ExitPolicy leaves the staked sponsorships whose funding is about to run out or whose yield has
+// dropped too low, and stakes the freed DATA into the remaining sponsorships.
+type ExitPolicy struct {
+	o      *Operator
+	store  *blockchain.Store
+	alerts *Alerter
+	config atomic.Pointer[ExitPolicyConfig]
+	prefix string
+
+	running  sync.Mutex
+	deferred map[ethcommon.Address]bool // sponsorships already alerted about
+
+	quit chan struct{}
+	wg   sync.WaitGroup
+}
+
+func NewExitPolicyConfig(p config.ExitPolicy) ExitPolicyConfig {
+	return ExitPolicyConfig{
+		MinRunway:     time.Duration(p.MinRunwayHours) * time.Hour,
+		MinAPYPercent: int64(p.MinAPYPercent),
+		Allocation:    p.Allocation,
+		Interval:      time.Duration(p.IntervalSeconds) * time.Second,
+		TxTimeout:     5 * time.Minute,
+	}
+}
+
+func NewExitPolicy(o *Operator, store *blockchain.Store, alerts *Alerter, config ExitPolicyConfig) *ExitPolicy {
+	p := &ExitPolicy{
+		o:        o,
+		store:    store,
+		alerts:   alerts,
+		prefix:   fmt.Sprintf("exit/%s/", strings.ToLower(o.ContractAddr.Hex())),
+		deferred: make(map[ethcommon.Address]bool),
+		quit:     make(chan struct{}),
+	}
+	p.config.Store(&config)
+	return p
+}
+
+func (p *ExitPolicy) Start() error {
+	config := p.config.Load()
+	if err := ValidAllocation(config.Allocation); err != nil {
+		return err
+	}
+	if config.Interval == 0 || (config.MinRunway == 0 && config.MinAPYPercent == 0) {
+		log.Printf("Exit policy is disabled")
+		return nil
+	}
+
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+
+		ticker := time.NewTicker(config.Interval)
+		defer ticker.Stop()
+
+		for {
+			if _, err := p.Check(false); err != nil {
+				log.Printf("Exit policy check failed: %v", err)
+			}
+
+			select {
+			case <-p.quit:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+	return nil
+}
+
+func (p *ExitPolicy) Stop() {
+	close(p.quit)
+	p.wg.Wait()
+}
+
+// Reload restarts the service with a new configuration once the check in progress is done.
+func (p *ExitPolicy) Reload(config ExitPolicyConfig) error {
+	p.Stop()
+	p.config.Store(&config)
+	p.quit = make(chan struct{})
+	return p.Start()
+}
+
+// Check evaluates every staked sponsorship and, unless dryRun is set, leaves the ones that
+// triggered the policy and redeploys the freed DATA. Runs that decided anything other than keeping
+// every sponsorship are recorded.
+func (p *ExitPolicy) Check(dryRun bool) (ExitReport, error) {
+	p.running.Lock()
+	defer p.running.Unlock()
+	o := p.o
+
+	report := ExitReport{
+		Timestamp:    time.Now().UTC(),
+		DryRun:       dryRun,
+		Decisions:    []ExitDecision{},
+		Freed:        common.NewAmount(nil),
+		Reserved:     common.NewAmount(nil),
+		Redeployed:   []StakeAddition{},
+		Transactions: []string{},
+	}
+	if o.Discovery == nil {
+		return report, fmt.Errorf("sponsorship discovery is not running")
+	}
+
+	staked, err := o.Discovery.Staked()
+	if err != nil {
+		return report, err
+	}
+	for _, m := range staked {
+		decision, err := p.decide(m, report.Timestamp)
+		if err != nil {
+			return report, err
+		}
+		log.Printf("Exit policy: %s %s (runway %ds, yield %.2f%%, remaining %s DATA, stake %s of %s DATA): %s",
+			decision.Action, m.Sponsorship.Hex(), m.RunwaySeconds, m.CurrentAPY*100, m.Remaining.DATA(),
+			m.OwnStake.DATA(), m.TotalStake.DATA(), strings.Join(decision.Reasons, "; "))
+		report.Decisions = append(report.Decisions, decision)
+	}
+
+	if !dryRun {
+		err = p.execute(&report)
+		if err != nil {
+			report.Error = err.Error()
+			p.alerts.Alert(AlertCritical, exitAlertSource, "exit policy failed: %v", err)
+		}
+	}
+
+	if !dryRun && report.acted() {
+		if storeErr := p.store.Put(fmt.Sprintf("%s%020d", p.prefix, report.Timestamp.UnixNano()), report); storeErr != nil {
+			log.Printf("Failed to record exit report: %v", storeErr)
+		}
+	}
+	return report, err
+}
+
+// decide applies the triggers to a sponsorship. A triggered sponsorship is only left once leaving
+// doesn't cost a penalty, which is the case after the minimum staking period or once the
+// sponsorship stops paying out.
+func (p *ExitPolicy) decide(m SponsorshipMetrics, now time.Time) (ExitDecision, error) {
+	decision := ExitDecision{Sponsorship: m.Sponsorship, Action: ExitKeep, Reasons: exitReasons(m, *p.config.Load()), Metrics: m}
+	if len(decision.Reasons) == 0 {
+		return decision, nil
+	}
+
+	penalty, err := p.o.GetLeavePenalty(m.Sponsorship)
+	if err != nil {
+		return decision, err
+	}
+	decision.LeavePenalty = common.NewAmount(penalty)
+	if penalty.Sign() == 0 {
+		decision.Action = ExitLeave
+		return decision, nil
+	}
+
+	decision.Action = ExitDefer
+	if joined, err := p.o.GetJoinTime(m.Sponsorship); err == nil {
+		penaltyFree := joined.Add(time.Duration(m.MinimumStakingPeriodSeconds) * time.Second)
+		if penaltyFree.After(now) {
+			decision.PenaltyFreeAt = &penaltyFree
+		}
+	}
+	return decision, nil
+}
+
+// exitReasons returns why a sponsorship should be left, if it should.
+func exitReasons(m SponsorshipMetrics, config ExitPolicyConfig) []string {
+	reasons := []string{}
+	if config.MinRunway > 0 && m.RunwaySeconds >= 0 && time.Duration(m.RunwaySeconds)*time.Second < config.MinRunway {
+		reasons = append(reasons, fmt.Sprintf("funding runs out in %s, under the minimum runway of %s",
+			(time.Duration(m.RunwaySeconds)*time.Second).String(), config.MinRunway.String()))
+	}
+	if config.MinAPYPercent > 0 && m.CurrentAPY*100 < float64(config.MinAPYPercent) {
+		reasons = append(reasons, fmt.Sprintf("yield of %.2f%% is under the minimum of %d%%", m.CurrentAPY*100, config.MinAPYPercent))
+	}
+	return reasons
+}
+
+// execute leaves the sponsorships decided on, withdrawing their earnings first, then stakes what
+// was freed, minus what the undelegation queue needs, into the remaining sponsorships.
+func (p *ExitPolicy) execute(report *ExitReport) error {
+	o := p.o
+
+	before, err := o.GetDataBalance()
+	if err != nil {
+		return err
+	}
+	sponsors, err := o.GetSponsorshipsAndEarnings()
+	if err != nil {
+		return err
+	}
+	earnings := make(map[ethcommon.Address]*big.Int)
+	for i, addr := range sponsors.Addresses {
+		earnings[addr] = sponsors.Earnings[i].Int()
+	}
+
+	// a failed leave doesn't stop the others, nor the redeployment of what they freed
+	var leaveErrs []error
+	exited := 0
+	triggered := make(map[ethcommon.Address]bool)
+	for i := range report.Decisions {
+		decision := &report.Decisions[i]
+		if decision.Action != ExitKeep {
+			triggered[decision.Sponsorship] = true
+		}
+		switch decision.Action {
+		case ExitLeave:
+			if err := p.leave(decision, earnings[decision.Sponsorship], report); err != nil {
+				decision.Error = err.Error()
+				leaveErrs = append(leaveErrs, fmt.Errorf("leaving %s: %w", decision.Sponsorship.Hex(), err))
+				continue
+			}
+			exited++
+			delete(p.deferred, decision.Sponsorship)
+			p.alerts.Alert(AlertWarning, exitAlertSource, "left sponsorship %s: %s", decision.Sponsorship.Hex(), strings.Join(decision.Reasons, "; "))
+		case ExitDefer:
+			if !p.deferred[decision.Sponsorship] {
+				p.deferred[decision.Sponsorship] = true
+				p.alerts.Alert(AlertInfo, exitAlertSource, "not leaving sponsorship %s yet, it would cost a penalty of %s DATA: %s",
+					decision.Sponsorship.Hex(), decision.LeavePenalty.DATA(), strings.Join(decision.Reasons, "; "))
+			}
+		}
+	}
+	if exited == 0 {
+		return errors.Join(leaveErrs...)
+	}
+
+	after, err := o.GetDataBalance()
+	if err != nil {
+		return errors.Join(append(leaveErrs, err)...)
+	}
+	reserved, _, err := o.pendingUndelegations()
+	if err != nil {
+		return errors.Join(append(leaveErrs, err)...)
+	}
+	freed := new(big.Int).Sub(after, before)
+	if freed.Sign() < 0 {
+		// the withdrawals paid out queued undelegations
+		freed.SetInt64(0)
+	}
+	report.Freed = common.NewAmount(freed)
+	report.Reserved = common.NewAmount(reserved)
+
+	available := new(big.Int).Sub(after, reserved)
+	if available.Cmp(freed) > 0 {
+		available.Set(freed)
+	}
+	if available.Sign() <= 0 {
+		return errors.Join(leaveErrs...)
+	}
+
+	stakes, txs, staked, err := o.distributeStake(available, p.config.Load().Allocation, earnings, triggered)
+	report.Redeployed = stakes
+	report.Transactions = append(report.Transactions, txs...)
+	if err != nil {
+		return errors.Join(append(leaveErrs, err)...)
+	}
+	if staked.Sign() == 0 {
+		p.alerts.Alert(AlertWarning, exitAlertSource, "%s DATA freed by leaving sponsorships was not redeployed, there is no other sponsorship to stake into", common.NewAmount(available).DATA())
+	}
+	for _, tx := range txs {
+		if err := o.waitMined(tx, p.config.Load().TxTimeout); err != nil {
+			return errors.Join(append(leaveErrs, err)...)
+		}
+	}
+	return errors.Join(leaveErrs...)
+}
+
+func (p *ExitPolicy) leave(decision *ExitDecision, earnings *big.Int, report *ExitReport) error {
+	o := p.o
+	if earnings != nil && earnings.Sign() > 0 {
+		tx, err := o.WithdrawEarningsFrom([]ethcommon.Address{decision.Sponsorship})
+		if err != nil {
+			return err
+		}
+		decision.Withdrawal = tx
+		report.Transactions = append(report.Transactions, tx)
+		if err := o.waitMined(tx, p.config.Load().TxTimeout); err != nil {
+			return err
+		}
+	}
+
+	tx, err := o.Unstake(decision.Sponsorship)
+	if err != nil {
+		return err
+	}
+	decision.Unstake = tx
+	report.Transactions = append(report.Transactions, tx)
+	return o.waitMined(tx, p.config.Load().TxTimeout)
+}
+
+func (r ExitReport) acted() bool {
+	for _, decision := range r.Decisions {
+		if decision.Action != ExitKeep {
+			return true
+		}
+	}
+	return false
+}
+
+// Reports returns the recorded runs in [from, to], oldest first. Zero times mean no bound.
+func (p *ExitPolicy) Reports(from time.Time, to time.Time) ([]ExitReport, error) {
+	reports := []ExitReport{}
+	err := p.store.ForEach(p.prefix, func(key string, value []byte) error {
+		var report ExitReport
+		if err := json.Unmarshal(value, &report); err != nil {
+			return err
+		}
+		if inRange(report.Timestamp, from, to) {
+			reports = append(reports, report)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return reports, nil
+}
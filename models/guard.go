@@ -0,0 +1,279 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"streamr_api/blockchain"
+	"streamr_api/common"
+	"streamr_api/config"
+
+	ethcommon "github.com/ethereum/go-ethereum/common"
+)
+
+const guardAlertSource = "earnings-guard"
+
+// ErrGuardDisabled is returned by checks while the threshold is 0.
+var ErrGuardDisabled = errors.New("earnings guard is disabled, the threshold is 0")
+
+type EarningsGuardConfig struct {
+	ThresholdPercent int64 // 0 disables the guard
+	Interval         time.Duration
+	TxTimeout        time.Duration
+}
+
+// EarningsGuardAction records a withdrawal sent by the guard.
+type EarningsGuardAction struct {
+	Timestamp    time.Time           `json:"timestamp"`
+	Total        *common.Amount      `json:"total"`
+	MaxAllowed   *common.Amount      `json:"maxAllowed"`
+	Threshold    *common.Amount      `json:"threshold"`
+	Sponsorships []ethcommon.Address `json:"sponsorships"`
+	Withdrawn    *common.Amount      `json:"withdrawn"`
+	TxHash       string              `json:"txHash,omitempty"`
+	Error        string              `json:"error,omitempty"`
+}
+
+type EarningsGuardStatus struct {
+	Enabled          bool           `json:"enabled"`
+	ThresholdPercent int64          `json:"thresholdPercent"`
+	Interval         string         `json:"interval"`
+	LastCheck        time.Time      `json:"lastCheck"`
+	Total            *common.Amount `json:"total"`
+	MaxAllowed       *common.Amount `json:"maxAllowed"`
+	Ratio            float64        `json:"ratio"` // total unwithdrawn earnings / maxAllowedEarnings
+	LastError        string         `json:"lastError,omitempty"`
+}
+
+// EarningsGuard watches the unwithdrawn earnings. Once they pass maxAllowedEarnings anyone may
+// withdraw them on the operator's behalf and take a cut, so when the total reaches the configured
+// percentage of it the guard withdraws from the sponsorships with the most earnings until the
+// total is back under the threshold.
+type EarningsGuard struct {
+	o      *Operator
+	store  *blockchain.Store
+	alerts *Alerter
+	config atomic.Pointer[EarningsGuardConfig]
+	prefix string
+
+	running sync.Mutex // serializes checks, which can wait minutes for a withdrawal to be mined
+	mu      sync.Mutex
+	status  EarningsGuardStatus
+
+	quit chan struct{}
+	wg   sync.WaitGroup
+}
+
+func NewEarningsGuardConfig(p config.GuardPolicy) EarningsGuardConfig {
+	return EarningsGuardConfig{
+		ThresholdPercent: int64(p.ThresholdPercent),
+		Interval:         time.Duration(p.IntervalSeconds) * time.Second,
+		TxTimeout:        5 * time.Minute,
+	}
+}
+
+func NewEarningsGuard(o *Operator, store *blockchain.Store, alerts *Alerter, config EarningsGuardConfig) *EarningsGuard {
+	g := &EarningsGuard{
+		o:      o,
+		store:  store,
+		alerts: alerts,
+		prefix: fmt.Sprintf("guard/%s/", strings.ToLower(o.ContractAddr.Hex())),
+		status: EarningsGuardStatus{
+			Enabled:          config.ThresholdPercent > 0,
+			ThresholdPercent: config.ThresholdPercent,
+			Interval:         config.Interval.String(),
+		},
+		quit: make(chan struct{}),
+	}
+	g.config.Store(&config)
+	return g
+}
+
+func (g *EarningsGuard) Start() {
+	config := g.config.Load()
+	if config.ThresholdPercent <= 0 {
+		log.Printf("Earnings guard is disabled")
+		return
+	}
+
+	g.wg.Add(1)
+	go func() {
+		defer g.wg.Done()
+
+		ticker := time.NewTicker(config.Interval)
+		defer ticker.Stop()
+
+		for {
+			if _, err := g.Check(); err != nil {
+				log.Printf("Earnings guard check failed: %v", err)
+			}
+
+			select {
+			case <-g.quit:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+func (g *EarningsGuard) Stop() {
+	close(g.quit)
+	g.wg.Wait()
+}
+
+// Reload restarts the service with a new configuration once the check in progress is done.
+func (g *EarningsGuard) Reload(config EarningsGuardConfig) {
+	g.Stop()
+	g.config.Store(&config)
+
+	g.mu.Lock()
+	g.status.Enabled = config.ThresholdPercent > 0
+	g.status.ThresholdPercent = config.ThresholdPercent
+	g.status.Interval = config.Interval.String()
+	g.mu.Unlock()
+
+	g.quit = make(chan struct{})
+	g.Start()
+}
+
+func (g *EarningsGuard) Status() EarningsGuardStatus {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.status
+}
+
+func (g *EarningsGuard) updateStatus(fn func(status *EarningsGuardStatus)) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.status.LastCheck = time.Now().UTC()
+	fn(&g.status)
+}
+
+// Check compares the unwithdrawn earnings against maxAllowedEarnings and withdraws if they reached
+// the threshold. It returns the action taken, or nil if the earnings are below the threshold.
+func (g *EarningsGuard) Check() (*EarningsGuardAction, error) {
+	g.running.Lock()
+	defer g.running.Unlock()
+
+	// a threshold of 0 would withdraw every sponsorship with earnings
+	thresholdPercent := g.config.Load().ThresholdPercent
+	if thresholdPercent <= 0 {
+		return nil, ErrGuardDisabled
+	}
+
+	sponsors, err := g.o.GetSponsorshipsAndEarnings()
+	if err != nil {
+		g.updateStatus(func(status *EarningsGuardStatus) { status.LastError = err.Error() })
+		return nil, err
+	}
+
+	total := big.NewInt(0)
+	for _, earnings := range sponsors.Earnings {
+		total.Add(total, earnings.Int())
+	}
+	maxAllowed := sponsors.MaxAllowedEarnings.Int()
+	g.updateStatus(func(status *EarningsGuardStatus) {
+		status.LastError = ""
+		status.Total = common.NewAmount(total)
+		status.MaxAllowed = common.NewAmount(maxAllowed)
+		status.Ratio = 0
+		if maxAllowed.Sign() > 0 {
+			status.Ratio = ratio(total, maxAllowed)
+		}
+	})
+
+	threshold := new(big.Int).Mul(maxAllowed, big.NewInt(thresholdPercent))
+	threshold.Div(threshold, big.NewInt(100))
+	if maxAllowed.Sign() == 0 || total.Cmp(threshold) < 0 {
+		return nil, nil
+	}
+
+	selected, withdrawn := selectOverThreshold(sponsors.Addresses, sponsors.Earnings, total, threshold)
+	if len(selected) == 0 {
+		return nil, nil
+	}
+	action := EarningsGuardAction{
+		Timestamp:    time.Now().UTC(),
+		Total:        common.NewAmount(total),
+		MaxAllowed:   common.NewAmount(maxAllowed),
+		Threshold:    common.NewAmount(threshold),
+		Sponsorships: selected,
+		Withdrawn:    common.NewAmount(withdrawn),
+	}
+
+	txHash, err := g.o.WithdrawEarningsFrom(selected)
+	if err == nil {
+		action.TxHash = txHash
+		// a reverted withdrawal leaves the earnings in place and fails the action
+		err = g.o.waitMined(txHash, g.config.Load().TxTimeout)
+	}
+	if err != nil {
+		action.Error = err.Error()
+		g.updateStatus(func(status *EarningsGuardStatus) { status.LastError = err.Error() })
+		g.alerts.Alert(AlertCritical, guardAlertSource,
+			"earnings of %s DATA reached %d%% of maxAllowedEarnings (%s DATA) and the withdrawal from %d sponsorship(s) failed: %v",
+			action.Total.DATA(), thresholdPercent, action.MaxAllowed.DATA(), len(selected), err)
+	} else {
+		g.alerts.Alert(AlertWarning, guardAlertSource,
+			"earnings of %s DATA reached %d%% of maxAllowedEarnings (%s DATA), withdrew %s DATA from %d sponsorship(s) in %s",
+			action.Total.DATA(), thresholdPercent, action.MaxAllowed.DATA(), action.Withdrawn.DATA(), len(selected), txHash)
+	}
+
+	if storeErr := g.store.Put(fmt.Sprintf("%s%020d", g.prefix, action.Timestamp.UnixNano()), action); storeErr != nil {
+		log.Printf("Failed to record earnings guard action: %v", storeErr)
+	}
+	return &action, err
+}
+
+// Actions returns the withdrawals sent by the guard in [from, to], oldest first. Zero times mean no bound.
+func (g *EarningsGuard) Actions(from time.Time, to time.Time) ([]EarningsGuardAction, error) {
+	actions := []EarningsGuardAction{}
+	err := g.store.ForEach(g.prefix, func(key string, value []byte) error {
+		var action EarningsGuardAction
+		if err := json.Unmarshal(value, &action); err != nil {
+			return err
+		}
+		if inRange(action.Timestamp, from, to) {
+			actions = append(actions, action)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return actions, nil
+}
+
+// selectOverThreshold picks the sponsorships with the most earnings until the earnings left behind
+// are below threshold, and returns them with the amount they hold.
+func selectOverThreshold(addresses []ethcommon.Address, earnings []*common.Amount, total *big.Int, threshold *big.Int) ([]ethcommon.Address, *big.Int) {
+	order := make([]int, len(addresses))
+	for i := range order {
+		order[i] = i
+	}
+	sort.Slice(order, func(a, b int) bool {
+		return earnings[order[a]].Int().Cmp(earnings[order[b]].Int()) > 0
+	})
+
+	selected := []ethcommon.Address{}
+	withdrawn := big.NewInt(0)
+	remaining := new(big.Int).Set(total)
+	for _, i := range order {
+		if remaining.Cmp(threshold) < 0 || earnings[i].Int().Sign() == 0 {
+			break
+		}
+		selected = append(selected, addresses[i])
+		withdrawn.Add(withdrawn, earnings[i].Int())
+		remaining.Sub(remaining, earnings[i].Int())
+	}
+	return selected, withdrawn
+}
@@ -0,0 +1,185 @@
+package models
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"streamr_api/blockchain"
+)
+
+// healthProbeKey is written and deleted again to check that the database is writable.
+const healthProbeKey = "health/probe"
+
+// HealthCheck is the result of one readiness check.
+type HealthCheck struct {
+	Name  string `json:"name"`
+	OK    bool   `json:"ok"`
+	Error string `json:"error,omitempty"`
+}
+
+// Readiness tells whether the service can serve requests. Ready is false if any check failed.
+type Readiness struct {
+	Ready  bool          `json:"ready"`
+	Checks []HealthCheck `json:"checks"`
+}
+
+// OperatorDiagnostics is the chain and transaction state of one operator. Error is set when the
+// node couldn't be read, and the fields read from it are missing.
+type OperatorDiagnostics struct {
+	Name            string                 `json:"name"`
+	BlockNumber     uint64                 `json:"blockNumber,omitempty"`
+	BlockTime       *time.Time             `json:"blockTime,omitempty"`
+	BlockLagSeconds float64                `json:"blockLagSeconds,omitempty"` // wall clock minus the latest block time
+	Nonce           *blockchain.NonceState `json:"nonce,omitempty"`
+	PendingTxs      int                    `json:"pendingTxs"`
+	Error           string                 `json:"error,omitempty"`
+}
+
+// Diagnostics is the state of the service for troubleshooting.
+type Diagnostics struct {
+	Version   string                `json:"version"`
+	Timestamp time.Time             `json:"timestamp"`
+	Operators []OperatorDiagnostics `json:"operators"`
+	CronJobs  map[string]CronJobRun `json:"cronJobs"` // by job ID
+}
+
+// CheckReadiness checks every operator's RPC endpoint, chain ID, ABI and signing key, the cron
+// scheduler and the database. The service isn't ready either once it is shutting down.
+func CheckReadiness(r *Registry, s *Scheduler, l *Lifecycle) Readiness {
+	operators := r.Operators()
+	results := make([][]HealthCheck, len(operators))
+	var wg sync.WaitGroup
+	for i, o := range operators {
+		wg.Add(1)
+		go func(i int, o *Operator) {
+			defer wg.Done()
+			results[i] = o.readinessChecks()
+		}(i, o)
+	}
+	wg.Wait()
+
+	readiness := Readiness{Ready: true, Checks: []HealthCheck{}}
+	for _, checks := range results {
+		readiness.Checks = append(readiness.Checks, checks...)
+	}
+
+	scheduler := HealthCheck{Name: "scheduler", OK: s != nil && s.Running()}
+	if !scheduler.OK {
+		scheduler.Error = "the cron scheduler is not running"
+	}
+	readiness.Checks = append(readiness.Checks, scheduler, checkStorage(r.Default().store))
+	if l != nil && l.Draining() {
+		readiness.Checks = append(readiness.Checks, HealthCheck{Name: "lifecycle", Error: "the service is shutting down"})
+	}
+
+	for _, check := range readiness.Checks {
+		if !check.OK {
+			readiness.Ready = false
+		}
+	}
+	return readiness
+}
+
+func (o *Operator) readinessChecks() []HealthCheck {
+	check := func(name string, err error) HealthCheck {
+		result := HealthCheck{Name: o.Name + "/" + name, OK: err == nil}
+		if err != nil {
+			result.Error = err.Error()
+		}
+		return result
+	}
+
+	chainID, err := o.TxManager.ChainID()
+	checks := []HealthCheck{check("rpc", err)}
+	chainErr := err
+	if err == nil {
+		if expected := o.config.RPC.ChainID; expected != 0 && chainID.Uint64() != expected {
+			chainErr = fmt.Errorf("the RPC is on chain %s, not on chain %d", chainID, expected)
+		}
+	}
+	checks = append(checks, check("chainId", chainErr))
+
+	var abiErr error
+	if len(o.ContractAbi.Methods) == 0 {
+		abiErr = fmt.Errorf("the operator contract ABI is not loaded")
+	}
+	checks = append(checks, check("abi", abiErr))
+
+	var signerErr error
+	if o.PrivateKey == nil || o.Signer == nil {
+		signerErr = fmt.Errorf("no signing key")
+	}
+	checks = append(checks, check("signer", signerErr))
+	return checks
+}
+
+func checkStorage(store *blockchain.Store) HealthCheck {
+	result := HealthCheck{Name: "storage", OK: true}
+	err := fmt.Errorf("the database is not open")
+	if store != nil {
+		err = store.Put(healthProbeKey, time.Now().UTC())
+		if err == nil {
+			err = store.Delete(healthProbeKey)
+		}
+	}
+	if err != nil {
+		result.OK = false
+		result.Error = err.Error()
+	}
+	return result
+}
+
+// Diagnose reads the chain and transaction state of every operator concurrently.
+func Diagnose(r *Registry, s *Scheduler, version string) Diagnostics {
+	operators := r.Operators()
+	diagnostics := Diagnostics{
+		Version:   version,
+		Timestamp: time.Now().UTC(),
+		Operators: make([]OperatorDiagnostics, len(operators)),
+		CronJobs:  map[string]CronJobRun{},
+	}
+	var wg sync.WaitGroup
+	for i, o := range operators {
+		wg.Add(1)
+		go func(i int, o *Operator) {
+			defer wg.Done()
+			diagnostics.Operators[i] = o.diagnose()
+		}(i, o)
+	}
+	wg.Wait()
+
+	if s != nil {
+		diagnostics.CronJobs = s.LastRuns()
+	}
+	return diagnostics
+}
+
+func (o *Operator) diagnose() OperatorDiagnostics {
+	diagnostics := OperatorDiagnostics{Name: o.Name}
+	if journal := o.TxManager.Journal(); journal != nil {
+		pending, err := journal.Pending()
+		if err != nil {
+			diagnostics.Error = err.Error()
+			return diagnostics
+		}
+		diagnostics.PendingTxs = len(pending)
+	}
+
+	header, err := o.TxManager.LatestHeader()
+	if err != nil {
+		diagnostics.Error = err.Error()
+		return diagnostics
+	}
+	blockTime := time.Unix(int64(header.Time), 0).UTC()
+	diagnostics.BlockNumber = header.Number.Uint64()
+	diagnostics.BlockTime = &blockTime
+	diagnostics.BlockLagSeconds = time.Since(blockTime).Seconds()
+
+	nonce, err := o.TxManager.NonceState()
+	diagnostics.Nonce = &nonce
+	if err != nil {
+		diagnostics.Error = err.Error()
+	}
+	return diagnostics
+}
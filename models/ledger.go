@@ -0,0 +1,264 @@
+package models
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"streamr_api/blockchain"
+	"streamr_api/common"
+
+	ethcommon "github.com/ethereum/go-ethereum/common"
+)
+
+const (
+	LedgerStake    = "stake"
+	LedgerUnstake  = "unstake"
+	LedgerWithdraw = "withdraw"
+	LedgerPayout   = "payout"
+)
+
+// ledgerActions maps the operator contract methods sent through the TxManager to ledger actions.
+var ledgerActions = map[string]string{
+	"stake":                            LedgerStake,
+	"reduceStakeTo":                    LedgerUnstake,
+	"unstake":                          LedgerUnstake,
+	"forceUnstake":                     LedgerUnstake,
+	"withdrawEarningsFromSponsorships": LedgerWithdraw,
+	"payOutQueue":                      LedgerPayout,
+}
+
+// The column layout follows the universal import format of common crypto tax tools.
+var ledgerCSVHeader = []string{
+	"Date", "Type", "Sent Amount", "Sent Currency", "Received Amount", "Received Currency",
+	"Fee Amount", "Fee Currency", "Label", "Description", "TxHash", "Block", "Counterparty", "Status",
+}
+
+// LedgerRow is a single on-chain action of the operator. Amounts are in DATA and gas costs in POL,
+// both with 18 decimals.
+type LedgerRow struct {
+	Timestamp    time.Time      `json:"timestamp"`
+	Block        uint64         `json:"block"`
+	TxHash       string         `json:"txHash"`
+	Action       string         `json:"action"`
+	Counterparty string         `json:"counterparty"` // the sponsorship(s), or the delegator for payouts
+	Amount       *common.Amount `json:"amount"`
+	ProtocolFee  *common.Amount `json:"protocolFee,omitempty"`
+	GasCost      *common.Amount `json:"gasCost"`
+	Status       string         `json:"status"`
+}
+
+// Ledger builds the operator's ledger for [from, to] from the store. Zero times mean no bound.
+func (o *Operator) Ledger(from time.Time, to time.Time) ([]LedgerRow, error) {
+	if o.store == nil {
+		return nil, errors.New("operator services are not running")
+	}
+	return BuildLedger(o.store, o.ContractAddr, from, to)
+}
+
+// BuildLedger builds the ledger of every stake, unstake, earnings withdrawal and queue payout of an
+// operator from its indexed events, with the gas costs taken from the tx journal. Transactions in
+// the journal that left no event (e.g. failed ones) are listed with their gas cost only.
+func BuildLedger(store *blockchain.Store, operatorAddr ethcommon.Address, from time.Time, to time.Time) ([]LedgerRow, error) {
+	events, err := blockchain.ListEvents(store, operatorAddr, blockchain.EventFilter{})
+	if err != nil {
+		return nil, err
+	}
+	entries, err := blockchain.NewTxJournal(store, operatorAddr).List(time.Time{}, time.Time{})
+	if err != nil {
+		return nil, err
+	}
+
+	// withdrawals only name the sponsorships through the events the sponsorships emit in the same tx
+	sponsorshipsByTx := make(map[ethcommon.Hash][]string)
+	for _, event := range events {
+		if event.Contract != operatorAddr && event.Name == "StakeUpdate" {
+			sponsorshipsByTx[event.TxHash] = append(sponsorshipsByTx[event.TxHash], event.Contract.Hex())
+		}
+	}
+
+	rows := []LedgerRow{}
+	stakes := make(map[string]*big.Int)
+	for _, event := range events {
+		if event.Contract != operatorAddr {
+			continue
+		}
+
+		switch event.Name {
+		case "StakeUpdate":
+			// the event carries the new stake, the ledger needs the change
+			sponsorship := ethcommon.HexToAddress(event.Args["sponsorship"]).Hex()
+			staked := eventAmount(event, "stakedWei")
+			previous, ok := stakes[sponsorship]
+			if !ok {
+				previous = big.NewInt(0)
+			}
+			stakes[sponsorship] = staked
+
+			delta := new(big.Int).Sub(staked, previous)
+			if delta.Sign() == 0 {
+				continue
+			}
+			action := LedgerStake
+			if delta.Sign() < 0 {
+				action = LedgerUnstake
+			}
+			rows = append(rows, eventRow(event, action, sponsorship, delta.Abs(delta)))
+		case "Profit":
+			// the protocol fee never reaches the operator contract
+			received := new(big.Int).Add(eventAmount(event, "valueIncreaseWei"), eventAmount(event, "operatorsCutDataWei"))
+			row := eventRow(event, LedgerWithdraw, strings.Join(sponsorshipsByTx[event.TxHash], ";"), received)
+			row.ProtocolFee = common.NewAmount(eventAmount(event, "protocolFeeDataWei"))
+			rows = append(rows, row)
+		case "Undelegated":
+			rows = append(rows, eventRow(event, LedgerPayout, ethcommon.HexToAddress(event.Args["delegator"]).Hex(), eventAmount(event, "amountDataWei", "amountWei")))
+		}
+	}
+
+	txRows := make(map[string]bool)
+	for _, row := range rows {
+		txRows[strings.ToLower(row.TxHash)] = true
+	}
+	for _, entry := range entries {
+		action, ok := ledgerActions[entry.Method]
+		if !ok || txRows[strings.ToLower(entry.Hash)] || entry.Status == blockchain.TxPending || entry.Status == blockchain.TxDropped {
+			continue
+		}
+
+		row := LedgerRow{
+			Timestamp: entry.MinedAt,
+			Block:     entry.BlockNumber,
+			TxHash:    entry.Hash,
+			Action:    action,
+			Amount:    common.NewAmount(nil),
+			Status:    entry.Status,
+		}
+		if row.Timestamp.IsZero() {
+			row.Timestamp = entry.SentAt
+		}
+		if len(entry.Params) > 0 && action != LedgerPayout {
+			row.Counterparty = strings.ReplaceAll(entry.Params[0], ",", ";")
+		}
+		if action == LedgerStake && entry.Status == blockchain.TxMined && len(entry.Params) > 1 {
+			if amount, ok := new(big.Int).SetString(entry.Params[1], 10); ok {
+				row.Amount = common.NewAmount(amount)
+			}
+		}
+		rows = append(rows, row)
+	}
+
+	sort.SliceStable(rows, func(i, j int) bool {
+		if rows[i].Block != rows[j].Block {
+			return rows[i].Block < rows[j].Block
+		}
+		return rows[i].Timestamp.Before(rows[j].Timestamp)
+	})
+
+	// the gas of a transaction is booked on its first row
+	gasByTx := make(map[string]*big.Int)
+	for _, entry := range entries {
+		if entry.GasCostWei != nil {
+			gasByTx[strings.ToLower(entry.Hash)] = entry.GasCostWei
+		}
+	}
+
+	ledger := []LedgerRow{}
+	for _, row := range rows {
+		row.GasCost = common.NewAmount(nil)
+		if gas, ok := gasByTx[strings.ToLower(row.TxHash)]; ok {
+			row.GasCost = common.NewAmount(gas)
+			delete(gasByTx, strings.ToLower(row.TxHash))
+		}
+
+		if inRange(row.Timestamp, from, to) {
+			ledger = append(ledger, row)
+		}
+	}
+	return ledger, nil
+}
+
+// WriteLedgerCSV writes the ledger in a column layout most crypto tax tools can import.
+func WriteLedgerCSV(w io.Writer, rows []LedgerRow) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(ledgerCSVHeader); err != nil {
+		return err
+	}
+
+	for _, row := range rows {
+		amount := common.FormatUnits(row.Amount.Int(), 18)
+		var sent, received, label string
+		switch row.Action {
+		case LedgerStake:
+			sent, label = amount, "stake"
+		case LedgerUnstake:
+			received, label = amount, "unstake"
+		case LedgerWithdraw:
+			received, label = amount, "reward"
+		case LedgerPayout:
+			sent = amount
+		}
+		if row.Amount.Int().Sign() == 0 {
+			sent, received = "", ""
+		}
+		sentCurrency, receivedCurrency := "", ""
+		if sent != "" {
+			sentCurrency = "DATA"
+		}
+		if received != "" {
+			receivedCurrency = "DATA"
+		}
+
+		description := fmt.Sprintf("Streamr operator %s", row.Action)
+		if row.ProtocolFee != nil && row.ProtocolFee.Int().Sign() > 0 {
+			description += fmt.Sprintf(" (protocol fee %s DATA)", common.FormatUnits(row.ProtocolFee.Int(), 18))
+		}
+
+		err := writer.Write([]string{
+			row.Timestamp.UTC().Format("2006-01-02 15:04:05 UTC"),
+			row.Action,
+			sent, sentCurrency,
+			received, receivedCurrency,
+			common.FormatUnits(row.GasCost.Int(), 18), "POL",
+			label,
+			description,
+			row.TxHash,
+			strconv.FormatUint(row.Block, 10),
+			row.Counterparty,
+			row.Status,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+func eventRow(event blockchain.IndexedEvent, action string, counterparty string, amount *big.Int) LedgerRow {
+	return LedgerRow{
+		Timestamp:    time.Unix(int64(event.Timestamp), 0).UTC(),
+		Block:        event.BlockNumber,
+		TxHash:       event.TxHash.Hex(),
+		Action:       action,
+		Counterparty: counterparty,
+		Amount:       common.NewAmount(amount),
+		Status:       blockchain.TxMined,
+	}
+}
+
+// eventAmount returns the first of the named integer arguments present in the event, or zero.
+func eventAmount(event blockchain.IndexedEvent, names ...string) *big.Int {
+	for _, name := range names {
+		if value, ok := new(big.Int).SetString(event.Args[name], 10); ok {
+			return value
+		}
+	}
+	return big.NewInt(0)
+}
@@ -0,0 +1,113 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"streamr_api/blockchain"
+)
+
+// Lifecycle serves the API until SIGTERM or SIGINT and then shuts the service down without cutting
+// off the work in flight: it refuses new mutating requests, lets the cron jobs and background
+// services finish their run, waits for the requests being served and gives the transactions sent
+// the rest of the timeout to be mined. The database is closed last, and only if nothing that may
+// still write to it is left running.
+type Lifecycle struct {
+	registry  *Registry
+	scheduler *Scheduler
+	store     *blockchain.Store
+	timeout   time.Duration
+
+	draining atomic.Bool
+}
+
+func NewLifecycle(r *Registry, s *Scheduler, store *blockchain.Store, timeout time.Duration) *Lifecycle {
+	return &Lifecycle{registry: r, scheduler: s, store: store, timeout: timeout}
+}
+
+// Draining reports whether the service is shutting down and refuses mutating requests.
+func (l *Lifecycle) Draining() bool {
+	return l.draining.Load()
+}
+
+// Serve serves handler on addr until a termination signal, then shuts down. If the server fails,
+// it shuts down as well and returns the error.
+func (l *Lifecycle) Serve(handler http.Handler, addr string) error {
+	server := &http.Server{Addr: addr, Handler: handler}
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- server.ListenAndServe()
+	}()
+
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, syscall.SIGTERM, syscall.SIGINT)
+	defer signal.Stop(stop)
+
+	var err error
+	select {
+	case err = <-serveErr:
+		log.Printf("Server failed, shutting down within %s: %v", l.timeout, err)
+	case sig := <-stop:
+		log.Printf("Received %s, shutting down within %s", sig, l.timeout)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), l.timeout)
+	defer cancel()
+	l.shutdown(ctx, server)
+	return err
+}
+
+func (l *Lifecycle) shutdown(ctx context.Context, server *http.Server) {
+	l.draining.Store(true)
+	clean := true // nothing writing to the database is left running
+
+	// the jobs call the API, so it keeps serving until they are done
+	if err := l.scheduler.Stop(ctx); err != nil {
+		log.Printf("Shutdown: cron jobs still running: %v", err)
+	}
+
+	stopped := make(chan struct{})
+	go func() {
+		l.registry.StopServices()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		clean = false
+		log.Printf("Shutdown: background services still running: %v", ctx.Err())
+	}
+
+	if err := server.Shutdown(ctx); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		clean = false
+		log.Printf("Shutdown: requests still being served: %v", err)
+	}
+
+	for _, o := range l.registry.Operators() {
+		pending, err := o.TxManager.CloseJournal(ctx)
+		if err != nil {
+			log.Printf("Shutdown: failed to reconcile the journal of operator %s: %v", o.Name, err)
+			continue
+		}
+		for _, entry := range pending {
+			log.Printf("Shutdown: operator %s transaction %s (%s, nonce %d) is still pending", o.Name, entry.Hash, entry.Method, entry.Nonce)
+		}
+	}
+
+	if !clean {
+		log.Printf("Shutdown: leaving the database open as work is still running")
+		return
+	}
+	if err := l.store.Close(); err != nil {
+		log.Printf("Shutdown: failed to close the database: %v", err)
+		return
+	}
+	log.Printf("Shutdown complete")
+}
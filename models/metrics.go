@@ -0,0 +1,158 @@
+package models
+
+import (
+	"log"
+	"math/big"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"streamr_api/config"
+	"streamr_api/metrics"
+)
+
+type MetricsConfig struct {
+	Interval time.Duration
+}
+
+// MetricsCollector refreshes the on-chain gauges in the background, so a Prometheus scrape
+// only reads the last known values and never waits on the RPC.
+type MetricsCollector struct {
+	o      *Operator
+	config atomic.Pointer[MetricsConfig]
+
+	mu           sync.Mutex
+	sponsorships map[string]bool
+
+	quit chan struct{}
+	wg   sync.WaitGroup
+}
+
+func NewMetricsConfig(p config.MetricsPolicy) MetricsConfig {
+	return MetricsConfig{
+		Interval: time.Duration(p.IntervalSeconds) * time.Second,
+	}
+}
+
+func NewMetricsCollector(o *Operator, config MetricsConfig) *MetricsCollector {
+	m := &MetricsCollector{
+		o:            o,
+		sponsorships: make(map[string]bool),
+		quit:         make(chan struct{}),
+	}
+	m.config.Store(&config)
+	return m
+}
+
+func (m *MetricsCollector) Start() {
+	m.wg.Add(1)
+	go func() {
+		defer m.wg.Done()
+
+		ticker := time.NewTicker(m.config.Load().Interval)
+		defer ticker.Stop()
+
+		for {
+			m.Collect()
+
+			select {
+			case <-m.quit:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+func (m *MetricsCollector) Stop() {
+	close(m.quit)
+	m.wg.Wait()
+}
+
+// Reload restarts the service with a new configuration once the refresh in progress is done.
+func (m *MetricsCollector) Reload(config MetricsConfig) {
+	m.Stop()
+	m.config.Store(&config)
+	m.quit = make(chan struct{})
+	m.Start()
+}
+
+// Collect reads the operator's state from the chain and updates the gauges. A failed read leaves
+// the affected gauges at their previous value.
+func (m *MetricsCollector) Collect() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	ok := true
+	fail := func(what string, err error) {
+		log.Printf("Failed to collect %s metrics: %v", what, err)
+		metrics.CollectorErrors.WithLabelValues(m.o.Name).Inc()
+		ok = false
+	}
+
+	if value, err := m.o.GetValueWithoutEarnings(); err != nil {
+		fail("operator value", err)
+	} else {
+		metrics.ValueWithoutEarnings.WithLabelValues(m.o.Name).Set(metrics.Float(value))
+	}
+
+	if deployed, err := m.o.GetDeployedStake(); err != nil {
+		fail("deployed stake", err)
+	} else {
+		metrics.TotalDeployedStake.WithLabelValues(m.o.Name).Set(metrics.Float(deployed.TotalDeployed.Int()))
+
+		// drop the series of sponsorships the operator has left
+		current := make(map[string]bool)
+		for addr, stake := range deployed.DeployedBySponsorship {
+			current[addr.Hex()] = true
+			metrics.SponsorshipStake.WithLabelValues(m.o.Name, addr.Hex()).Set(metrics.Float(stake.Int()))
+		}
+		for addr := range m.sponsorships {
+			if !current[addr] {
+				metrics.SponsorshipStake.DeleteLabelValues(m.o.Name, addr)
+				metrics.SponsorshipEarnings.DeleteLabelValues(m.o.Name, addr)
+			}
+		}
+		m.sponsorships = current
+	}
+
+	if sponsors, err := m.o.GetSponsorshipsAndEarnings(); err != nil {
+		fail("earnings", err)
+	} else {
+		total := big.NewInt(0)
+		for i, addr := range sponsors.Addresses {
+			metrics.SponsorshipEarnings.WithLabelValues(m.o.Name, addr.Hex()).Set(metrics.Float(sponsors.Earnings[i].Int()))
+			total.Add(total, sponsors.Earnings[i].Int())
+		}
+		if sponsors.MaxAllowedEarnings.Int().Sign() > 0 {
+			metrics.EarningsRatio.WithLabelValues(m.o.Name).Set(ratio(total, sponsors.MaxAllowedEarnings.Int()))
+		}
+	}
+
+	if queue, err := m.o.GetUndelegationQueue(); err != nil {
+		fail("undelegation queue", err)
+	} else {
+		length := 0
+		total := big.NewInt(0)
+		for _, records := range queue {
+			for _, record := range records {
+				length++
+				if record.Amount != nil {
+					total.Add(total, record.Amount.Int())
+				}
+			}
+		}
+		metrics.UndelegationQueueLength.WithLabelValues(m.o.Name).Set(float64(length))
+		metrics.UndelegationQueueTotal.WithLabelValues(m.o.Name).Set(metrics.Float(total))
+	}
+
+	if balance, err := m.o.TxManager.BalanceAt(m.o.OwnerAddr); err != nil {
+		fail("owner balance", err)
+	} else {
+		metrics.OwnerBalance.WithLabelValues(m.o.Name).Set(metrics.Float(balance))
+	}
+
+	if ok {
+		metrics.CollectorLastSuccess.WithLabelValues(m.o.Name).SetToCurrentTime()
+	}
+}
@@ -0,0 +1,391 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"streamr_api/blockchain"
+
+	ethcommon "github.com/ethereum/go-ethereum/common"
+)
+
+// ErrNoChange is returned when an update would leave the on-chain values as they are.
+var ErrNoChange = errors.New("nothing to change, the values are already on chain")
+
+// ErrMetadataUnknown is returned when the current metadata can't be known for sure. Updates publish
+// the whole JSON, so one made from incomplete metadata would wipe the fields it left out.
+var ErrMetadataUnknown = errors.New("the current metadata is unknown")
+
+// ErrIndexerBehind is returned when the indexed events may not include the latest ones yet.
+var ErrIndexerBehind = errors.New("event indexer is still catching up")
+
+// Metadata fields the API manages. Other fields in the metadata JSON are kept as they are.
+const (
+	MetadataName             = "name"
+	MetadataDescription      = "description"
+	MetadataImageURL         = "imageUrl"
+	MetadataRedundancyFactor = "redundancyFactor"
+)
+
+// NodeInfo is a node address of the operator and the last heartbeat the node published.
+type NodeInfo struct {
+	Address       ethcommon.Address `json:"address"`
+	LastHeartbeat *time.Time        `json:"lastHeartbeat,omitempty"`
+	Heartbeat     string            `json:"heartbeat,omitempty"` // JSON the node sent with it
+}
+
+// NodeAddressChange shows a change of node addresses. Tx is empty for dry runs.
+type NodeAddressChange struct {
+	Current  []ethcommon.Address `json:"current"`
+	Proposed []ethcommon.Address `json:"proposed"`
+	Added    []ethcommon.Address `json:"added"`
+	Removed  []ethcommon.Address `json:"removed"`
+	Tx       string              `json:"tx,omitempty"`
+}
+
+// OperatorMetadata is the metadata JSON published by the operator, as last set on chain.
+type OperatorMetadata struct {
+	Fields    map[string]interface{} `json:"fields"`
+	Raw       string                 `json:"raw"`
+	UpdatedAt *time.Time             `json:"updatedAt,omitempty"`
+	Tx        string                 `json:"tx,omitempty"` // transaction that set it
+}
+
+// MetadataUpdate sets metadata fields. Fields left out keep their current value.
+type MetadataUpdate struct {
+	Name             *string `json:"name,omitempty"`
+	Description      *string `json:"description,omitempty"`
+	ImageURL         *string `json:"imageUrl,omitempty"`
+	RedundancyFactor *int    `json:"redundancyFactor,omitempty"`
+}
+
+// FieldChange is one field that differs between the current and the proposed metadata.
+type FieldChange struct {
+	Field    string      `json:"field"`
+	Current  interface{} `json:"current"`
+	Proposed interface{} `json:"proposed"`
+}
+
+// MetadataChange shows a metadata update and the exact JSON that is sent. Tx is empty for dry runs.
+type MetadataChange struct {
+	Current  OperatorMetadata `json:"current"`
+	Proposed string           `json:"proposed"`
+	Diff     []FieldChange    `json:"diff"`
+	Tx       string           `json:"tx,omitempty"`
+}
+
+// GetNodeAddresses reads the node addresses from the operator contract and, when events are
+// indexed, the last heartbeat of each node.
+func (o *Operator) GetNodeAddresses() ([]NodeInfo, error) {
+	addresses, err := o.nodeAddresses()
+	if err != nil {
+		return nil, err
+	}
+
+	nodes := make([]NodeInfo, len(addresses))
+	index := make(map[ethcommon.Address]int)
+	for i, addr := range addresses {
+		nodes[i] = NodeInfo{Address: addr}
+		index[addr] = i
+	}
+	if o.Indexer == nil {
+		return nodes, nil
+	}
+
+	events, err := o.Indexer.Events(blockchain.EventFilter{Names: []string{"Heartbeat"}, Contract: o.ContractAddr})
+	if err != nil {
+		return nil, err
+	}
+	for _, event := range events {
+		i, ok := index[ethcommon.HexToAddress(event.Args["nodeAddress"])]
+		if !ok {
+			continue
+		}
+		// events come in chain order, so the last one wins
+		at := time.Unix(int64(event.Timestamp), 0).UTC()
+		nodes[i].LastHeartbeat = &at
+		nodes[i].Heartbeat = event.Args["jsonData"]
+	}
+	return nodes, nil
+}
+
+// SetNodeAddresses replaces the node addresses of the operator. With dryRun only the change is
+// worked out. Otherwise it waits for the transaction to be mined.
+func (o *Operator) SetNodeAddresses(addresses []ethcommon.Address, dryRun bool) (NodeAddressChange, error) {
+	change := NodeAddressChange{Proposed: addresses, Added: []ethcommon.Address{}, Removed: []ethcommon.Address{}}
+	if err := o.ValidateNodeAddresses(addresses); err != nil {
+		return change, err
+	}
+	seen := make(map[ethcommon.Address]bool)
+	for _, addr := range addresses {
+		seen[addr] = true
+	}
+
+	current, err := o.nodeAddresses()
+	if err != nil {
+		return change, err
+	}
+	change.Current = current
+
+	existing := make(map[ethcommon.Address]bool)
+	for _, addr := range current {
+		existing[addr] = true
+		if !seen[addr] {
+			change.Removed = append(change.Removed, addr)
+		}
+	}
+	for _, addr := range addresses {
+		if !existing[addr] {
+			change.Added = append(change.Added, addr)
+		}
+	}
+	if len(change.Added) == 0 && len(change.Removed) == 0 {
+		return change, ErrNoChange
+	}
+	if dryRun {
+		return change, nil
+	}
+
+	tx, err := o.TxManager.ContractSendTx("setNodeAddresses", []interface{}{addresses})
+	if err != nil {
+		return change, err
+	}
+	change.Tx = tx
+	return change, o.waitMined(tx, 2*time.Minute)
+}
+
+// ValidateNodeAddresses checks a new set of node addresses.
+func (o *Operator) ValidateNodeAddresses(addresses []ethcommon.Address) error {
+	seen := make(map[ethcommon.Address]bool)
+	for _, addr := range addresses {
+		if addr == (ethcommon.Address{}) {
+			return errors.New("the zero address can't be a node address")
+		}
+		if addr == o.ContractAddr {
+			return errors.New("the operator contract can't be a node address")
+		}
+		if seen[addr] {
+			return fmt.Errorf("duplicate node address %s", addr.Hex())
+		}
+		seen[addr] = true
+	}
+	return nil
+}
+
+// GetMetadata returns the metadata JSON last set by updateMetadata. It is read from the contract if
+// the contract keeps it, otherwise from the last indexed MetadataUpdated event.
+func (o *Operator) GetMetadata() (OperatorMetadata, error) {
+	metadata := OperatorMetadata{Fields: map[string]interface{}{}}
+	if raw, ok, err := o.contractMetadata(); err != nil {
+		return metadata, err
+	} else if ok {
+		metadata.Raw = raw
+		metadata.parse()
+		return metadata, nil
+	}
+	if o.Indexer == nil {
+		return metadata, blockchain.ErrIndexerDisabled
+	}
+
+	events, err := o.Indexer.Events(blockchain.EventFilter{Names: []string{"MetadataUpdated"}, Contract: o.ContractAddr})
+	if err != nil {
+		return metadata, err
+	}
+	if len(events) == 0 {
+		return metadata, nil
+	}
+
+	event := events[len(events)-1]
+	at := time.Unix(int64(event.Timestamp), 0).UTC()
+	metadata.Raw = event.Args["metadataJsonString"]
+	metadata.UpdatedAt = &at
+	metadata.Tx = event.TxHash.Hex()
+	metadata.parse()
+	return metadata, nil
+}
+
+// contractMetadata reads the metadata from the contract, if its ABI has a metadata() getter.
+func (o *Operator) contractMetadata() (string, bool, error) {
+	method, ok := o.ContractAbi.Methods["metadata"]
+	if !ok || len(method.Inputs) != 0 || len(method.Outputs) != 1 || method.Outputs[0].Type.String() != "string" {
+		return "", false, nil
+	}
+	result, err := o.TxManager.ContractCall("metadata", nil)
+	if err != nil {
+		return "", false, err
+	}
+	raw, ok := result[0].(string)
+	if !ok {
+		return "", false, fmt.Errorf("unexpected metadata result: %v", result[0])
+	}
+	return raw, true, nil
+}
+
+// parse fills in Fields from Raw and fails if Raw isn't a JSON object. Fields stay empty then, and
+// the raw value stays visible.
+func (m *OperatorMetadata) parse() error {
+	m.Fields = map[string]interface{}{}
+	if strings.TrimSpace(m.Raw) == "" {
+		return nil
+	}
+	fields := map[string]interface{}{}
+	if err := json.Unmarshal([]byte(m.Raw), &fields); err != nil {
+		return err
+	}
+	m.Fields = fields
+	return nil
+}
+
+// metadataForUpdate returns the current metadata only if it is complete: read from the contract, or
+// from the latest MetadataUpdated event once the indexer has caught up, and valid JSON.
+func (o *Operator) metadataForUpdate() (OperatorMetadata, error) {
+	metadata := OperatorMetadata{Fields: map[string]interface{}{}}
+	raw, fromContract, err := o.contractMetadata()
+	if err != nil {
+		return metadata, err
+	}
+	if fromContract {
+		metadata.Raw = raw
+	} else {
+		if o.Indexer == nil {
+			return metadata, blockchain.ErrIndexerDisabled
+		}
+		if !o.Indexer.CaughtUp() {
+			return metadata, ErrIndexerBehind
+		}
+		if metadata, err = o.GetMetadata(); err != nil {
+			return metadata, err
+		}
+		if metadata.UpdatedAt == nil {
+			return metadata, fmt.Errorf("%w: no MetadataUpdated event was indexed, index from the block the operator was deployed in", ErrMetadataUnknown)
+		}
+	}
+
+	if err := metadata.parse(); err != nil {
+		return metadata, fmt.Errorf("%w: the metadata on chain is not a JSON object: %v", ErrMetadataUnknown, err)
+	}
+	return metadata, nil
+}
+
+// UpdateMetadata applies the update to the current metadata and publishes the result. It is refused
+// while the current metadata isn't known for sure. With dryRun only the change is worked out,
+// otherwise it waits for the transaction to be mined.
+func (o *Operator) UpdateMetadata(update MetadataUpdate, dryRun bool) (MetadataChange, error) {
+	change := MetadataChange{Diff: []FieldChange{}}
+	if err := update.Validate(); err != nil {
+		return change, err
+	}
+
+	current, err := o.metadataForUpdate()
+	change.Current = current
+	if err != nil {
+		return change, err
+	}
+
+	proposed := make(map[string]interface{}, len(current.Fields))
+	for key, value := range current.Fields {
+		proposed[key] = value
+	}
+	update.apply(proposed)
+
+	raw, err := json.Marshal(proposed)
+	if err != nil {
+		return change, err
+	}
+	change.Proposed = string(raw)
+	change.Diff = metadataDiff(current.Fields, proposed)
+	if len(change.Diff) == 0 {
+		return change, ErrNoChange
+	}
+	if dryRun {
+		return change, nil
+	}
+
+	tx, err := o.TxManager.ContractSendTx("updateMetadata", []interface{}{change.Proposed})
+	if err != nil {
+		return change, err
+	}
+	change.Tx = tx
+	return change, o.waitMined(tx, 2*time.Minute)
+}
+
+func (u MetadataUpdate) Validate() error {
+	if u.Name == nil && u.Description == nil && u.ImageURL == nil && u.RedundancyFactor == nil {
+		return errors.New("no metadata fields to update")
+	}
+	if u.Name != nil && strings.TrimSpace(*u.Name) == "" {
+		return errors.New("name can't be empty")
+	}
+	if u.ImageURL != nil && *u.ImageURL != "" {
+		parsed, err := url.Parse(*u.ImageURL)
+		if err != nil || (parsed.Scheme != "https" && parsed.Scheme != "http" && parsed.Scheme != "ipfs") || parsed.Host == "" {
+			return fmt.Errorf("invalid imageUrl %q", *u.ImageURL)
+		}
+	}
+	if u.RedundancyFactor != nil && *u.RedundancyFactor < 1 {
+		return fmt.Errorf("invalid redundancyFactor %d, must be at least 1", *u.RedundancyFactor)
+	}
+	return nil
+}
+
+// apply sets the updated fields. An empty description or image URL removes the field.
+func (u MetadataUpdate) apply(fields map[string]interface{}) {
+	if u.Name != nil {
+		fields[MetadataName] = strings.TrimSpace(*u.Name)
+	}
+	setOrDelete := func(key string, value *string) {
+		if value == nil {
+			return
+		}
+		if *value == "" {
+			delete(fields, key)
+		} else {
+			fields[key] = *value
+		}
+	}
+	setOrDelete(MetadataDescription, u.Description)
+	setOrDelete(MetadataImageURL, u.ImageURL)
+	if u.RedundancyFactor != nil {
+		// numbers decode as float64, so store it the same way to compare like with like
+		fields[MetadataRedundancyFactor] = float64(*u.RedundancyFactor)
+	}
+}
+
+// metadataDiff lists the fields that differ, sorted by name. Missing fields are nil.
+func metadataDiff(current map[string]interface{}, proposed map[string]interface{}) []FieldChange {
+	keys := make(map[string]bool)
+	for key := range current {
+		keys[key] = true
+	}
+	for key := range proposed {
+		keys[key] = true
+	}
+
+	diff := []FieldChange{}
+	for key := range keys {
+		if !reflect.DeepEqual(current[key], proposed[key]) {
+			diff = append(diff, FieldChange{Field: key, Current: current[key], Proposed: proposed[key]})
+		}
+	}
+	sort.Slice(diff, func(i, j int) bool { return diff[i].Field < diff[j].Field })
+	return diff
+}
+
+func (o *Operator) nodeAddresses() ([]ethcommon.Address, error) {
+	result, err := o.TxManager.ContractCall("getNodeAddresses", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	addresses, ok := result[0].([]ethcommon.Address)
+	if !ok {
+		return nil, fmt.Errorf("unexpected getNodeAddresses result: %v", result[0])
+	}
+	return addresses, nil
+}
@@ -3,10 +3,14 @@ package models
 import (
 	"crypto/ecdsa"
 	"encoding/json"
+	"fmt"
 	"log"
 	"math/big"
 	"streamr_api/blockchain"
+	"streamr_api/common"
+	"streamr_api/config"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/ethereum/go-ethereum/accounts/abi"
@@ -15,78 +19,145 @@ import (
 )
 
 type Operator struct {
+	Name         string            `json:"name"`
 	ContractAddr ethcommon.Address `json:"contractAddr"`
 	ContractAbi  abi.ABI           `json:"contractAbi"`
 	OwnerAddr    ethcommon.Address `json:"ownerAddr"`
-	PrivateKey   *ecdsa.PrivateKey
+	PrivateKey   *ecdsa.PrivateKey `json:"-"`
 	TxManager    *blockchain.TxManager
+	Indexer      *blockchain.Indexer `json:"-"`
+	Accounting   *Accounting         `json:"-"`
+	Metrics      *MetricsCollector   `json:"-"`
+	Alerts       *Alerter            `json:"-"`
+	Guard        *EarningsGuard      `json:"-"`
+	Queue        *QueueService       `json:"-"`
+	Rebalancer   *Rebalancer         `json:"-"`
+	Discovery    *Discovery          `json:"-"`
+	Exits        *ExitPolicy         `json:"-"`
+	Reviews      *ReviewService      `json:"-"`
+	Cut          *CutManager         `json:"-"`
+	Wallet       *WalletMonitor      `json:"-"`
+	Signer       *SignerVerifier     `json:"-"`
+
+	config *config.Config // as started; the services hold the reloadable parts
+	store  *blockchain.Store
+
+	tokenMu     sync.Mutex
+	token       *blockchain.Token
+	tokenSource string
+
+	undelegating sync.Mutex // one self-undelegation at a time, so two can't both pass the minimum
 }
 
 type GetSponsorshipsAndEarningsResponse struct {
 	Addresses          []ethcommon.Address `json:"addresses"`
-	Earnings           []*big.Int          `json:"earnings"`
-	MaxAllowedEarnings *big.Int            `json:"maxAllowedEarnings"`
+	Earnings           []*common.Amount    `json:"earnings"`
+	MaxAllowedEarnings *common.Amount      `json:"maxAllowedEarnings"`
 }
 
 type DeployedStakeResponse struct {
-	DeployedBySponsorship map[ethcommon.Address]*big.Int `json:"deployedBySponsorship"`
-	TotalDeployed         *big.Int                       `json:"totalDeployed"`
+	DeployedBySponsorship map[ethcommon.Address]*common.Amount `json:"deployedBySponsorship"`
+	TotalDeployed         *common.Amount                       `json:"totalDeployed"`
 }
 
 type UndelegationRecordResponse struct {
 	Delegator ethcommon.Address `json:"delegator"`
-	Amount    *big.Int          `json:"amountWei"`
+	Amount    *common.Amount    `json:"amountWei"`
 	Timestamp *big.Int          `json:"timestamp"`
 }
 
 type StakedIntoResponse struct {
-	StakedInto *big.Int `json:"stakedInto"`
+	StakedInto *common.Amount `json:"stakedInto"`
 }
 
-func NewOperator(contractAddr string, ownerAddr string, privateKey string) *Operator {
-	abiStr, err := blockchain.FetchContractABI(contractAddr)
+// NewOperator creates an operator of a validated configuration.
+func NewOperator(c *config.Config, operatorConfig config.OperatorConfig) (*Operator, error) {
+	abiStr, err := blockchain.FetchContractABI(operatorConfig.Contract)
 	if err != nil {
-		panic(err)
+		return nil, err
 	}
 
 	contractABI, err := abi.JSON(strings.NewReader(abiStr))
 	if err != nil {
-		log.Fatalf("Invalid ABI: %v", err)
+		return nil, fmt.Errorf("invalid ABI: %v", err)
 	}
 
-	privKey, err := crypto.HexToECDSA(privateKey)
+	key, err := c.OperatorKey(operatorConfig)
 	if err != nil {
-		log.Fatalf("Failed to load private key: %v", err)
+		return nil, err
+	}
+	privKey, err := crypto.HexToECDSA(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load private key: %v", err)
 	}
 
-	txManager, err := blockchain.NewTxManager(privKey, ethcommon.HexToAddress(contractAddr), contractABI)
+	txManager, err := blockchain.NewTxManagerAt(c.OperatorRPC(operatorConfig), privKey, ethcommon.HexToAddress(operatorConfig.Contract), contractABI)
 	if err != nil {
-		log.Fatalf("Failed to create tx manager: %v", err)
+		return nil, fmt.Errorf("failed to create tx manager: %v", err)
 	}
 
 	return &Operator{
-		ContractAddr: ethcommon.HexToAddress(contractAddr),
+		Name:         operatorConfig.Name,
+		ContractAddr: ethcommon.HexToAddress(operatorConfig.Contract),
 		ContractAbi:  contractABI,
-		OwnerAddr:    ethcommon.HexToAddress(ownerAddr),
+		OwnerAddr:    ethcommon.HexToAddress(operatorConfig.Owner),
 		PrivateKey:   privKey,
 		TxManager:    txManager,
-	}
+		config:       c,
+	}, nil
 }
 
 func (o *Operator) GetValueWithoutEarnings() (*big.Int, error) {
 	result, err := o.TxManager.ContractCall("valueWithoutEarnings", []interface{}{})
 	if err != nil {
-		log.Fatalf("Failed to unpack the output: %v", err)
+		log.Printf("Failed to unpack the output: %v", err)
 		return nil, err
 	}
 
 	return result[0].(*big.Int), nil
 }
 
+// GetTokenAddress returns the DATA token contract the operator stakes and pays out in.
+func (o *Operator) GetTokenAddress() (ethcommon.Address, error) {
+	result, err := o.TxManager.ContractCall("token", []interface{}{})
+	if err != nil {
+		return ethcommon.Address{}, err
+	}
+
+	addr, ok := result[0].(ethcommon.Address)
+	if !ok {
+		return ethcommon.Address{}, fmt.Errorf("unexpected token result: %v", result[0])
+	}
+	return addr, nil
+}
+
+// GetDataBalance returns the DATA held by the operator contract that is not staked in any sponsorship.
+func (o *Operator) GetDataBalance() (*big.Int, error) {
+	token, err := o.DataToken()
+	if err != nil {
+		return nil, err
+	}
+	return token.BalanceOf(o.ContractAddr)
+}
+
+// GetDelegatorBalance returns the value of a delegator's operator tokens in DATA.
+func (o *Operator) GetDelegatorBalance(delegator ethcommon.Address) (*big.Int, error) {
+	result, err := o.TxManager.ContractCall("balanceInData", []interface{}{delegator})
+	if err != nil {
+		return nil, err
+	}
+
+	balance, ok := result[0].(*big.Int)
+	if !ok {
+		return nil, fmt.Errorf("unexpected balanceInData result: %v", result[0])
+	}
+	return balance, nil
+}
+
 func (o *Operator) GetUndelegationQueue() ([][]UndelegationRecordResponse, error) {
 	result, err := o.TxManager.ContractCallSpecial("undelegationQueue", []interface{}{})
 	if err != nil {
-		log.Fatalf("Failed to unpack the output: %v", err)
+		log.Printf("Failed to unpack the output: %v", err)
 		return nil, err
 	}
 
@@ -104,94 +175,57 @@ func (o *Operator) WithdrawEarnings() (string, error) {
 
 	sponsors, err := o.GetSponsorshipsAndEarnings()
 	if err != nil {
-		log.Fatalf("Failed to get sponsorships and earnings: %v", err)
+		log.Printf("Failed to get sponsorships and earnings: %v", err)
 		return "", err
 	}
 
-	params := []interface{}{} // The parameters for your method, if any
-
-	params = append(params, sponsors.Addresses)
-
-	result, err := o.TxManager.ContractSendTx("withdrawEarningsFromSponsorships", params)
-	if err != nil {
-		log.Fatalf("Failed to send transaction: %v", err)
-		return "", err
-	}
-
-	return result, nil
+	return o.WithdrawEarningsFrom(sponsors.Addresses)
 }
 
-func (o *Operator) WithdrawEarningsAndCompound() ([]string, error) {
-	txList := []string{}
-	sponsors, err := o.GetSponsorshipsAndEarnings()
-	if err != nil {
-		log.Fatalf("Failed to get sponsorships and earnings: %v", err)
-		return nil, err
+// WithdrawEarningsFrom withdraws the earnings of the given sponsorships only.
+func (o *Operator) WithdrawEarningsFrom(sponsorships []ethcommon.Address) (string, error) {
+	// snapshot the earnings right before withdrawing so the accounting knows what was withdrawn
+	var before EarningsSnapshot
+	var err error
+	if o.Accounting != nil {
+		before, err = o.Accounting.Snapshot()
+		if err != nil {
+			log.Printf("Failed to snapshot earnings before withdrawal: %v", err)
+		}
 	}
 
 	params := []interface{}{} // The parameters for your method, if any
 
-	params = append(params, sponsors.Addresses)
+	params = append(params, sponsorships)
 
 	result, err := o.TxManager.ContractSendTx("withdrawEarningsFromSponsorships", params)
 	if err != nil {
-		log.Fatalf("Failed to send transaction: %v", err)
-		return nil, err
-	}
-
-	txList = append(txList, result)
-
-	// get current deploy stake
-	deployedStake, err := o.GetDeployedStake()
-	if err != nil {
-		log.Fatalf("Failed to get deployed stake: %v", err)
-		return nil, err
+		log.Printf("Failed to send transaction: %v", err)
+		return "", err
 	}
 
-	// iterate each sponsor and set stake to the current amount plus the earnings withdrawn in the previous transaction
-	for sponsorA := range deployedStake.DeployedBySponsorship {
-		for i, sponsorB := range sponsors.Addresses {
-			if sponsorA == sponsorB {
-
-				// the protocol take 5% of earnings, so reduce the earnings by 5% before compounding
-				amount := sponsors.Earnings[i]
-				amount = amount.Mul(amount, big.NewInt(95))
-				amount = amount.Div(amount, big.NewInt(100))
-
-				log.Printf("Adding %s stake to %s\n", sponsors.Earnings[i].String(), sponsorA.Hex())
-
-				tx, err := o.Stake(sponsorA, amount)
-				go func() {
-					txResult, err := o.TxManager.PolygonWaitForTx(tx, 120*time.Second)
-					if err != nil {
-						log.Printf("Failed to wait for transaction: %v", err)
-					} else {
-						log.Printf("Transaction %s completed as: %v\n", txResult.Hash().Hex(), txResult)
-					}
-				}()
-
-				if err != nil {
-					log.Fatalf("Failed to reduce stake: %v", err)
-					return nil, err
-				}
-				txList = append(txList, tx)
-			}
+	if o.Accounting != nil && before.Earnings != nil {
+		if err := o.Accounting.RecordWithdrawal(result, before, sponsorships); err != nil {
+			log.Printf("Failed to record withdrawal %s: %v", result, err)
 		}
 	}
 
-	return txList, nil
+	return result, nil
 }
 
 func (o *Operator) GetSponsorshipsAndEarnings() (GetSponsorshipsAndEarningsResponse, error) {
 	result, err := o.TxManager.ContractCall("getSponsorshipsAndEarnings", []interface{}{})
 	if err != nil {
-		log.Fatalf("Failed to unpack the output: %v", err)
+		log.Printf("Failed to unpack the output: %v", err)
 		return GetSponsorshipsAndEarningsResponse{}, err
 	}
 	var jsonResult GetSponsorshipsAndEarningsResponse = GetSponsorshipsAndEarningsResponse{
 		Addresses:          result[0].([]ethcommon.Address),
-		Earnings:           result[1].([]*big.Int),
-		MaxAllowedEarnings: result[2].(*big.Int),
+		Earnings:           []*common.Amount{},
+		MaxAllowedEarnings: common.NewAmount(result[2].(*big.Int)),
+	}
+	for _, earnings := range result[1].([]*big.Int) {
+		jsonResult.Earnings = append(jsonResult.Earnings, common.NewAmount(earnings))
 	}
 	return jsonResult, nil
 }
@@ -203,18 +237,18 @@ func (o *Operator) StakedInto(sponsorshipAddr ethcommon.Address) (StakedIntoResp
 	result, err := o.TxManager.ContractCall("stakedInto", params)
 
 	if err != nil {
-		log.Fatalf("Failed to unpack the output: %v", err)
+		log.Printf("Failed to unpack the output: %v", err)
 		return StakedIntoResponse{}, err
 	}
 
 	bigIntPointer, ok := result[0].(*big.Int)
 	if !ok {
-		log.Fatalf("Failed to cast the result to *big.Int: %v", err)
-		return StakedIntoResponse{}, err
+		log.Printf("Failed to cast the result to *big.Int: %v", result[0])
+		return StakedIntoResponse{}, fmt.Errorf("unexpected stakedInto result: %v", result[0])
 	}
 
 	var jsonResult StakedIntoResponse = StakedIntoResponse{
-		StakedInto: bigIntPointer,
+		StakedInto: common.NewAmount(bigIntPointer),
 	}
 
 	return jsonResult, nil
@@ -227,8 +261,8 @@ func (o *Operator) GetDeployedStake() (DeployedStakeResponse, error) {
 	}
 
 	response := DeployedStakeResponse{
-		DeployedBySponsorship: make(map[ethcommon.Address]*big.Int),
-		TotalDeployed:         big.NewInt(0),
+		DeployedBySponsorship: make(map[ethcommon.Address]*common.Amount),
+		TotalDeployed:         common.NewAmount(nil),
 	}
 
 	for _, addr := range SAE.Addresses {
@@ -238,7 +272,7 @@ func (o *Operator) GetDeployedStake() (DeployedStakeResponse, error) {
 			return DeployedStakeResponse{}, err
 		}
 		response.DeployedBySponsorship[addr] = sponsorDeployed.StakedInto
-		response.TotalDeployed.Add(response.TotalDeployed, sponsorDeployed.StakedInto)
+		response.TotalDeployed.Int().Add(response.TotalDeployed.Int(), sponsorDeployed.StakedInto.Int())
 	}
 	log.Printf("Total Deployed: %s\n", response.TotalDeployed.String())
 	return response, nil
@@ -249,7 +283,7 @@ func (o *Operator) ReduceStakeTo(addr ethcommon.Address, targetStake *big.Int) (
 	params := []interface{}{addr, targetStake}
 	result, err := o.TxManager.ContractSendTx("reduceStakeTo", params)
 	if err != nil {
-		log.Fatalf("Failed to send transaction: %v", err)
+		log.Printf("Failed to send transaction: %v", err)
 		return "", err
 	}
 
@@ -260,13 +294,53 @@ func (o *Operator) Stake(addr ethcommon.Address, targetStake *big.Int) (string,
 	params := []interface{}{addr, targetStake}
 	result, err := o.TxManager.ContractSendTx("stake", params)
 	if err != nil {
-		log.Fatalf("Failed to send transaction: %v", err)
+		log.Printf("Failed to send transaction: %v", err)
 		return "", err
 	}
 
 	return result, nil
 }
 
+// Unstake leaves a sponsorship, returning the whole stake to the operator minus any leave penalty.
+func (o *Operator) Unstake(addr ethcommon.Address) (string, error) {
+	result, err := o.TxManager.ContractSendTx("unstake", []interface{}{addr})
+	if err != nil {
+		log.Printf("Failed to send transaction: %v", err)
+		return "", err
+	}
+
+	return result, nil
+}
+
+// GetLeavePenalty returns the stake the operator would forfeit by leaving a sponsorship now. It is
+// non-zero while the sponsorship is running, funded and the minimum staking period hasn't passed.
+func (o *Operator) GetLeavePenalty(sponsorship ethcommon.Address) (*big.Int, error) {
+	result, err := o.TxManager.ContractCallAt(sponsorship, blockchain.SponsorshipAbi, "getLeavePenalty", []interface{}{o.ContractAddr})
+	if err != nil {
+		return nil, err
+	}
+
+	value, ok := result[0].(*big.Int)
+	if !ok {
+		return nil, fmt.Errorf("unexpected getLeavePenalty result: %v", result[0])
+	}
+	return value, nil
+}
+
+// GetJoinTime returns when the operator joined a sponsorship.
+func (o *Operator) GetJoinTime(sponsorship ethcommon.Address) (time.Time, error) {
+	result, err := o.TxManager.ContractCallAt(sponsorship, blockchain.SponsorshipAbi, "joinTimestampOfOperator", []interface{}{o.ContractAddr})
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	value, ok := result[0].(*big.Int)
+	if !ok {
+		return time.Time{}, fmt.Errorf("unexpected joinTimestampOfOperator result: %v", result[0])
+	}
+	return time.Unix(value.Int64(), 0).UTC(), nil
+}
+
 func (o *Operator) StakeProRata() ([]string, error) {
 	deployedStake, err := o.GetDeployedStake()
 	if err != nil {
@@ -275,13 +349,13 @@ func (o *Operator) StakeProRata() ([]string, error) {
 	}
 	log.Printf("Deployed Stake: %v\n", deployedStake.TotalDeployed)
 	totalValue, err := o.GetValueWithoutEarnings()
-	unstaked := new(big.Int).Sub(totalValue, deployedStake.TotalDeployed)
+	unstaked := new(big.Int).Sub(totalValue, deployedStake.TotalDeployed.Int())
 	log.Printf("Unstaked: %s\nerr: %v", unstaked.String(), err)
 	// use a DeployedStakeResponse object to calculate and store amounts of stake to deploy to each sponsorship
 	// using this data type for convenience
 	var stakeProRata DeployedStakeResponse = DeployedStakeResponse{
-		DeployedBySponsorship: make(map[ethcommon.Address]*big.Int),
-		TotalDeployed:         totalValue,
+		DeployedBySponsorship: make(map[ethcommon.Address]*common.Amount),
+		TotalDeployed:         (*common.Amount)(totalValue),
 	}
 
 	// calculate the total amount of stake to deploy to each sponsoship based on total available DATA and
@@ -289,30 +363,30 @@ func (o *Operator) StakeProRata() ([]string, error) {
 	for sponsorhip, deployed := range deployedStake.DeployedBySponsorship {
 		// calculate share of new stake to this sponsorship. Multiply first to avoid rounding errors, then divide by totalDeployed
 		numerator := new(big.Int)
-		numerator.Mul(unstaked, deployed)
-		share := new(big.Int).Div(numerator, deployedStake.TotalDeployed)
-		stakeProRata.DeployedBySponsorship[sponsorhip] = share
-		stakeProRata.TotalDeployed.Add(stakeProRata.TotalDeployed, share)
+		numerator.Mul(unstaked, deployed.Int())
+		share := new(big.Int).Div(numerator, deployedStake.TotalDeployed.Int())
+		stakeProRata.DeployedBySponsorship[sponsorhip] = common.NewAmount(share)
+		stakeProRata.TotalDeployed.Int().Add(stakeProRata.TotalDeployed.Int(), share)
 		log.Printf("Share for %s: %s\n", sponsorhip.Hex(), share.String())
 	}
 
 	// check to see if the total amount of stake to deploy is equal to the total amount of DATA available
 	// if not, adjust the stake to deploy to the first sponsorship to make up the difference
-	if stakeProRata.TotalDeployed.Cmp(totalValue) != 0 {
-		adjustment := new(big.Int).Sub(totalValue, stakeProRata.TotalDeployed)
+	if stakeProRata.TotalDeployed.Int().Cmp(totalValue) != 0 {
+		adjustment := new(big.Int).Sub(totalValue, stakeProRata.TotalDeployed.Int())
 		lastSponsorship := ethcommon.Address{}
 		for sponsorhip := range deployedStake.DeployedBySponsorship {
 			lastSponsorship = sponsorhip
 			break
 		}
-		stakeProRata.DeployedBySponsorship[lastSponsorship].Add(stakeProRata.DeployedBySponsorship[lastSponsorship], adjustment)
-		stakeProRata.TotalDeployed.Add(stakeProRata.TotalDeployed, adjustment)
+		stakeProRata.DeployedBySponsorship[lastSponsorship].Int().Add(stakeProRata.DeployedBySponsorship[lastSponsorship].Int(), adjustment)
+		stakeProRata.TotalDeployed.Int().Add(stakeProRata.TotalDeployed.Int(), adjustment)
 	}
 
 	// iterate through the sponsorships and deploy the calculated amount of stake to each
 	txList := []string{}
 	for sponsorhip, amount := range stakeProRata.DeployedBySponsorship {
-		tx, err := o.Stake(sponsorhip, amount)
+		tx, err := o.Stake(sponsorhip, amount.Int())
 		if err != nil {
 			log.Printf("Failed to increase stake: %v", err)
 			return nil, err
@@ -0,0 +1,324 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"math/big"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"streamr_api/blockchain"
+	"streamr_api/common"
+	"streamr_api/config"
+
+	ethcommon "github.com/ethereum/go-ethereum/common"
+)
+
+const queueAlertSource = "undelegation-queue"
+
+type QueueServiceConfig struct {
+	Policy    string
+	Interval  time.Duration // 0 disables servicing in the background
+	TxTimeout time.Duration
+}
+
+// QueuePlan shows how the pending undelegations would be paid out: from the DATA the operator
+// holds, then from the earnings, then by reducing stake according to the policy.
+type QueuePlan struct {
+	Timestamp  time.Time        `json:"timestamp"`
+	Policy     string           `json:"policy"`
+	Entries    int              `json:"entries"`
+	Pending    *common.Amount   `json:"pending"`  // DATA the queue needs, capped at each delegator's balance
+	Balance    *common.Amount   `json:"balance"`  // DATA held by the operator contract
+	Earnings   *common.Amount   `json:"earnings"` // unwithdrawn earnings after the protocol fee
+	Reductions []StakeReduction `json:"reductions"`
+	Shortfall  *common.Amount   `json:"shortfall"` // what can't be freed without leaving sponsorships
+
+	withdraw []ethcommon.Address
+}
+
+// QueueServiceReport records a run of the queue servicing workflow.
+type QueueServiceReport struct {
+	QueuePlan
+	Transactions []string `json:"transactions"`
+	Error        string   `json:"error,omitempty"`
+}
+
+// QueueService pays out the undelegation queue, freeing the DATA it needs by withdrawing earnings
+// and reducing stake when the operator doesn't hold enough.
+type QueueService struct {
+	o      *Operator
+	store  *blockchain.Store
+	alerts *Alerter
+	config atomic.Pointer[QueueServiceConfig]
+	prefix string
+
+	mu   sync.Mutex
+	quit chan struct{}
+	wg   sync.WaitGroup
+}
+
+func NewQueueServiceConfig(p config.QueuePolicy) QueueServiceConfig {
+	return QueueServiceConfig{
+		Policy:    p.Policy,
+		Interval:  time.Duration(p.IntervalSeconds) * time.Second,
+		TxTimeout: 5 * time.Minute,
+	}
+}
+
+func NewQueueService(o *Operator, store *blockchain.Store, alerts *Alerter, config QueueServiceConfig) *QueueService {
+	q := &QueueService{
+		o:      o,
+		store:  store,
+		alerts: alerts,
+		prefix: fmt.Sprintf("queue/%s/", strings.ToLower(o.ContractAddr.Hex())),
+		quit:   make(chan struct{}),
+	}
+	q.config.Store(&config)
+	return q
+}
+
+func (q *QueueService) Start() error {
+	config := q.config.Load()
+	if err := ValidPolicy(config.Policy); err != nil {
+		return err
+	}
+	if config.Interval == 0 {
+		log.Printf("Undelegation queue servicing is disabled")
+		return nil
+	}
+
+	q.wg.Add(1)
+	go func() {
+		defer q.wg.Done()
+
+		ticker := time.NewTicker(config.Interval)
+		defer ticker.Stop()
+
+		for {
+			if _, err := q.Service(config.Policy); err != nil {
+				log.Printf("Failed to service undelegation queue: %v", err)
+			}
+
+			select {
+			case <-q.quit:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+	return nil
+}
+
+func (q *QueueService) Stop() {
+	close(q.quit)
+	q.wg.Wait()
+}
+
+// Reload restarts the service with a new configuration once the run in progress is done.
+func (q *QueueService) Reload(config QueueServiceConfig) error {
+	q.Stop()
+	q.config.Store(&config)
+	q.quit = make(chan struct{})
+	return q.Start()
+}
+
+// Plan works out how the queue would be paid out with the given policy, without sending anything.
+func (q *QueueService) Plan(policy string) (QueuePlan, error) {
+	if err := ValidPolicy(policy); err != nil {
+		return QueuePlan{}, err
+	}
+	o := q.o
+
+	plan := QueuePlan{Timestamp: time.Now().UTC(), Policy: policy, Reductions: []StakeReduction{}}
+	pending, entries, err := o.pendingUndelegations()
+	if err != nil {
+		return plan, err
+	}
+	plan.Entries = entries
+	plan.Pending = common.NewAmount(pending)
+
+	balance, err := o.GetDataBalance()
+	if err != nil {
+		return plan, err
+	}
+	plan.Balance = common.NewAmount(balance)
+	plan.Earnings = common.NewAmount(nil)
+	plan.Shortfall = common.NewAmount(nil)
+
+	needed := new(big.Int).Sub(pending, balance)
+	if needed.Sign() <= 0 {
+		return plan, nil
+	}
+
+	// earnings come first, they don't reduce the operator's stake
+	sponsors, err := o.GetSponsorshipsAndEarnings()
+	if err != nil {
+		return plan, err
+	}
+	protocolFee, err := o.GetProtocolFeeFraction()
+	if err != nil {
+		return plan, err
+	}
+	earnings := big.NewInt(0)
+	for i, addr := range sponsors.Addresses {
+		if sponsors.Earnings[i].Int().Sign() > 0 {
+			plan.withdraw = append(plan.withdraw, addr)
+			earnings.Add(earnings, sponsors.Earnings[i].Int())
+		}
+	}
+	earnings.Sub(earnings, applyFraction(earnings, protocolFee))
+	plan.Earnings = common.NewAmount(earnings)
+
+	needed.Sub(needed, earnings)
+	if needed.Sign() <= 0 {
+		return plan, nil
+	}
+
+	positions, err := o.stakePositions()
+	if err != nil {
+		return plan, err
+	}
+	minStake, err := o.GetMinimumStake()
+	if err != nil {
+		return plan, err
+	}
+	reductions, shortfall := planReductions(positions, minStake, needed, policy)
+	plan.Reductions = reductions
+	plan.Shortfall = common.NewAmount(shortfall)
+	return plan, nil
+}
+
+// Service plans the payout, withdraws earnings and reduces stake as planned, then pays out the queue.
+func (q *QueueService) Service(policy string) (QueueServiceReport, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	plan, err := q.Plan(policy)
+	report := QueueServiceReport{QueuePlan: plan, Transactions: []string{}}
+	if err != nil || plan.Entries == 0 {
+		return report, err
+	}
+
+	err = q.execute(&report)
+	if err != nil {
+		report.Error = err.Error()
+		q.alerts.Alert(AlertCritical, queueAlertSource, "failed to pay out %d undelegation(s) of %s DATA: %v", plan.Entries, plan.Pending.DATA(), err)
+	} else if plan.Shortfall.Int().Sign() > 0 {
+		q.alerts.Alert(AlertWarning, queueAlertSource, "paid out what was possible, %s DATA of the %s DATA queued can only be freed by leaving sponsorships", plan.Shortfall.DATA(), plan.Pending.DATA())
+	} else {
+		q.alerts.Alert(AlertInfo, queueAlertSource, "paid out %d undelegation(s) of %s DATA in %d transaction(s)", plan.Entries, plan.Pending.DATA(), len(report.Transactions))
+	}
+
+	if storeErr := q.store.Put(fmt.Sprintf("%s%020d", q.prefix, report.Timestamp.UnixNano()), report); storeErr != nil {
+		log.Printf("Failed to record undelegation queue report: %v", storeErr)
+	}
+	return report, err
+}
+
+func (q *QueueService) execute(report *QueueServiceReport) error {
+	o := q.o
+	sent := []string{}
+
+	if len(report.withdraw) > 0 {
+		tx, err := o.WithdrawEarningsFrom(report.withdraw)
+		if err != nil {
+			return err
+		}
+		sent = append(sent, tx)
+	}
+	for _, reduction := range report.Reductions {
+		tx, err := o.ReduceStakeTo(reduction.Sponsorship, reduction.Target.Int())
+		if err != nil {
+			return err
+		}
+		sent = append(sent, tx)
+	}
+	report.Transactions = append(report.Transactions, sent...)
+	if err := q.waitFor(sent); err != nil {
+		return err
+	}
+
+	// withdrawals and stake reductions already pay out the queue, this takes care of whatever is left
+	tx, err := o.PayOutQueue()
+	if err != nil {
+		return err
+	}
+	report.Transactions = append(report.Transactions, tx)
+	return q.waitFor([]string{tx})
+}
+
+func (q *QueueService) waitFor(txHashes []string) error {
+	for _, txHash := range txHashes {
+		if err := q.o.waitMined(txHash, q.config.Load().TxTimeout); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Reports returns the recorded runs in [from, to], oldest first. Zero times mean no bound.
+func (q *QueueService) Reports(from time.Time, to time.Time) ([]QueueServiceReport, error) {
+	reports := []QueueServiceReport{}
+	err := q.store.ForEach(q.prefix, func(key string, value []byte) error {
+		var report QueueServiceReport
+		if err := json.Unmarshal(value, &report); err != nil {
+			return err
+		}
+		if inRange(report.Timestamp, from, to) {
+			reports = append(reports, report)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return reports, nil
+}
+
+// PayOutQueue pays out as much of the undelegation queue as the operator's DATA balance allows.
+func (o *Operator) PayOutQueue() (string, error) {
+	// 0 means no limit on the number of queue entries processed
+	return o.TxManager.ContractSendTx("payOutQueue", []interface{}{big.NewInt(0)})
+}
+
+// pendingUndelegations returns the DATA needed to pay out the whole queue and its number of entries.
+// Delegators may queue more than they hold (e.g. to undelegate everything), and the contract only
+// pays out their balance, so each delegator's total is capped at it.
+func (o *Operator) pendingUndelegations() (*big.Int, int, error) {
+	queue, err := o.GetUndelegationQueue()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	requested := make(map[ethcommon.Address]*big.Int)
+	entries := 0
+	for _, records := range queue {
+		for _, record := range records {
+			if record.Amount == nil {
+				continue
+			}
+			entries++
+			if _, ok := requested[record.Delegator]; !ok {
+				requested[record.Delegator] = big.NewInt(0)
+			}
+			requested[record.Delegator].Add(requested[record.Delegator], record.Amount.Int())
+		}
+	}
+
+	pending := big.NewInt(0)
+	for delegator, amount := range requested {
+		balance, err := o.GetDelegatorBalance(delegator)
+		if err != nil {
+			return nil, 0, err
+		}
+		if amount.Cmp(balance) > 0 {
+			amount = balance
+		}
+		pending.Add(pending, amount)
+	}
+	return pending, entries, nil
+}
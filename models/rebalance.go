@@ -0,0 +1,373 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"math/big"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"streamr_api/blockchain"
+	"streamr_api/common"
+	"streamr_api/config"
+
+	ethcommon "github.com/ethereum/go-ethereum/common"
+)
+
+const rebalanceAlertSource = "rebalance"
+
+// Ways of declaring the target allocation.
+const (
+	TargetWeights = "weights" // explicit relative weights per sponsorship, e.g. 40/30/30
+	TargetEqual   = "equal"   // the same stake in every current sponsorship
+	TargetYield   = "yield"   // proportional to each sponsorship's annualized yield over the last 30 days
+)
+
+// RebalanceTargets declares the allocation the rebalancer works towards.
+type RebalanceTargets struct {
+	Mode             string                        `json:"mode" example:"weights"`
+	Weights          map[ethcommon.Address]float64 `json:"weights,omitempty"`            // relative, only used by the weights mode
+	TolerancePercent float64                       `json:"tolerancePercent" example:"5"` // drift of the operator's value ignored per sponsorship
+	MaxMoves         int                           `json:"maxMoves" example:"5"`         // stake and reduceStakeTo transactions per run, 0 for no limit
+}
+
+// RebalanceMove is a single stake or reduceStakeTo transaction of a rebalance.
+type RebalanceMove struct {
+	Sponsorship ethcommon.Address `json:"sponsorship"`
+	Method      string            `json:"method" example:"reduceStakeTo"` // reduceStakeTo or stake
+	Current     *common.Amount    `json:"current"`
+	Target      *common.Amount    `json:"target"`
+	Amount      *common.Amount    `json:"amount"` // always positive, the direction is given by the method
+	TxHash      string            `json:"txHash,omitempty"`
+}
+
+// RebalancePlan is the ordered set of moves towards the targets, reductions first to free DATA.
+type RebalancePlan struct {
+	Timestamp time.Time        `json:"timestamp"`
+	Targets   RebalanceTargets `json:"targets"`
+	Total     *common.Amount   `json:"total"`     // staked plus free DATA, minus what the undelegation queue needs
+	Available *common.Amount   `json:"available"` // free DATA not reserved for the undelegation queue
+	Moves     []RebalanceMove  `json:"moves"`
+	Skipped   []RebalanceMove  `json:"skipped"` // within tolerance or over the move limit
+	Error     string           `json:"error,omitempty"`
+}
+
+// Rebalancer moves stake between sponsorships towards declared target weights.
+type Rebalancer struct {
+	o      *Operator
+	store  *blockchain.Store
+	alerts *Alerter
+	key    string
+
+	defaults atomic.Pointer[config.RebalancePolicy] // used until targets are set
+	mu       sync.Mutex
+}
+
+func DefaultRebalanceTargets(p config.RebalancePolicy) RebalanceTargets {
+	return RebalanceTargets{
+		Mode:             TargetEqual,
+		TolerancePercent: p.TolerancePercent,
+		MaxMoves:         p.MaxMoves,
+	}
+}
+
+func NewRebalancer(o *Operator, store *blockchain.Store, alerts *Alerter, defaults config.RebalancePolicy) *Rebalancer {
+	r := &Rebalancer{
+		o:      o,
+		store:  store,
+		alerts: alerts,
+		key:    fmt.Sprintf("rebalance/%s/targets", strings.ToLower(o.ContractAddr.Hex())),
+	}
+	r.defaults.Store(&defaults)
+	return r
+}
+
+// SetDefaults replaces the targets used until targets are set.
+func (r *Rebalancer) SetDefaults(defaults config.RebalancePolicy) {
+	r.defaults.Store(&defaults)
+}
+
+func (t RebalanceTargets) Validate() error {
+	switch t.Mode {
+	case TargetEqual, TargetYield:
+	case TargetWeights:
+		if len(t.Weights) == 0 {
+			return errors.New("weights mode needs at least one weight")
+		}
+		for addr, weight := range t.Weights {
+			if weight < 0 || math.IsNaN(weight) || math.IsInf(weight, 0) {
+				return fmt.Errorf("invalid weight %v for %s", weight, addr.Hex())
+			}
+		}
+	default:
+		return fmt.Errorf("invalid mode %q, expected %s, %s or %s", t.Mode, TargetWeights, TargetEqual, TargetYield)
+	}
+	if t.TolerancePercent < 0 || t.TolerancePercent >= 100 {
+		return fmt.Errorf("invalid tolerancePercent %v", t.TolerancePercent)
+	}
+	if t.MaxMoves < 0 {
+		return fmt.Errorf("invalid maxMoves %d", t.MaxMoves)
+	}
+	return nil
+}
+
+// Targets returns the stored targets, or the defaults if none were set.
+func (r *Rebalancer) Targets() (RebalanceTargets, error) {
+	targets := DefaultRebalanceTargets(*r.defaults.Load())
+	_, err := r.store.Get(r.key, &targets)
+	return targets, err
+}
+
+func (r *Rebalancer) SetTargets(targets RebalanceTargets) error {
+	if err := targets.Validate(); err != nil {
+		return err
+	}
+	return r.store.Put(r.key, targets)
+}
+
+// Plan computes the moves towards the stored targets without sending anything.
+func (r *Rebalancer) Plan() (RebalancePlan, error) {
+	targets, err := r.Targets()
+	if err != nil {
+		return RebalancePlan{}, err
+	}
+	return r.o.planRebalance(targets)
+}
+
+// Rebalance sends the planned moves: all reductions, waiting for them to be mined, then the stakes.
+func (r *Rebalancer) Rebalance() (RebalancePlan, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	plan, err := r.Plan()
+	if err != nil || len(plan.Moves) == 0 {
+		return plan, err
+	}
+
+	err = r.execute(&plan)
+	if err != nil {
+		plan.Error = err.Error()
+		r.alerts.Alert(AlertCritical, rebalanceAlertSource, "rebalancing failed: %v", err)
+		return plan, err
+	}
+	r.alerts.Alert(AlertInfo, rebalanceAlertSource, "rebalanced with %d move(s), %d skipped", len(plan.Moves), len(plan.Skipped))
+	return plan, nil
+}
+
+func (r *Rebalancer) execute(plan *RebalancePlan) error {
+	o := r.o
+	for _, method := range []string{"reduceStakeTo", "stake"} {
+		sent := []string{}
+		for i := range plan.Moves {
+			move := &plan.Moves[i]
+			if move.Method != method {
+				continue
+			}
+
+			var tx string
+			var err error
+			if method == "reduceStakeTo" {
+				tx, err = o.ReduceStakeTo(move.Sponsorship, move.Target.Int())
+			} else {
+				tx, err = o.Stake(move.Sponsorship, move.Amount.Int())
+			}
+			if err != nil {
+				return err
+			}
+			move.TxHash = tx
+			sent = append(sent, tx)
+		}
+		// the stakes spend the DATA the reductions free, so they must be mined first
+		for _, tx := range sent {
+			if err := o.waitMined(tx, 5*time.Minute); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (o *Operator) planRebalance(targets RebalanceTargets) (RebalancePlan, error) {
+	plan := RebalancePlan{Timestamp: time.Now().UTC(), Targets: targets, Moves: []RebalanceMove{}, Skipped: []RebalanceMove{}}
+	if err := targets.Validate(); err != nil {
+		return plan, err
+	}
+
+	positions, err := o.stakePositions()
+	if err != nil {
+		return plan, err
+	}
+	balance, err := o.GetDataBalance()
+	if err != nil {
+		return plan, err
+	}
+	reserved, _, err := o.pendingUndelegations()
+	if err != nil {
+		return plan, err
+	}
+	minStake, err := o.GetMinimumStake()
+	if err != nil {
+		return plan, err
+	}
+
+	available := new(big.Int).Sub(balance, reserved)
+	if available.Sign() < 0 {
+		available.SetInt64(0)
+	}
+	return computeRebalance(plan, positions, available, minStake)
+}
+
+// computeRebalance fills in the moves of plan from the current positions and the free DATA.
+func computeRebalance(plan RebalancePlan, positions []stakePosition, available *big.Int, minStake *big.Int) (RebalancePlan, error) {
+	targets := plan.Targets
+	total := new(big.Int).Set(available)
+	current := make(map[ethcommon.Address]*big.Int)
+	for _, p := range positions {
+		current[p.sponsorship] = p.stake
+		total.Add(total, p.stake)
+	}
+	plan.Total = common.NewAmount(total)
+	plan.Available = common.NewAmount(available)
+
+	// sponsorships the operator isn't in yet can only be joined in weights mode
+	addresses := []ethcommon.Address{}
+	weights := []*big.Int{}
+	for _, p := range positions {
+		addresses = append(addresses, p.sponsorship)
+	}
+	if targets.Mode == TargetWeights {
+		for addr := range targets.Weights {
+			if _, ok := current[addr]; !ok {
+				addresses = append(addresses, addr)
+			}
+		}
+	}
+	sort.Slice(addresses, func(i, j int) bool { return addresses[i].Hex() < addresses[j].Hex() })
+
+	yields := make(map[ethcommon.Address]float64)
+	for _, p := range positions {
+		yields[p.sponsorship] = p.yield
+	}
+	for _, addr := range addresses {
+		var weight float64
+		switch targets.Mode {
+		case TargetWeights:
+			weight = targets.Weights[addr]
+		case TargetEqual:
+			weight = 1
+		case TargetYield:
+			weight = math.Max(yields[addr], 0)
+		}
+		// weights are relative, a millionth is precise enough
+		weights = append(weights, big.NewInt(int64(math.Round(weight*1e6))))
+	}
+	goal := splitByWeight(total, weights)
+	if len(addresses) > 0 && allZero(goal) {
+		return plan, fmt.Errorf("the %s targets give every sponsorship a weight of zero", targets.Mode)
+	}
+
+	tolerance := new(big.Float).Mul(new(big.Float).SetInt(total), big.NewFloat(targets.TolerancePercent/100))
+	toleranceWei, _ := tolerance.Int(nil)
+
+	reductions := []RebalanceMove{}
+	additions := []RebalanceMove{}
+	for i, addr := range addresses {
+		stake, staked := current[addr]
+		if !staked {
+			stake = big.NewInt(0)
+		}
+		target := goal[i]
+		// a sponsorship can't be held below the minimum stake without leaving it, which the
+		// rebalancer doesn't do, and a new one is only joined with at least the minimum stake
+		if staked && target.Cmp(minStake) < 0 {
+			target = new(big.Int).Set(minStake)
+		}
+		if !staked && target.Cmp(minStake) < 0 {
+			target = big.NewInt(0)
+		}
+
+		diff := new(big.Int).Sub(target, stake)
+		if diff.Sign() == 0 {
+			continue
+		}
+		move := RebalanceMove{
+			Sponsorship: addr,
+			Current:     common.NewAmount(stake),
+			Target:      common.NewAmount(target),
+			Amount:      common.NewAmount(new(big.Int).Abs(diff)),
+		}
+		if new(big.Int).Abs(diff).Cmp(toleranceWei) <= 0 {
+			plan.Skipped = append(plan.Skipped, move)
+			continue
+		}
+		if diff.Sign() < 0 {
+			move.Method = "reduceStakeTo"
+			reductions = append(reductions, move)
+		} else {
+			move.Method = "stake"
+			additions = append(additions, move)
+		}
+	}
+
+	// the largest drifts matter most, so they get the moves first
+	bySize := func(moves []RebalanceMove) {
+		sort.SliceStable(moves, func(i, j int) bool { return moves[i].Amount.Int().Cmp(moves[j].Amount.Int()) > 0 })
+	}
+	bySize(reductions)
+	bySize(additions)
+	candidates := append(append([]RebalanceMove{}, reductions...), additions...)
+	bySize(candidates)
+	selected := make(map[ethcommon.Address]bool)
+	for i, move := range candidates {
+		if targets.MaxMoves > 0 && i >= targets.MaxMoves {
+			break
+		}
+		selected[move.Sponsorship] = true
+	}
+
+	// stakes can only spend the free DATA plus what the selected reductions free
+	funds := new(big.Int).Set(available)
+	for _, move := range reductions {
+		if selected[move.Sponsorship] {
+			plan.Moves = append(plan.Moves, move)
+			funds.Add(funds, move.Amount.Int())
+		} else {
+			plan.Skipped = append(plan.Skipped, move)
+		}
+	}
+	for _, move := range additions {
+		if !selected[move.Sponsorship] || funds.Sign() == 0 {
+			plan.Skipped = append(plan.Skipped, move)
+			continue
+		}
+		if move.Amount.Int().Cmp(funds) > 0 {
+			move.Amount = common.NewAmount(funds)
+			move.Target = common.NewAmount(new(big.Int).Add(move.Current.Int(), funds))
+			if move.Current.Int().Sign() == 0 && move.Target.Int().Cmp(minStake) < 0 {
+				plan.Skipped = append(plan.Skipped, move)
+				continue
+			}
+		}
+		funds.Sub(funds, move.Amount.Int())
+		plan.Moves = append(plan.Moves, move)
+	}
+
+	if len(plan.Moves) == 0 {
+		log.Printf("Stake is within %v%% of the %s targets", targets.TolerancePercent, targets.Mode)
+	}
+	return plan, nil
+}
+
+func allZero(values []*big.Int) bool {
+	for _, value := range values {
+		if value.Sign() != 0 {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,228 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"sync"
+	"time"
+
+	"streamr_api/blockchain"
+	"streamr_api/common"
+	"streamr_api/config"
+
+	ethcommon "github.com/ethereum/go-ethereum/common"
+)
+
+// OperatorInfo identifies an operator of the registry.
+type OperatorInfo struct {
+	Name     string            `json:"name"`
+	Contract ethcommon.Address `json:"contract"`
+	Owner    ethcommon.Address `json:"owner"`
+	Signer   ethcommon.Address `json:"signer"`
+}
+
+// OperatorPosition is the stake and earnings of one operator. Error is set, and the amounts are
+// missing, when its state couldn't be read.
+type OperatorPosition struct {
+	OperatorInfo
+	Value              *common.Amount `json:"value,omitempty"` // valueWithoutEarnings
+	DeployedStake      *common.Amount `json:"deployedStake,omitempty"`
+	Earnings           *common.Amount `json:"earnings,omitempty"` // unwithdrawn
+	MaxAllowedEarnings *common.Amount `json:"maxAllowedEarnings,omitempty"`
+	Sponsorships       int            `json:"sponsorships"`
+	Error              string         `json:"error,omitempty"`
+
+	deployed map[ethcommon.Address]*common.Amount
+}
+
+// SponsorshipExposure is the stake all operators have in one sponsorship.
+type SponsorshipExposure struct {
+	Sponsorship ethcommon.Address `json:"sponsorship"`
+	Stake       *common.Amount    `json:"stake"`
+	Operators   []string          `json:"operators"`
+}
+
+// Portfolio aggregates the stake and earnings of every operator. The totals only include the
+// operators that could be read.
+type Portfolio struct {
+	Timestamp     time.Time             `json:"timestamp"`
+	Operators     []OperatorPosition    `json:"operators"`
+	TotalValue    *common.Amount        `json:"totalValue"`
+	TotalStake    *common.Amount        `json:"totalStake"`
+	TotalEarnings *common.Amount        `json:"totalEarnings"`
+	Sponsorships  []SponsorshipExposure `json:"sponsorships"`
+}
+
+// Registry holds the operators run by the service. The first one is the default operator, which
+// the routes without an operator name act on.
+type Registry struct {
+	operators []*Operator
+	byName    map[string]*Operator
+
+	servicesMu sync.Mutex // serializes reloads with stopping the services
+	stopped    bool
+}
+
+// NewRegistry creates the operators of a validated configuration. Operators on the same RPC endpoint
+// share a client and those with the same key share a nonce sequence, see blockchain.RPCPool.
+func NewRegistry(c *config.Config) (*Registry, error) {
+	r := &Registry{byName: make(map[string]*Operator)}
+	for _, operatorConfig := range c.Operators {
+		o, err := NewOperator(c, operatorConfig)
+		if err != nil {
+			return nil, fmt.Errorf("operator %s: %v", operatorConfig.Name, err)
+		}
+		r.operators = append(r.operators, o)
+		r.byName[o.Name] = o
+	}
+	return r, nil
+}
+
+// ErrServicesStopped is returned by reloads once the services were stopped for shutdown.
+var ErrServicesStopped = errors.New("the services are stopped")
+
+// Reload applies the policies and notifications of a new configuration to every operator.
+func (r *Registry) Reload(c *config.Config) error {
+	r.servicesMu.Lock()
+	defer r.servicesMu.Unlock()
+	if r.stopped {
+		return ErrServicesStopped
+	}
+
+	for _, o := range r.operators {
+		if err := o.Reload(c); err != nil {
+			return fmt.Errorf("operator %s: %v", o.Name, err)
+		}
+	}
+	return nil
+}
+
+// StartServices starts the background services of every operator.
+func (r *Registry) StartServices(store *blockchain.Store) error {
+	for _, o := range r.operators {
+		if err := o.StartServices(store); err != nil {
+			return fmt.Errorf("operator %s: %v", o.Name, err)
+		}
+	}
+	return nil
+}
+
+// StopServices stops the background services of every operator, once. A reload in progress is
+// finished first, and later reloads are refused.
+func (r *Registry) StopServices() {
+	r.servicesMu.Lock()
+	defer r.servicesMu.Unlock()
+	if r.stopped {
+		return
+	}
+	r.stopped = true
+
+	for _, o := range r.operators {
+		o.StopServices()
+	}
+}
+
+func (r *Registry) Default() *Operator {
+	return r.operators[0]
+}
+
+func (r *Registry) Get(name string) (*Operator, bool) {
+	o, ok := r.byName[name]
+	return o, ok
+}
+
+func (r *Registry) Operators() []*Operator {
+	return r.operators
+}
+
+func (r *Registry) Infos() []OperatorInfo {
+	infos := make([]OperatorInfo, len(r.operators))
+	for i, o := range r.operators {
+		infos[i] = o.Info()
+	}
+	return infos
+}
+
+func (o *Operator) Info() OperatorInfo {
+	return OperatorInfo{Name: o.Name, Contract: o.ContractAddr, Owner: o.OwnerAddr, Signer: o.TxManager.SignerAddress()}
+}
+
+// Portfolio reads every operator concurrently and adds up their value, stake and earnings.
+func (r *Registry) Portfolio() Portfolio {
+	positions := make([]OperatorPosition, len(r.operators))
+	var wg sync.WaitGroup
+	for i, o := range r.operators {
+		wg.Add(1)
+		go func(i int, o *Operator) {
+			defer wg.Done()
+			positions[i] = o.position()
+		}(i, o)
+	}
+	wg.Wait()
+
+	portfolio := Portfolio{Timestamp: time.Now().UTC(), Operators: positions, Sponsorships: []SponsorshipExposure{}}
+	value, stake, earnings := big.NewInt(0), big.NewInt(0), big.NewInt(0)
+	exposures := make(map[ethcommon.Address]*SponsorshipExposure)
+	for _, position := range positions {
+		if position.Error != "" {
+			continue
+		}
+		value.Add(value, position.Value.Int())
+		stake.Add(stake, position.DeployedStake.Int())
+		earnings.Add(earnings, position.Earnings.Int())
+
+		for addr, amount := range position.deployed {
+			exposure, ok := exposures[addr]
+			if !ok {
+				exposure = &SponsorshipExposure{Sponsorship: addr, Stake: common.NewAmount(big.NewInt(0)), Operators: []string{}}
+				exposures[addr] = exposure
+			}
+			exposure.Stake = common.NewAmount(new(big.Int).Add(exposure.Stake.Int(), amount.Int()))
+			exposure.Operators = append(exposure.Operators, position.Name)
+		}
+	}
+	for _, exposure := range exposures {
+		portfolio.Sponsorships = append(portfolio.Sponsorships, *exposure)
+	}
+	sort.Slice(portfolio.Sponsorships, func(i, j int) bool {
+		return portfolio.Sponsorships[i].Stake.Int().Cmp(portfolio.Sponsorships[j].Stake.Int()) > 0
+	})
+
+	portfolio.TotalValue = common.NewAmount(value)
+	portfolio.TotalStake = common.NewAmount(stake)
+	portfolio.TotalEarnings = common.NewAmount(earnings)
+	return portfolio
+}
+
+func (o *Operator) position() OperatorPosition {
+	position := OperatorPosition{OperatorInfo: o.Info()}
+	value, err := o.GetValueWithoutEarnings()
+	if err != nil {
+		position.Error = err.Error()
+		return position
+	}
+	earnings, err := o.GetSponsorshipsAndEarnings()
+	if err != nil {
+		position.Error = err.Error()
+		return position
+	}
+	deployed, err := o.GetDeployedStake()
+	if err != nil {
+		position.Error = err.Error()
+		return position
+	}
+
+	total := big.NewInt(0)
+	for _, amount := range earnings.Earnings {
+		total.Add(total, amount.Int())
+	}
+	position.Value = common.NewAmount(value)
+	position.DeployedStake = deployed.TotalDeployed
+	position.Earnings = common.NewAmount(total)
+	position.MaxAllowedEarnings = earnings.MaxAllowedEarnings
+	position.Sponsorships = len(earnings.Addresses)
+	position.deployed = deployed.DeployedBySponsorship
+	return position
+}
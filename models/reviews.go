@@ -0,0 +1,448 @@
+package models
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"streamr_api/blockchain"
+	"streamr_api/config"
+
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+const reviewAlertSource = "reviews"
+
+// Review statuses.
+const (
+	ReviewPending = "pending" // the vote can or soon can be cast
+	ReviewVoted   = "voted"
+	ReviewExpired = "expired" // the voting period ended without a vote from us
+)
+
+// Review policies.
+const (
+	ReviewPolicyManual  = "manual"  // only vote through the API
+	ReviewPolicyWebhook = "webhook" // vote as the webhook says once voting opens
+)
+
+var ErrUnknownReview = errors.New("no review request for this sponsorship and target")
+
+// ErrAlreadyVoted is returned for reviews a vote was already sent on.
+var ErrAlreadyVoted = errors.New("a vote on this review was already sent")
+
+// Vote statuses. A vote is recorded as sent before waiting for it to be mined, so it is never sent
+// twice, and only removed again if it reverted.
+const (
+	VoteSent  = "sent"
+	VoteMined = "mined"
+)
+
+type ReviewConfig struct {
+	Policy      string
+	WebhookURL  string        // asked for the verdict with the webhook policy
+	AlertBefore time.Duration // alert when the voting period ends this soon without a vote
+	Interval    time.Duration // 0 disables watching in the background
+}
+
+// ReviewVote is a vote we cast on a flag.
+type ReviewVote struct {
+	Kick    bool      `json:"kick"`
+	TxHash  string    `json:"txHash"`
+	VotedAt time.Time `json:"votedAt"`
+	Source  string    `json:"source"` // api or the policy that cast it
+	Status  string    `json:"status"` // sent or mined
+}
+
+// Review is a request to our operator to review a flag raised against another operator in a
+// sponsorship. Missing the vote risks getting slashed.
+type Review struct {
+	Sponsorship ethcommon.Address `json:"sponsorship"`
+	Target      ethcommon.Address `json:"target"`
+	VoteStart   time.Time         `json:"voteStart"`
+	VoteEnd     time.Time         `json:"voteEnd"`
+	Metadata    string            `json:"metadata,omitempty"`
+	RequestTx   string            `json:"requestTx"`
+	Status      string            `json:"status"`
+	Vote        *ReviewVote       `json:"vote,omitempty"`
+}
+
+// reviewVerdict is what the webhook answers.
+type reviewVerdict struct {
+	Kick bool `json:"kick"`
+}
+
+// ReviewService watches the review requests addressed to our operator, alerts before their
+// deadline and, with the webhook policy, votes on them automatically.
+type ReviewService struct {
+	o      *Operator
+	store  *blockchain.Store
+	alerts *Alerter
+	config atomic.Pointer[ReviewConfig]
+	prefix string
+	client *http.Client
+
+	mu      sync.Mutex
+	alerted map[string]bool        // review key and kind of the alerts already raised
+	voting  map[string]*sync.Mutex // by review key, so votes from the API and the policy never overlap
+
+	quit chan struct{}
+	wg   sync.WaitGroup
+}
+
+func NewReviewConfig(p config.ReviewPolicy, n config.NotificationsConfig) ReviewConfig {
+	return ReviewConfig{
+		Policy:      p.Policy,
+		WebhookURL:  n.ReviewWebhookURL,
+		AlertBefore: time.Duration(p.AlertMinutes) * time.Minute,
+		Interval:    time.Duration(p.IntervalSeconds) * time.Second,
+	}
+}
+
+func NewReviewService(o *Operator, store *blockchain.Store, alerts *Alerter, config ReviewConfig) *ReviewService {
+	r := &ReviewService{
+		o:       o,
+		store:   store,
+		alerts:  alerts,
+		prefix:  fmt.Sprintf("reviews/%s/", strings.ToLower(o.ContractAddr.Hex())),
+		client:  &http.Client{Timeout: 30 * time.Second},
+		alerted: make(map[string]bool),
+		voting:  make(map[string]*sync.Mutex),
+		quit:    make(chan struct{}),
+	}
+	r.config.Store(&config)
+	return r
+}
+
+func (c ReviewConfig) Validate() error {
+	switch c.Policy {
+	case ReviewPolicyManual:
+	case ReviewPolicyWebhook:
+		if c.WebhookURL == "" {
+			return fmt.Errorf("review policy %s needs REVIEW_WEBHOOK_URL", ReviewPolicyWebhook)
+		}
+	default:
+		return fmt.Errorf("invalid review policy %q, expected %s or %s", c.Policy, ReviewPolicyManual, ReviewPolicyWebhook)
+	}
+	return nil
+}
+
+func (r *ReviewService) Start() error {
+	config := r.config.Load()
+	if err := config.Validate(); err != nil {
+		return err
+	}
+	if config.Interval == 0 {
+		log.Printf("Review watching is disabled")
+		return nil
+	}
+
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+
+		ticker := time.NewTicker(config.Interval)
+		defer ticker.Stop()
+
+		for {
+			if err := r.Check(); err != nil {
+				log.Printf("Failed to check review requests: %v", err)
+			}
+
+			select {
+			case <-r.quit:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+	return nil
+}
+
+func (r *ReviewService) Stop() {
+	close(r.quit)
+	r.wg.Wait()
+}
+
+// Reload restarts the service with a new configuration once the check in progress is done.
+func (r *ReviewService) Reload(config ReviewConfig) error {
+	r.Stop()
+	r.config.Store(&config)
+	r.quit = make(chan struct{})
+	return r.Start()
+}
+
+// Reviews returns the review requests, the most recent first. Unless all is set only the pending
+// ones are returned.
+func (r *ReviewService) Reviews(all bool) ([]Review, error) {
+	if r.o.Indexer == nil {
+		return nil, blockchain.ErrIndexerDisabled
+	}
+	events, err := r.o.Indexer.Events(blockchain.EventFilter{Names: []string{"ReviewRequest"}})
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	reviews := []Review{}
+	for _, event := range events {
+		if event.Contract != r.o.ContractAddr {
+			continue
+		}
+		review := Review{
+			Sponsorship: ethcommon.HexToAddress(event.Args["sponsorship"]),
+			Target:      ethcommon.HexToAddress(event.Args["targetOperator"]),
+			VoteStart:   time.Unix(eventAmount(event, "voteStartTimestamp").Int64(), 0).UTC(),
+			VoteEnd:     time.Unix(eventAmount(event, "voteEndTimestamp").Int64(), 0).UTC(),
+			Metadata:    event.Args["flagMetadata"],
+			RequestTx:   event.TxHash.Hex(),
+		}
+
+		var vote ReviewVote
+		found, err := r.store.Get(r.prefix+reviewKey(review), &vote)
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case found:
+			review.Status, review.Vote = ReviewVoted, &vote
+		case now.After(review.VoteEnd):
+			review.Status = ReviewExpired
+		default:
+			review.Status = ReviewPending
+		}
+
+		if all || review.Status == ReviewPending {
+			reviews = append(reviews, review)
+		}
+	}
+	sort.SliceStable(reviews, func(i, j int) bool { return reviews[i].VoteStart.After(reviews[j].VoteStart) })
+	return reviews, nil
+}
+
+// Vote casts our vote on the pending review of target in sponsorship and waits for it to be mined.
+func (r *ReviewService) Vote(sponsorship ethcommon.Address, target ethcommon.Address, kick bool, source string) (Review, error) {
+	reviews, err := r.Reviews(true)
+	if err != nil {
+		return Review{}, err
+	}
+	for _, review := range reviews {
+		if review.Sponsorship == sponsorship && review.Target == target && review.Status != ReviewExpired {
+			err := r.vote(&review, kick, source)
+			return review, err
+		}
+	}
+	return Review{}, ErrUnknownReview
+}
+
+func (r *ReviewService) reviewLock(key string) *sync.Mutex {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	lock, ok := r.voting[key]
+	if !ok {
+		lock = &sync.Mutex{}
+		r.voting[key] = lock
+	}
+	return lock
+}
+
+// vote sends the vote unless one was already sent, records it as sent and waits for it to be mined.
+// A vote whose wait times out stays recorded, so it isn't sent again; one that reverted is removed,
+// so it can be cast again.
+func (r *ReviewService) vote(review *Review, kick bool, source string) error {
+	if time.Now().Before(review.VoteStart) {
+		return fmt.Errorf("voting on %s in %s opens at %s", review.Target.Hex(), review.Sponsorship.Hex(), review.VoteStart.Format(time.RFC3339))
+	}
+
+	key := r.prefix + reviewKey(*review)
+	lock := r.reviewLock(key)
+	lock.Lock()
+	defer lock.Unlock()
+
+	var sent ReviewVote
+	found, err := r.store.Get(key, &sent)
+	if err != nil {
+		return err
+	}
+	if found {
+		review.Status, review.Vote = ReviewVoted, &sent
+		return fmt.Errorf("%w in %s", ErrAlreadyVoted, sent.TxHash)
+	}
+
+	tx, err := r.o.VoteOnFlag(review.Sponsorship, review.Target, kick)
+	if err != nil {
+		return err
+	}
+	vote := ReviewVote{Kick: kick, TxHash: tx, VotedAt: time.Now().UTC(), Source: source, Status: VoteSent}
+	if err := r.store.Put(key, vote); err != nil {
+		log.Printf("Failed to record vote %s: %v", tx, err)
+	}
+	review.Status, review.Vote = ReviewVoted, &vote
+
+	if err := r.o.waitMined(tx, 2*time.Minute); err != nil {
+		if errors.Is(err, ErrTxReverted) {
+			if err := r.store.Delete(key); err != nil {
+				log.Printf("Failed to remove reverted vote %s: %v", tx, err)
+			}
+			review.Status, review.Vote = ReviewPending, nil
+		}
+		return err
+	}
+
+	vote.Status = VoteMined
+	if err := r.store.Put(key, vote); err != nil {
+		log.Printf("Failed to record vote %s: %v", tx, err)
+	}
+	r.alerts.Alert(AlertInfo, reviewAlertSource, "voted %s on the flag against %s in sponsorship %s (%s)", voteName(kick), review.Target.Hex(), review.Sponsorship.Hex(), source)
+	return nil
+}
+
+// settleVotes checks the votes still recorded as sent. Mined ones are marked as such, reverted ones
+// removed so the review is pending again, and ones not mined in a while are alerted about.
+func (r *ReviewService) settleVotes(reviews []Review) {
+	for _, review := range reviews {
+		if review.Vote == nil || review.Vote.Status != VoteSent {
+			continue
+		}
+		key := r.prefix + reviewKey(review)
+		vote := *review.Vote
+		receipt, err := r.o.TxManager.Receipt(vote.TxHash)
+		if err != nil {
+			if time.Since(vote.VotedAt) > 30*time.Minute {
+				r.alertOnce(reviewKey(review), "unmined", AlertCritical, "the vote %s on the flag against %s in sponsorship %s hasn't been mined since %s",
+					vote.TxHash, review.Target.Hex(), review.Sponsorship.Hex(), vote.VotedAt.Format(time.RFC3339))
+			}
+			continue
+		}
+
+		if receipt.Status == types.ReceiptStatusFailed {
+			err = r.store.Delete(key)
+		} else {
+			vote.Status = VoteMined
+			err = r.store.Put(key, vote)
+		}
+		if err != nil {
+			log.Printf("Failed to update vote %s: %v", vote.TxHash, err)
+		}
+	}
+}
+
+// Check alerts about new review requests and approaching deadlines and, with the webhook policy,
+// votes on the reviews whose voting period has started.
+func (r *ReviewService) Check() error {
+	all, err := r.Reviews(true)
+	if err != nil {
+		return err
+	}
+	r.settleVotes(all)
+
+	reviews, err := r.Reviews(false)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	for i := range reviews {
+		review := &reviews[i]
+		key := reviewKey(*review)
+
+		if r.config.Load().Policy == ReviewPolicyWebhook && !now.Before(review.VoteStart) {
+			kick, err := r.askWebhook(*review)
+			if err == nil {
+				err = r.vote(review, kick, ReviewPolicyWebhook)
+			}
+			if err == nil || errors.Is(err, ErrAlreadyVoted) {
+				continue
+			}
+			log.Printf("Automatic vote on %s in %s failed: %v", review.Target.Hex(), review.Sponsorship.Hex(), err)
+			r.alertOnce(key, "failed", AlertWarning, "automatic vote on the flag against %s in sponsorship %s failed: %v", review.Target.Hex(), review.Sponsorship.Hex(), err)
+		}
+
+		r.alertOnce(key, "new", AlertWarning, "selected to review the flag against %s in sponsorship %s, voting is open from %s to %s",
+			review.Target.Hex(), review.Sponsorship.Hex(), review.VoteStart.Format(time.RFC3339), review.VoteEnd.Format(time.RFC3339))
+		if review.VoteEnd.Sub(now) <= r.config.Load().AlertBefore {
+			r.alertOnce(key, "deadline", AlertCritical, "the vote on the flag against %s in sponsorship %s closes at %s and we haven't voted, missing it risks slashing",
+				review.Target.Hex(), review.Sponsorship.Hex(), review.VoteEnd.Format(time.RFC3339))
+		}
+	}
+	return nil
+}
+
+// alertOnce raises each kind of alert only once per review.
+func (r *ReviewService) alertOnce(key string, kind string, level string, format string, args ...interface{}) {
+	r.mu.Lock()
+	if r.alerted[key+"/"+kind] {
+		r.mu.Unlock()
+		return
+	}
+	r.alerted[key+"/"+kind] = true
+	r.mu.Unlock()
+	r.alerts.Alert(level, reviewAlertSource, format, args...)
+}
+
+// askWebhook posts the review to the webhook, e.g. a liveness checker, which answers {"kick": bool}.
+func (r *ReviewService) askWebhook(review Review) (bool, error) {
+	body, err := json.Marshal(struct {
+		Operator string `json:"operator"`
+		Review
+	}{r.o.ContractAddr.Hex(), review})
+	if err != nil {
+		return false, err
+	}
+
+	resp, err := r.client.Post(r.config.Load().WebhookURL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return false, err
+	}
+	if resp.StatusCode >= 300 {
+		return false, fmt.Errorf("review webhook responded with status %d", resp.StatusCode)
+	}
+	var verdict reviewVerdict
+	if err := json.Unmarshal(respBody, &verdict); err != nil {
+		return false, fmt.Errorf("invalid review webhook response: %v", err)
+	}
+	return verdict.Kick, nil
+}
+
+func reviewKey(review Review) string {
+	return fmt.Sprintf("%s/%s/%020d", strings.ToLower(review.Sponsorship.Hex()), strings.ToLower(review.Target.Hex()), review.VoteStart.Unix())
+}
+
+func voteName(kick bool) string {
+	if kick {
+		return "kick"
+	}
+	return "no kick"
+}
+
+// VoteOnFlag votes on a flag our operator was selected to review.
+func (o *Operator) VoteOnFlag(sponsorship ethcommon.Address, target ethcommon.Address, kick bool) (string, error) {
+	// the vote is a bytes32, 1 for kick and 0 for no kick
+	var vote [32]byte
+	if kick {
+		vote[31] = 1
+	}
+	return o.TxManager.ContractSendTx("voteOnFlag", []interface{}{sponsorship, target, vote})
+}
+
+// Flag flags target for misbehaving in a sponsorship both operators are staked in. Part of our stake
+// is locked until the flag is resolved.
+func (o *Operator) Flag(sponsorship ethcommon.Address, target ethcommon.Address, metadata string) (string, error) {
+	return o.TxManager.ContractSendTx("flag", []interface{}{sponsorship, target, metadata})
+}
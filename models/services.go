@@ -0,0 +1,111 @@
+package models
+
+import (
+	"fmt"
+
+	"streamr_api/blockchain"
+	"streamr_api/config"
+)
+
+// StartServices starts the operator's background services, which keep their state in store.
+func (o *Operator) StartServices(store *blockchain.Store) error {
+	policies, notifications := o.config.Policies, o.config.Notifications
+
+	o.store = store
+	o.Alerts = NewAlerter(o, store, NewAlertConfig(notifications))
+	o.TxManager.SetJournal(blockchain.NewTxJournal(store, o.ContractAddr))
+
+	o.Signer = NewSignerVerifier(o, o.Alerts)
+	logSignerStatus(o.Name, o.Signer.Check())
+
+	o.Indexer = blockchain.NewIndexer(o.TxManager, store, blockchain.NewIndexerConfig(o.config.Indexer))
+	if err := o.Indexer.Start(); err != nil {
+		return err
+	}
+
+	o.Accounting = NewAccounting(o, store, NewAccountingConfig(policies.Accounting))
+	o.Accounting.Start()
+
+	o.Metrics = NewMetricsCollector(o, NewMetricsConfig(policies.Metrics))
+	o.Metrics.Start()
+
+	walletConfig, err := NewWalletMonitorConfig(policies.Wallet)
+	if err != nil {
+		return err
+	}
+	o.Wallet = NewWalletMonitor(o, o.Alerts, walletConfig)
+	o.Wallet.Start()
+
+	o.Guard = NewEarningsGuard(o, store, o.Alerts, NewEarningsGuardConfig(policies.Guard))
+	o.Guard.Start()
+
+	o.Queue = NewQueueService(o, store, o.Alerts, NewQueueServiceConfig(policies.Queue))
+	if err := o.Queue.Start(); err != nil {
+		return err
+	}
+
+	o.Rebalancer = NewRebalancer(o, store, o.Alerts, policies.Rebalance)
+	o.Discovery = NewDiscovery(o, NewDiscoveryConfig(policies.Discovery))
+	o.Cut = NewCutManager(o, store, o.Alerts, NewCutConfig(policies.Cut))
+
+	o.Exits = NewExitPolicy(o, store, o.Alerts, NewExitPolicyConfig(policies.Exit))
+	if err := o.Exits.Start(); err != nil {
+		return err
+	}
+
+	o.Reviews = NewReviewService(o, store, o.Alerts, NewReviewConfig(policies.Reviews, notifications))
+	if err := o.Reviews.Start(); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// StopServices stops the operator's background services, each after the run it is in.
+func (o *Operator) StopServices() {
+	o.Reviews.Stop()
+	o.Exits.Stop()
+	o.Queue.Stop()
+	o.Guard.Stop()
+	o.Wallet.Stop()
+	o.Metrics.Stop()
+	o.Accounting.Stop()
+	o.Indexer.Stop()
+}
+
+// Reload applies the policies and notifications of c to the running services. Each background
+// service finishes the run it is in before picking up its new configuration.
+func (o *Operator) Reload(c *config.Config) error {
+	policies, notifications := c.Policies, c.Notifications
+	walletConfig, err := NewWalletMonitorConfig(policies.Wallet)
+	if err != nil {
+		return err
+	}
+
+	o.Alerts.SetConfig(NewAlertConfig(notifications))
+	o.Accounting.Reload(NewAccountingConfig(policies.Accounting))
+	o.Metrics.Reload(NewMetricsConfig(policies.Metrics))
+	o.Wallet.Reload(walletConfig)
+	o.Guard.Reload(NewEarningsGuardConfig(policies.Guard))
+	if err := o.Queue.Reload(NewQueueServiceConfig(policies.Queue)); err != nil {
+		return err
+	}
+	o.Rebalancer.SetDefaults(policies.Rebalance)
+	o.Discovery.SetConfig(NewDiscoveryConfig(policies.Discovery))
+	o.Cut.SetConfig(NewCutConfig(policies.Cut))
+	if err := o.Exits.Reload(NewExitPolicyConfig(policies.Exit)); err != nil {
+		return err
+	}
+	return o.Reviews.Reload(NewReviewConfig(policies.Reviews, notifications))
+}
+
+// CheckPolicies reports the policy settings config.Validate can't check, like unknown policy names.
+func CheckPolicies(c *config.Config) error {
+	if err := ValidPolicy(c.Policies.Queue.Policy); err != nil {
+		return fmt.Errorf("policies.queue.policy (QUEUE_POLICY): %v", err)
+	}
+	if err := ValidAllocation(c.Policies.Exit.Allocation); err != nil {
+		return fmt.Errorf("policies.exit.allocation (EXIT_ALLOCATION): %v", err)
+	}
+	return NewReviewConfig(c.Policies.Reviews, c.Notifications).Validate()
+}
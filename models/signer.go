@@ -0,0 +1,278 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+const signerAlertSource = "signer"
+
+// Actions the signing key may or may not be allowed to take on the operator contract.
+const (
+	ActionStake       = "stake"
+	ActionReduceStake = "reduceStakeTo"
+	ActionUnstake     = "unstake"
+	ActionWithdraw    = "withdraw"
+	ActionNodes       = "setNodeAddresses"
+	ActionMetadata    = "updateMetadata"
+	ActionCut         = "updateOperatorsCut"
+	ActionDelegate    = "delegate"
+	ActionFlag        = "flag"
+	ActionVote        = "voteOnFlag"
+	ActionApprove     = "approve"
+)
+
+// Who may call an action.
+const (
+	permissionAnyone     = "anyone"
+	permissionController = "controller" // CONTROLLER_ROLE on the operator contract
+	permissionOwner      = "owner"      // the owner of the operator contract
+	permissionNode       = "node"       // one of the operator's node addresses
+	permissionHolder     = "holder"     // the owner wallet, whose DATA token approvals are for
+)
+
+var actionPermissions = map[string]string{
+	ActionStake:       permissionController,
+	ActionReduceStake: permissionController,
+	ActionUnstake:     permissionController,
+	ActionWithdraw:    permissionAnyone,
+	ActionNodes:       permissionController,
+	ActionMetadata:    permissionController,
+	ActionCut:         permissionOwner,
+	ActionDelegate:    permissionOwner,
+	ActionFlag:        permissionNode,
+	ActionVote:        permissionNode,
+	ActionApprove:     permissionHolder,
+}
+
+var (
+	ownerRole      = crypto.Keccak256Hash([]byte("OWNER_ROLE"))
+	controllerRole = crypto.Keccak256Hash([]byte("CONTROLLER_ROLE"))
+)
+
+// signerCheckTTL is how long a verification is reused before the contract is asked again, and
+// signerRetryTTL how long one that failed to read the contract is.
+const (
+	signerCheckTTL = 5 * time.Minute
+	signerRetryTTL = 30 * time.Second
+)
+
+// ErrSignerNotPermitted is returned for actions the signing key isn't allowed to take.
+var ErrSignerNotPermitted = errors.New("signer not permitted")
+
+// ErrSignerUnverified is returned for actions needing a role while the roles have never been read.
+var ErrSignerUnverified = errors.New("the roles of the signing key couldn't be read from the operator contract")
+
+// SignerStatus compares the signing key with the configured owner and the roles it holds in the
+// operator contract. Actions tells for each action whether the key can take it. Error is set when
+// the contract couldn't be read; the contract fields are then those last read at RolesCheckedAt,
+// or missing if they never were.
+type SignerStatus struct {
+	Signer          ethcommon.Address  `json:"signer"`
+	ConfiguredOwner ethcommon.Address  `json:"configuredOwner"`
+	ContractOwner   *ethcommon.Address `json:"contractOwner,omitempty"`
+	SignerIsOwner   bool               `json:"signerIsOwner"`
+	OwnerRole       bool               `json:"ownerRole"`
+	ControllerRole  bool               `json:"controllerRole"`
+	NodeAddress     bool               `json:"nodeAddress"`
+	Actions         map[string]bool    `json:"actions"`
+	Problems        []string           `json:"problems"`
+	CheckedAt       time.Time          `json:"checkedAt"`
+	RolesCheckedAt  *time.Time         `json:"rolesCheckedAt,omitempty"`
+	Error           string             `json:"error,omitempty"`
+}
+
+// SignerVerifier checks what the signing key is allowed to do, so mutating requests it can't sign
+// are refused up front instead of reverting on chain.
+type SignerVerifier struct {
+	o      *Operator
+	alerts *Alerter
+
+	mu       sync.Mutex
+	status   *SignerStatus
+	problems string // last alerted problems, to alert only on changes
+}
+
+func NewSignerVerifier(o *Operator, alerts *Alerter) *SignerVerifier {
+	return &SignerVerifier{o: o, alerts: alerts}
+}
+
+// Status returns the last verification, verifying again once it is older than a few minutes.
+func (v *SignerVerifier) Status() SignerStatus {
+	v.mu.Lock()
+	status := v.status
+	v.mu.Unlock()
+	ttl := signerCheckTTL
+	if status != nil && status.Error != "" {
+		ttl = signerRetryTTL
+	}
+	if status != nil && time.Since(status.CheckedAt) < ttl {
+		return *status
+	}
+	return v.Check()
+}
+
+// Check reads the owner and the signer's roles from the operator contract.
+func (v *SignerVerifier) Check() SignerStatus {
+	o := v.o
+	signer := o.TxManager.SignerAddress()
+	status := SignerStatus{
+		Signer:          signer,
+		ConfiguredOwner: o.OwnerAddr,
+		SignerIsOwner:   signer == o.OwnerAddr,
+		Actions:         make(map[string]bool),
+		Problems:        []string{},
+		CheckedAt:       time.Now().UTC(),
+	}
+	if !status.SignerIsOwner {
+		status.Problems = append(status.Problems, fmt.Sprintf("the key signs as %s, not as the configured owner %s", signer.Hex(), o.OwnerAddr.Hex()))
+	}
+
+	read := status
+	err := v.readContract(&read)
+	if err == nil {
+		status = read
+		status.RolesCheckedAt = &status.CheckedAt
+	} else {
+		// fall back on the roles last read, a flaky RPC mustn't lift the check
+		status.Error = err.Error()
+		status.Problems = append(status.Problems, fmt.Sprintf("failed to read the roles from the operator contract: %v", err))
+		v.mu.Lock()
+		if last := v.status; last != nil && last.RolesCheckedAt != nil {
+			status.ContractOwner, status.RolesCheckedAt = last.ContractOwner, last.RolesCheckedAt
+			status.OwnerRole, status.ControllerRole, status.NodeAddress = last.OwnerRole, last.ControllerRole, last.NodeAddress
+		}
+		v.mu.Unlock()
+	}
+
+	for action, permission := range actionPermissions {
+		switch permission {
+		case permissionAnyone:
+			status.Actions[action] = true
+		case permissionHolder:
+			status.Actions[action] = status.SignerIsOwner
+		case permissionController:
+			status.Actions[action] = status.ControllerRole
+		case permissionOwner:
+			status.Actions[action] = status.ContractOwner != nil && *status.ContractOwner == signer
+		case permissionNode:
+			status.Actions[action] = status.NodeAddress
+		}
+	}
+	if err == nil {
+		// the owner's key is usually not a node, so missing node actions are no problem
+		var refused []string
+		for action, allowed := range status.Actions {
+			if !allowed && actionPermissions[action] != permissionNode {
+				refused = append(refused, action)
+			}
+		}
+		sort.Strings(refused)
+		if len(refused) > 0 {
+			status.Problems = append(status.Problems, fmt.Sprintf("the key can't call %v", refused))
+		}
+	}
+
+	v.mu.Lock()
+	v.status = &status
+	alert := fmt.Sprint(status.Problems) != v.problems && err == nil
+	if alert {
+		v.problems = fmt.Sprint(status.Problems)
+	}
+	v.mu.Unlock()
+
+	if alert && len(status.Problems) > 0 && v.alerts != nil {
+		v.alerts.Alert(AlertWarning, signerAlertSource, "Signing key %s: %v", signer.Hex(), status.Problems)
+	}
+	return status
+}
+
+func (v *SignerVerifier) readContract(status *SignerStatus) error {
+	o := v.o
+	result, err := o.TxManager.ContractCall("owner", nil)
+	if err != nil {
+		return err
+	}
+	owner, ok := result[0].(ethcommon.Address)
+	if !ok {
+		return fmt.Errorf("unexpected owner result: %v", result[0])
+	}
+	status.ContractOwner = &owner
+	if owner != o.OwnerAddr {
+		status.Problems = append(status.Problems, fmt.Sprintf("the configured owner %s is not the contract owner %s", o.OwnerAddr.Hex(), owner.Hex()))
+	}
+
+	if status.OwnerRole, err = o.hasRole(ownerRole, status.Signer); err != nil {
+		return err
+	}
+	if status.ControllerRole, err = o.hasRole(controllerRole, status.Signer); err != nil {
+		return err
+	}
+
+	nodes, err := o.nodeAddresses()
+	if err != nil {
+		return err
+	}
+	for _, node := range nodes {
+		if node == status.Signer {
+			status.NodeAddress = true
+		}
+	}
+	return nil
+}
+
+// Allowed fails with ErrSignerNotPermitted when the signing key can't take the action. If the
+// contract can't be read, the roles last read decide, and without any it fails with
+// ErrSignerUnverified.
+func (v *SignerVerifier) Allowed(action string) error {
+	status := v.Status()
+	if status.Actions[action] {
+		return nil
+	}
+	if permission := actionPermissions[action]; status.RolesCheckedAt == nil && permission != permissionHolder {
+		return fmt.Errorf("%w: %s", ErrSignerUnverified, status.Error)
+	}
+
+	var reason string
+	switch actionPermissions[action] {
+	case permissionController:
+		reason = "it lacks CONTROLLER_ROLE on the operator contract"
+	case permissionOwner:
+		reason = fmt.Sprintf("only the owner %s can", status.ContractOwner.Hex())
+	case permissionNode:
+		reason = "it is not one of the operator's node addresses"
+	case permissionHolder:
+		reason = fmt.Sprintf("the DATA to approve is held by the owner %s", status.ConfiguredOwner.Hex())
+	}
+	return fmt.Errorf("%w: %s can't %s, %s", ErrSignerNotPermitted, status.Signer.Hex(), action, reason)
+}
+
+func (o *Operator) hasRole(role ethcommon.Hash, account ethcommon.Address) (bool, error) {
+	result, err := o.TxManager.ContractCall("hasRole", []interface{}{[32]byte(role), account})
+	if err != nil {
+		return false, err
+	}
+	has, ok := result[0].(bool)
+	if !ok {
+		return false, fmt.Errorf("unexpected hasRole result: %v", result[0])
+	}
+	return has, nil
+}
+
+// logSignerStatus reports at startup what the signing key can't do.
+func logSignerStatus(name string, status SignerStatus) {
+	if len(status.Problems) == 0 {
+		log.Printf("Operator %s: signing key %s is the owner and holds all roles", name, status.Signer.Hex())
+		return
+	}
+	for _, problem := range status.Problems {
+		log.Printf("Operator %s: %s", name, problem)
+	}
+}
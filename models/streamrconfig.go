@@ -0,0 +1,80 @@
+package models
+
+import (
+	"fmt"
+	"math/big"
+	"streamr_api/blockchain"
+
+	ethcommon "github.com/ethereum/go-ethereum/common"
+)
+
+// Fractions in the Streamr contracts are fixed point numbers where 1e18 means 100%.
+var fractionOne = new(big.Int).Exp(big.NewInt(10), big.NewInt(18), nil)
+
+// applyFraction returns amount * fraction / 1e18.
+func applyFraction(amount *big.Int, fraction *big.Int) *big.Int {
+	result := new(big.Int).Mul(amount, fraction)
+	return result.Div(result, fractionOne)
+}
+
+// GetStreamrConfigAddress returns the StreamrConfig contract the operator reads its protocol parameters from.
+func (o *Operator) GetStreamrConfigAddress() (ethcommon.Address, error) {
+	result, err := o.TxManager.ContractCall("streamrConfig", []interface{}{})
+	if err != nil {
+		return ethcommon.Address{}, err
+	}
+
+	addr, ok := result[0].(ethcommon.Address)
+	if !ok {
+		return ethcommon.Address{}, fmt.Errorf("unexpected streamrConfig result: %v", result[0])
+	}
+	return addr, nil
+}
+
+func (o *Operator) streamrConfigUint(method string) (*big.Int, error) {
+	configAddr, err := o.GetStreamrConfigAddress()
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := o.TxManager.ContractCallAt(configAddr, blockchain.StreamrConfigAbi, method, []interface{}{})
+	if err != nil {
+		return nil, err
+	}
+
+	value, ok := result[0].(*big.Int)
+	if !ok {
+		return nil, fmt.Errorf("unexpected %s result: %v", method, result[0])
+	}
+	return value, nil
+}
+
+// GetProtocolFeeFraction returns the share of earnings the protocol takes on withdrawal.
+func (o *Operator) GetProtocolFeeFraction() (*big.Int, error) {
+	return o.streamrConfigUint("protocolFeeFraction")
+}
+
+// GetMinimumStake returns the smallest stake an operator can keep in a sponsorship without leaving it.
+func (o *Operator) GetMinimumStake() (*big.Int, error) {
+	return o.streamrConfigUint("minimumStakeWei")
+}
+
+// GetMinimumSelfDelegationFraction returns the share of operator tokens the owner must hold for the
+// operator to accept delegations.
+func (o *Operator) GetMinimumSelfDelegationFraction() (*big.Int, error) {
+	return o.streamrConfigUint("minimumSelfDelegationFraction")
+}
+
+// GetOperatorsCutFraction returns the share of the earnings (after the protocol fee) that goes to the operator.
+func (o *Operator) GetOperatorsCutFraction() (*big.Int, error) {
+	result, err := o.TxManager.ContractCall("operatorsCutFraction", []interface{}{})
+	if err != nil {
+		return nil, err
+	}
+
+	value, ok := result[0].(*big.Int)
+	if !ok {
+		return nil, fmt.Errorf("unexpected operatorsCutFraction result: %v", result[0])
+	}
+	return value, nil
+}
@@ -0,0 +1,179 @@
+package models
+
+import (
+	"errors"
+	"log"
+	"math/big"
+	"time"
+
+	"streamr_api/blockchain"
+	"streamr_api/common"
+
+	ethcommon "github.com/ethereum/go-ethereum/common"
+)
+
+// Where the DATA token address came from.
+const (
+	TokenFromConfig   = "config"
+	TokenFromOperator = "operator"
+)
+
+// TokenInfo describes the DATA token the service works with.
+type TokenInfo struct {
+	Address     ethcommon.Address `json:"address"`
+	Source      string            `json:"source"` // config (DATA_TOKEN_ADDRESS) or operator (its token())
+	Symbol      string            `json:"symbol"`
+	Decimals    uint8             `json:"decimals"`
+	TotalSupply *common.Amount    `json:"totalSupply"`
+}
+
+// TokenBalances shows the DATA held by the accounts the service works with.
+type TokenBalances struct {
+	Operator *common.Amount `json:"operator"` // free to stake or pay out, not staked in sponsorships
+	Owner    *common.Amount `json:"owner"`
+	Signer   *common.Amount `json:"signer"` // the wallet transactions are signed with, usually the owner
+}
+
+// Allowance is how much DATA spender may transfer from owner.
+type Allowance struct {
+	Owner   ethcommon.Address `json:"owner"`
+	Spender ethcommon.Address `json:"spender"`
+	Amount  *common.Amount    `json:"amount"`
+}
+
+// ApprovalResult shows an approval from the signing wallet. Tx is empty when the allowance was
+// already the requested amount.
+type ApprovalResult struct {
+	Before Allowance `json:"before"`
+	After  Allowance `json:"after"`
+	Tx     string    `json:"tx,omitempty"`
+}
+
+// DataToken returns the DATA token, at DATA_TOKEN_ADDRESS if set or else the token of the operator
+// contract. The address is looked up once.
+func (o *Operator) DataToken() (*blockchain.Token, error) {
+	o.tokenMu.Lock()
+	defer o.tokenMu.Unlock()
+	if o.token != nil {
+		return o.token, nil
+	}
+
+	configured := o.config.Token.Address
+	operatorToken, err := o.GetTokenAddress()
+	if err != nil {
+		if configured == "" {
+			return nil, err
+		}
+		// the configured address works without the operator contract answering
+		o.token, o.tokenSource = blockchain.NewToken(o.TxManager, ethcommon.HexToAddress(configured)), TokenFromConfig
+		return o.token, nil
+	}
+
+	o.token, o.tokenSource = blockchain.NewToken(o.TxManager, operatorToken), TokenFromOperator
+	if configured != "" {
+		if addr := ethcommon.HexToAddress(configured); addr != operatorToken {
+			// staking always moves the operator's own token, so it wins over a wrong configuration
+			log.Printf("Configured DATA token %s is not the token %s of the operator, using the operator's", addr.Hex(), operatorToken.Hex())
+		} else {
+			o.tokenSource = TokenFromConfig
+		}
+	}
+	return o.token, nil
+}
+
+func (o *Operator) GetTokenInfo() (TokenInfo, error) {
+	token, err := o.DataToken()
+	if err != nil {
+		return TokenInfo{}, err
+	}
+	info := TokenInfo{Address: token.Address, Source: o.tokenSource}
+
+	if info.Symbol, err = token.Symbol(); err != nil {
+		return info, err
+	}
+	if info.Decimals, err = token.Decimals(); err != nil {
+		return info, err
+	}
+	supply, err := token.TotalSupply()
+	if err != nil {
+		return info, err
+	}
+	info.TotalSupply = common.NewAmount(supply)
+	return info, nil
+}
+
+func (o *Operator) GetTokenBalances() (TokenBalances, error) {
+	balances := TokenBalances{}
+	token, err := o.DataToken()
+	if err != nil {
+		return balances, err
+	}
+
+	operator, err := token.BalanceOf(o.ContractAddr)
+	if err != nil {
+		return balances, err
+	}
+	owner, err := token.BalanceOf(o.OwnerAddr)
+	if err != nil {
+		return balances, err
+	}
+	signer := owner
+	if o.TxManager.SignerAddress() != o.OwnerAddr {
+		if signer, err = token.BalanceOf(o.TxManager.SignerAddress()); err != nil {
+			return balances, err
+		}
+	}
+
+	balances.Operator = common.NewAmount(operator)
+	balances.Owner = common.NewAmount(owner)
+	balances.Signer = common.NewAmount(signer)
+	return balances, nil
+}
+
+func (o *Operator) GetAllowance(owner ethcommon.Address, spender ethcommon.Address) (Allowance, error) {
+	allowance := Allowance{Owner: owner, Spender: spender}
+	token, err := o.DataToken()
+	if err != nil {
+		return allowance, err
+	}
+
+	amount, err := token.Allowance(owner, spender)
+	if err != nil {
+		return allowance, err
+	}
+	allowance.Amount = common.NewAmount(amount)
+	return allowance, nil
+}
+
+// Approve sets the allowance of spender over the signing wallet's DATA to amount and waits for it
+// to be mined. Zero revokes it.
+func (o *Operator) Approve(spender ethcommon.Address, amount *big.Int) (ApprovalResult, error) {
+	result := ApprovalResult{}
+	if spender == (ethcommon.Address{}) {
+		return result, errors.New("can't approve the zero address")
+	}
+	token, err := o.DataToken()
+	if err != nil {
+		return result, err
+	}
+
+	before, err := o.GetAllowance(o.TxManager.SignerAddress(), spender)
+	if err != nil {
+		return result, err
+	}
+	result.Before, result.After = before, before
+	if before.Amount.Int().Cmp(amount) == 0 {
+		return result, nil
+	}
+
+	tx, err := token.Approve(spender, amount)
+	if err != nil {
+		return result, err
+	}
+	result.Tx = tx
+	if err := o.waitMined(tx, 2*time.Minute); err != nil {
+		return result, err
+	}
+	result.After = Allowance{Owner: before.Owner, Spender: spender, Amount: common.NewAmount(amount)}
+	return result, nil
+}
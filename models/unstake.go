@@ -0,0 +1,105 @@
+package models
+
+import (
+	"fmt"
+	"math/big"
+	"time"
+
+	"streamr_api/blockchain"
+	"streamr_api/common"
+
+	ethcommon "github.com/ethereum/go-ethereum/common"
+)
+
+// UnstakePreview shows what leaving a sponsorship would return and cost, as read from the
+// sponsorship contract. A normal unstake only goes through without locked stake and without a leave
+// penalty; a forced unstake always does, but forfeits both.
+type UnstakePreview struct {
+	Sponsorship     ethcommon.Address `json:"sponsorship"`
+	Forced          bool              `json:"forced"`
+	Stake           *common.Amount    `json:"stake"`
+	LockedStake     *common.Amount    `json:"lockedStake"` // committed to open flags
+	Earnings        *common.Amount    `json:"earnings"`    // paid out along with the stake
+	LeavePenalty    *common.Amount    `json:"leavePenalty"`
+	Forfeit         *common.Amount    `json:"forfeit"`  // lost by a forced unstake
+	Returned        *common.Amount    `json:"returned"` // stake returned to the operator
+	JoinedAt        time.Time         `json:"joinedAt"`
+	PenaltyFreeAt   *time.Time        `json:"penaltyFreeAt,omitempty"` // end of the minimum staking period, if still ahead
+	CanUnstake      bool              `json:"canUnstake"`              // a normal unstake would go through
+	NeedsConfirm    bool              `json:"needsConfirmation"`       // a forced unstake would forfeit stake
+	Warnings        []string          `json:"warnings"`
+	TxHash          string            `json:"txHash,omitempty"`
+	MaxQueuePayouts int64             `json:"maxQueuePayouts,omitempty"` // queue entries a forced unstake pays out, 0 for all
+}
+
+// PreviewUnstake reads what leaving the sponsorship now would return and forfeit.
+func (o *Operator) PreviewUnstake(sponsorship ethcommon.Address, forced bool) (UnstakePreview, error) {
+	preview := UnstakePreview{Sponsorship: sponsorship, Forced: forced, Warnings: []string{}}
+
+	values := make(map[string]*big.Int)
+	for _, method := range []string{"stakedWei", "lockedStakeWei", "getEarnings", "getLeavePenalty", "joinTimestampOfOperator"} {
+		result, err := o.TxManager.ContractCallAt(sponsorship, blockchain.SponsorshipAbi, method, []interface{}{o.ContractAddr})
+		if err != nil {
+			return preview, err
+		}
+		value, ok := result[0].(*big.Int)
+		if !ok {
+			return preview, fmt.Errorf("unexpected %s result: %v", method, result[0])
+		}
+		values[method] = value
+	}
+	stake := values["stakedWei"]
+	if stake.Sign() == 0 {
+		return preview, fmt.Errorf("operator is not staked in sponsorship %s", sponsorship.Hex())
+	}
+	locked := values["lockedStakeWei"]
+	penalty := values["getLeavePenalty"]
+
+	preview.Stake = common.NewAmount(stake)
+	preview.LockedStake = common.NewAmount(locked)
+	preview.Earnings = common.NewAmount(values["getEarnings"])
+	preview.LeavePenalty = common.NewAmount(penalty)
+	preview.JoinedAt = time.Unix(values["joinTimestampOfOperator"].Int64(), 0).UTC()
+
+	// older sponsorships keep the period in their leave policy, the penalty still tells the story
+	if result, err := o.TxManager.ContractCallAt(sponsorship, blockchain.SponsorshipAbi, "minimumStakingPeriodSeconds", []interface{}{}); err == nil {
+		if period, ok := result[0].(*big.Int); ok && period.IsInt64() {
+			end := preview.JoinedAt.Add(time.Duration(period.Int64()) * time.Second)
+			if end.After(time.Now()) {
+				preview.PenaltyFreeAt = &end
+			}
+		}
+	}
+
+	forfeit := new(big.Int).Add(locked, penalty)
+	if forfeit.Cmp(stake) > 0 {
+		forfeit.Set(stake)
+	}
+	preview.CanUnstake = locked.Sign() == 0 && penalty.Sign() == 0
+	if forced {
+		preview.Forfeit = common.NewAmount(forfeit)
+		preview.Returned = common.NewAmount(new(big.Int).Sub(stake, forfeit))
+		preview.NeedsConfirm = forfeit.Sign() > 0
+	} else {
+		preview.Forfeit = common.NewAmount(nil)
+		preview.Returned = common.NewAmount(stake)
+	}
+
+	if locked.Sign() > 0 {
+		preview.Warnings = append(preview.Warnings, fmt.Sprintf("%s DATA is locked in open flags; a normal unstake is refused until they resolve, a forced unstake forfeits it", preview.LockedStake.DATA()))
+	}
+	if penalty.Sign() > 0 {
+		msg := fmt.Sprintf("leaving now costs a leave penalty of %s DATA", preview.LeavePenalty.DATA())
+		if preview.PenaltyFreeAt != nil {
+			msg += fmt.Sprintf(", the minimum staking period ends at %s", preview.PenaltyFreeAt.Format(time.RFC3339))
+		}
+		preview.Warnings = append(preview.Warnings, msg)
+	}
+	return preview, nil
+}
+
+// ForceUnstake leaves a sponsorship even with locked stake or a leave penalty, forfeiting both, and
+// then pays out up to maxQueuePayouts undelegation queue entries (0 for all).
+func (o *Operator) ForceUnstake(addr ethcommon.Address, maxQueuePayouts int64) (string, error) {
+	return o.TxManager.ContractSendTx("forceUnstake", []interface{}{addr, big.NewInt(maxQueuePayouts)})
+}
@@ -0,0 +1,239 @@
+package models
+
+import (
+	"fmt"
+	"log"
+	"math/big"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"streamr_api/common"
+	"streamr_api/config"
+	"streamr_api/metrics"
+
+	ethcommon "github.com/ethereum/go-ethereum/common"
+)
+
+const walletAlertSource = "wallet"
+
+// WalletLevelOK is the wallet level while no threshold is crossed.
+const WalletLevelOK = "ok"
+
+type WalletMonitorConfig struct {
+	MinPOL       *big.Int      // warn below this balance whatever the spend, 0 disables
+	AlertDays    float64       // warn when the POL covers fewer days of gas
+	CriticalDays float64       // raise a critical alert when the POL covers fewer days of gas
+	GasWindow    time.Duration // how much journal history the daily gas spend is averaged over
+	Interval     time.Duration // 0 disables monitoring in the background
+}
+
+// WalletStatus shows the balances of the signing wallet, which pays the gas of every transaction the
+// service sends, and how long the POL lasts at the recent gas spend.
+type WalletStatus struct {
+	Address      ethcommon.Address `json:"address"`
+	POL          *common.Amount    `json:"pol"`
+	DATA         *common.Amount    `json:"data"`
+	GasSpent     *common.Amount    `json:"gasSpent"`     // POL paid for gas within the window
+	Transactions int               `json:"transactions"` // mined or reverted within the window
+	WindowDays   float64           `json:"windowDays"`   // span the spend is averaged over, shorter than configured while the journal is young
+	DailyGas     *common.Amount    `json:"dailyGas"`     // average POL spent on gas per day
+	DaysCovered  float64           `json:"daysCovered"`  // -1 when nothing was spent, so there is no estimate
+	Level        string            `json:"level"`        // ok, warning or critical
+	LastCheck    time.Time         `json:"lastCheck"`
+	LastError    string            `json:"lastError,omitempty"`
+}
+
+// WalletMonitor checks the balances of the signing wallet and alerts before it runs out of POL, as
+// every transaction, including the ones cron jobs trigger, fails once it can't pay for gas.
+type WalletMonitor struct {
+	o      *Operator
+	alerts *Alerter
+	config atomic.Pointer[WalletMonitorConfig]
+
+	mu     sync.Mutex
+	status WalletStatus
+
+	quit chan struct{}
+	wg   sync.WaitGroup
+}
+
+func NewWalletMonitorConfig(p config.WalletPolicy) (WalletMonitorConfig, error) {
+	minPOL, err := common.ParseUnits(p.MinPOL, 18)
+	if err != nil {
+		return WalletMonitorConfig{}, fmt.Errorf("policies.wallet.minPol (WALLET_MIN_POL): %v", err)
+	}
+	return WalletMonitorConfig{
+		MinPOL:       minPOL,
+		AlertDays:    p.AlertDays,
+		CriticalDays: p.CriticalDays,
+		GasWindow:    time.Duration(p.GasWindowDays) * 24 * time.Hour,
+		Interval:     time.Duration(p.IntervalSeconds) * time.Second,
+	}, nil
+}
+
+func NewWalletMonitor(o *Operator, alerts *Alerter, config WalletMonitorConfig) *WalletMonitor {
+	w := &WalletMonitor{
+		o:      o,
+		alerts: alerts,
+		status: WalletStatus{Level: WalletLevelOK},
+		quit:   make(chan struct{}),
+	}
+	w.config.Store(&config)
+	return w
+}
+
+func (w *WalletMonitor) Start() {
+	config := w.config.Load()
+	if config.Interval == 0 {
+		log.Printf("Wallet monitoring is disabled")
+		return
+	}
+
+	w.wg.Add(1)
+	go func() {
+		defer w.wg.Done()
+
+		ticker := time.NewTicker(config.Interval)
+		defer ticker.Stop()
+
+		for {
+			if _, err := w.Check(); err != nil {
+				log.Printf("Wallet check failed: %v", err)
+			}
+
+			select {
+			case <-w.quit:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+func (w *WalletMonitor) Stop() {
+	close(w.quit)
+	w.wg.Wait()
+}
+
+// Reload restarts the service with a new configuration once the check in progress is done.
+func (w *WalletMonitor) Reload(config WalletMonitorConfig) {
+	w.Stop()
+	w.config.Store(&config)
+	w.quit = make(chan struct{})
+	w.Start()
+}
+
+func (w *WalletMonitor) Status() WalletStatus {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.status
+}
+
+// Check reads the balances and the gas spend, updates the gauges and alerts when the level of the
+// wallet changed for the worse.
+func (w *WalletMonitor) Check() (WalletStatus, error) {
+	status := WalletStatus{Address: w.o.TxManager.SignerAddress(), DaysCovered: -1, Level: WalletLevelOK, LastCheck: time.Now().UTC()}
+
+	pol, err := w.o.TxManager.BalanceAt(status.Address)
+	if err == nil {
+		status.POL = common.NewAmount(pol)
+		var data *big.Int
+		if data, err = w.o.GetWalletDataBalance(status.Address); err == nil {
+			status.DATA = common.NewAmount(data)
+			err = w.gasSpend(&status)
+		}
+	}
+	if err != nil {
+		w.mu.Lock()
+		w.status.LastCheck = status.LastCheck
+		w.status.LastError = err.Error()
+		w.mu.Unlock()
+		return w.Status(), err
+	}
+
+	status.Level = w.level(status)
+	metrics.WalletPOLBalance.WithLabelValues(w.o.Name, status.Address.Hex()).Set(metrics.Float(status.POL.Int()))
+	metrics.WalletDataBalance.WithLabelValues(w.o.Name).Set(metrics.Float(status.DATA.Int()))
+	metrics.GasDailySpend.WithLabelValues(w.o.Name).Set(metrics.Float(status.DailyGas.Int()))
+	metrics.GasDaysCovered.WithLabelValues(w.o.Name).Set(status.DaysCovered)
+
+	w.mu.Lock()
+	previous := w.status.Level
+	w.status = status
+	w.mu.Unlock()
+
+	if status.Level != previous && status.Level != WalletLevelOK {
+		days := "no recent gas spend to estimate from"
+		if status.DaysCovered >= 0 {
+			days = fmt.Sprintf("covers %.1f days of gas at %s POL a day", status.DaysCovered, status.DailyGas.DATA())
+		}
+		w.alerts.Alert(status.Level, walletAlertSource, "signing wallet %s holds %s POL, which %s, top it up before transactions start failing",
+			status.Address.Hex(), status.POL.DATA(), days)
+	} else if status.Level == WalletLevelOK && previous != WalletLevelOK {
+		w.alerts.Alert(AlertInfo, walletAlertSource, "signing wallet %s holds %s POL again", status.Address.Hex(), status.POL.DATA())
+	}
+	return status, nil
+}
+
+// gasSpend averages the gas paid in the journal window per day and works out the days the POL covers.
+func (w *WalletMonitor) gasSpend(status *WalletStatus) error {
+	status.GasSpent = common.NewAmount(big.NewInt(0))
+	status.DailyGas = common.NewAmount(big.NewInt(0))
+	journal := w.o.TxManager.Journal()
+	if journal == nil {
+		return nil
+	}
+
+	now := time.Now().UTC()
+	entries, err := journal.List(now.Add(-w.config.Load().GasWindow), time.Time{})
+	if err != nil {
+		return err
+	}
+
+	spent := big.NewInt(0)
+	var first time.Time
+	for _, entry := range entries {
+		if entry.GasCostWei == nil {
+			continue
+		}
+		if first.IsZero() {
+			first = entry.SentAt
+		}
+		spent.Add(spent, entry.GasCostWei)
+		status.Transactions++
+	}
+	if status.Transactions == 0 {
+		return nil
+	}
+
+	// a young journal is averaged over the time it covers, but at least a day so a burst of
+	// transactions right after starting doesn't look like the daily rate
+	window := now.Sub(first)
+	if window < 24*time.Hour {
+		window = 24 * time.Hour
+	}
+	status.WindowDays = window.Hours() / 24
+	status.GasSpent = common.NewAmount(spent)
+	daily, _ := new(big.Float).Quo(new(big.Float).SetInt(spent), big.NewFloat(status.WindowDays)).Int(nil)
+	status.DailyGas = common.NewAmount(daily)
+	if daily.Sign() > 0 {
+		status.DaysCovered = ratio(status.POL.Int(), daily)
+	}
+	return nil
+}
+
+func (w *WalletMonitor) level(status WalletStatus) string {
+	covered := status.DaysCovered
+	switch {
+	case status.POL.Int().Sign() == 0:
+		return AlertCritical
+	case covered >= 0 && covered < w.config.Load().CriticalDays:
+		return AlertCritical
+	case covered >= 0 && covered < w.config.Load().AlertDays:
+		return AlertWarning
+	case w.config.Load().MinPOL.Sign() > 0 && status.POL.Int().Cmp(w.config.Load().MinPOL) < 0:
+		return AlertWarning
+	}
+	return WalletLevelOK
+}
@@ -0,0 +1,211 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"sort"
+	"time"
+
+	"streamr_api/blockchain"
+	"streamr_api/common"
+
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// ErrInvalidSelection is returned when a withdrawal selection names a sponsorship the operator
+// isn't staked in or matches no sponsorship with earnings.
+var ErrInvalidSelection = errors.New("invalid withdrawal selection")
+
+// WithdrawalSelection chooses the sponsorships to withdraw earnings from. The filters combine:
+// the explicit list (all sponsorships if empty) is narrowed to those with at least MinEarnings,
+// then to the TopN with the most earnings. Sponsorships without earnings are always skipped.
+type WithdrawalSelection struct {
+	Sponsorships []ethcommon.Address
+	MinEarnings  *big.Int // nil for no minimum
+	TopN         int      // 0 for no limit
+}
+
+type SponsorshipEarnings struct {
+	Sponsorship ethcommon.Address `json:"sponsorship"`
+	Earnings    *common.Amount    `json:"earnings"`
+}
+
+// WithdrawalResult shows the earnings expected from the selected sponsorships when the
+// transaction was sent and, once it is mined, the amounts the sponsorships actually paid out.
+// Earnings keep accruing until the transaction is mined, so the actual amounts are usually a
+// little higher.
+type WithdrawalResult struct {
+	Selected      []SponsorshipEarnings `json:"selected"`
+	Skipped       []SponsorshipEarnings `json:"skipped"`
+	ExpectedTotal *common.Amount        `json:"expectedTotal"`
+	TxHash        string                `json:"txHash,omitempty"`
+	Status        string                `json:"status,omitempty"` // pending, mined or failed; empty for a dry run
+	Actual        []SponsorshipEarnings `json:"actual,omitempty"`
+	ActualTotal   *common.Amount        `json:"actualTotal,omitempty"`
+	ProtocolFee   *common.Amount        `json:"protocolFee,omitempty"`
+	OperatorCut   *common.Amount        `json:"operatorCut,omitempty"`
+}
+
+// PlanWithdrawal applies the selection to the current earnings without sending anything.
+func (o *Operator) PlanWithdrawal(selection WithdrawalSelection) (WithdrawalResult, error) {
+	sponsors, err := o.GetSponsorshipsAndEarnings()
+	if err != nil {
+		return WithdrawalResult{}, err
+	}
+	return selectWithdrawal(sponsors, selection)
+}
+
+// WithdrawSelected withdraws the earnings of the sponsorships picked by the selection. If wait is
+// non-zero it waits up to that long for the transaction and reports the amounts actually withdrawn.
+func (o *Operator) WithdrawSelected(selection WithdrawalSelection, wait time.Duration) (WithdrawalResult, error) {
+	result, err := o.PlanWithdrawal(selection)
+	if err != nil {
+		return result, err
+	}
+	if len(result.Selected) == 0 {
+		return result, fmt.Errorf("%w: no sponsorship with earnings matches it", ErrInvalidSelection)
+	}
+
+	addresses := make([]ethcommon.Address, len(result.Selected))
+	for i, selected := range result.Selected {
+		addresses[i] = selected.Sponsorship
+	}
+
+	result.TxHash, err = o.WithdrawEarningsFrom(addresses)
+	if err != nil {
+		return result, err
+	}
+	result.Status = blockchain.TxPending
+	if wait == 0 {
+		return result, nil
+	}
+
+	if _, err := o.TxManager.PolygonWaitForTx(result.TxHash, wait); err != nil {
+		log.Printf("Withdrawal %s not mined yet: %v", result.TxHash, err)
+		return result, nil
+	}
+	receipt, err := o.TxManager.Receipt(result.TxHash)
+	if err != nil {
+		log.Printf("Failed to get receipt of %s: %v", result.TxHash, err)
+		return result, nil
+	}
+	if receipt.Status == types.ReceiptStatusFailed {
+		result.Status = blockchain.TxFailed
+		return result, fmt.Errorf("withdrawal %s reverted", result.TxHash)
+	}
+
+	result.Status = blockchain.TxMined
+	if err := o.applyWithdrawalReceipt(&result, addresses, receipt); err != nil {
+		log.Printf("Failed to read withdrawn amounts of %s: %v", result.TxHash, err)
+	}
+	return result, nil
+}
+
+// applyWithdrawalReceipt reads the DATA transfers from each sponsorship to the operator and the
+// Profit event of the operator from the receipt.
+func (o *Operator) applyWithdrawalReceipt(result *WithdrawalResult, sponsorships []ethcommon.Address, receipt *types.Receipt) error {
+	token, err := o.DataToken()
+	if err != nil {
+		return err
+	}
+
+	transfer := blockchain.ERC20Abi.Events["Transfer"]
+	withdrawn := make(map[ethcommon.Address]*big.Int)
+	for _, l := range receipt.Logs {
+		switch {
+		case l.Address == token.Address && len(l.Topics) == 3 && l.Topics[0] == transfer.ID:
+			from := ethcommon.BytesToAddress(l.Topics[1].Bytes())
+			to := ethcommon.BytesToAddress(l.Topics[2].Bytes())
+			if to != o.ContractAddr {
+				continue
+			}
+			if _, ok := withdrawn[from]; !ok {
+				withdrawn[from] = big.NewInt(0)
+			}
+			withdrawn[from].Add(withdrawn[from], new(big.Int).SetBytes(l.Data))
+		case l.Address == o.ContractAddr:
+			profit, ok := o.ContractAbi.Events["Profit"]
+			if !ok || len(l.Topics) == 0 || l.Topics[0] != profit.ID {
+				continue
+			}
+			values := make(map[string]interface{})
+			if err := o.ContractAbi.UnpackIntoMap(values, "Profit", l.Data); err != nil {
+				return err
+			}
+			if fee, ok := values["protocolFeeDataWei"].(*big.Int); ok {
+				result.ProtocolFee = common.NewAmount(fee)
+			}
+			if cut, ok := values["operatorsCutDataWei"].(*big.Int); ok {
+				result.OperatorCut = common.NewAmount(cut)
+			}
+		}
+	}
+
+	total := big.NewInt(0)
+	result.Actual = []SponsorshipEarnings{}
+	for _, addr := range sponsorships {
+		amount, ok := withdrawn[addr]
+		if !ok {
+			amount = big.NewInt(0)
+		}
+		total.Add(total, amount)
+		result.Actual = append(result.Actual, SponsorshipEarnings{Sponsorship: addr, Earnings: common.NewAmount(amount)})
+	}
+	result.ActualTotal = common.NewAmount(total)
+	return nil
+}
+
+func selectWithdrawal(sponsors GetSponsorshipsAndEarningsResponse, selection WithdrawalSelection) (WithdrawalResult, error) {
+	earnings := make(map[ethcommon.Address]*common.Amount)
+	for i, addr := range sponsors.Addresses {
+		earnings[addr] = sponsors.Earnings[i]
+	}
+
+	candidates := sponsors.Addresses
+	if len(selection.Sponsorships) > 0 {
+		// a sponsorship listed twice would be withdrawn from twice in the same transaction
+		candidates = []ethcommon.Address{}
+		listed := make(map[ethcommon.Address]bool)
+		for _, addr := range selection.Sponsorships {
+			if _, ok := earnings[addr]; !ok {
+				return WithdrawalResult{}, fmt.Errorf("%w: operator is not staked in sponsorship %s", ErrInvalidSelection, addr.Hex())
+			}
+			if !listed[addr] {
+				listed[addr] = true
+				candidates = append(candidates, addr)
+			}
+		}
+	}
+
+	result := WithdrawalResult{
+		Selected: []SponsorshipEarnings{},
+		Skipped:  []SponsorshipEarnings{},
+	}
+	for _, addr := range candidates {
+		entry := SponsorshipEarnings{Sponsorship: addr, Earnings: earnings[addr]}
+		amount := entry.Earnings.Int()
+		if amount.Sign() == 0 || (selection.MinEarnings != nil && amount.Cmp(selection.MinEarnings) < 0) {
+			result.Skipped = append(result.Skipped, entry)
+			continue
+		}
+		result.Selected = append(result.Selected, entry)
+	}
+
+	sort.SliceStable(result.Selected, func(i, j int) bool {
+		return result.Selected[i].Earnings.Int().Cmp(result.Selected[j].Earnings.Int()) > 0
+	})
+	if selection.TopN > 0 && len(result.Selected) > selection.TopN {
+		result.Skipped = append(result.Skipped, result.Selected[selection.TopN:]...)
+		result.Selected = result.Selected[:selection.TopN]
+	}
+
+	total := big.NewInt(0)
+	for _, selected := range result.Selected {
+		total.Add(total, selected.Earnings.Int())
+	}
+	result.ExpectedTotal = common.NewAmount(total)
+	return result, nil
+}
@@ -1,36 +1,126 @@
 package routes
 
 import (
+	"streamr_api/config"
 	"streamr_api/handlers"
 	"streamr_api/models"
 
 	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
-func SetupRouter(o *models.Operator, s *models.Scheduler) *gin.Engine {
+func SetupRouter(r *models.Registry, s *models.Scheduler, l *models.Lifecycle, auth config.AuthConfig, version string) *gin.Engine {
 	gin.SetMode(gin.DebugMode)
 	router := gin.New()
 
-	v1 := router.Group("/api/v1")
+	router.GET("/metrics", gin.WrapH(promhttp.Handler()))
+	router.GET("/healthz", handlers.Healthz())
+	router.GET("/readyz", handlers.Readyz(r, s, l))
+
+	v1 := router.Group("/api/v1", handlers.RequireAPIKey(auth.APIKeys))
 	{
-		v1.GET("/operator", handlers.GetOperator(o))
-		v1.GET("/operator/valuewithoutearnings", handlers.OperatorValueWithoutEarnings(o))
-		v1.GET("/operator/withdrawearnings", handlers.OperatorWithdrawEarnings(o))
-		// v1.GET("/operator/withdrawearningsandcompound", handlers.WithdrawEarningsAndCompound(o))
-		v1.GET("/operator/stakeprorata", handlers.StakeProRata(o))
-		v1.GET("/operator/sponsorshipsandearnings", handlers.SponsorshipsAndEarnings(o))
-		v1.GET("/operator/stakedinto/:address", handlers.StakedInto(o))
-		v1.GET("/operator/deployedstake", handlers.DeployedStake(o))
-		v1.GET("/operator/reducestaketo/:sponsorship/:amount", handlers.ReduceStakeTo(o))
-		v1.GET("/operator/stake/:sponsorship/:amount", handlers.Stake(o))
-		v1.GET("/operator/undelegationqueue", handlers.UndelegationQueue(o))
-
-		v1.POST("/cronjobs/create", handlers.CreateCronJob(s))
+		operatorRoutes(v1, r.Default(), l)
+
+		v1.GET("/operators", handlers.Operators(r))
+		v1.GET("/portfolio", handlers.Portfolio(r))
+		v1.GET("/diagnostics", handlers.Diagnostics(r, s, version))
+		for _, o := range r.Operators() {
+			operatorRoutes(v1.Group("/operators/"+o.Name), o, l)
+		}
+
+		draining := handlers.RejectWhileDraining(l)
+		v1.POST("/cronjobs/create", draining, handlers.CreateCronJob(s))
 		v1.GET("/cronjobs", handlers.GetCronJobs(s))
-		v1.POST("/cronjobs/disable/:id", handlers.DisableCronJob(s))
-		v1.POST("/cronjobs/enable/:id", handlers.EnableCronJob(s))
-		v1.POST("/cronjobs/delete/:id", handlers.DeleteCronJob(s))
+		v1.POST("/cronjobs/disable/:id", draining, handlers.DisableCronJob(s))
+		v1.POST("/cronjobs/enable/:id", draining, handlers.EnableCronJob(s))
+		v1.POST("/cronjobs/delete/:id", draining, handlers.DeleteCronJob(s))
 	}
 
 	return router
 }
+
+// operatorRoutes registers the routes acting on one operator. The default operator's are on /api/v1
+// itself, every operator's under /api/v1/operators/{name}. The routes sending transactions are refused
+// once the service is shutting down.
+func operatorRoutes(g *gin.RouterGroup, o *models.Operator, l *models.Lifecycle) {
+	draining := handlers.RejectWhileDraining(l)
+
+	g.GET("/operator", handlers.GetOperator(o))
+	g.GET("/operator/valuewithoutearnings", handlers.OperatorValueWithoutEarnings(o))
+	g.GET("/operator/withdrawearnings", draining, handlers.RequireSigner(o, models.ActionWithdraw), handlers.OperatorWithdrawEarnings(o))
+	g.GET("/operator/withdrawearnings/selective", draining, handlers.RequireSigner(o, models.ActionWithdraw), handlers.SelectiveWithdraw(o))
+	g.GET("/operator/withdrawearningsandcompound", draining, handlers.RequireSigner(o, models.ActionStake), handlers.WithdrawEarningsAndCompound(o))
+	g.GET("/operator/stakeprorata", draining, handlers.RequireSigner(o, models.ActionStake), handlers.StakeProRata(o))
+	g.GET("/operator/sponsorshipsandearnings", handlers.SponsorshipsAndEarnings(o))
+	g.GET("/operator/stakedinto/:address", handlers.StakedInto(o))
+	g.GET("/operator/deployedstake", handlers.DeployedStake(o))
+	g.GET("/operator/reducestaketo/:sponsorship/:amount", draining, handlers.RequireSigner(o, models.ActionReduceStake), handlers.ReduceStakeTo(o))
+	g.GET("/operator/stake/:sponsorship/:amount", draining, handlers.RequireSigner(o, models.ActionStake), handlers.Stake(o))
+	g.GET("/operator/unstake/:sponsorship", draining, handlers.RequireSigner(o, models.ActionUnstake), handlers.Unstake(o))
+	g.GET("/operator/forceunstake/:sponsorship", draining, handlers.RequireSigner(o, models.ActionUnstake), handlers.ForceUnstake(o))
+	g.GET("/operator/undelegationqueue", handlers.UndelegationQueue(o))
+	g.GET("/operator/undelegationqueue/plan", handlers.QueuePlan(o))
+	g.GET("/operator/undelegationqueue/service", draining, handlers.RequireSigner(o, models.ActionReduceStake), handlers.ServiceQueue(o))
+	g.GET("/operator/undelegationqueue/reports", handlers.QueueReports(o))
+	g.GET("/operator/transactions", handlers.Transactions(o))
+	g.GET("/operator/signer", handlers.Signer(o))
+	g.GET("/operator/selfdelegation", handlers.SelfDelegation(o))
+	g.GET("/operator/selfdelegation/delegate/:amount", draining, handlers.RequireSigner(o, models.ActionDelegate), handlers.SelfDelegate(o))
+	g.GET("/operator/selfdelegation/undelegate/:amount", draining, handlers.RequireSigner(o, models.ActionDelegate), handlers.SelfUndelegate(o))
+
+	g.GET("/events", handlers.Events(o))
+	g.GET("/events/status", handlers.IndexerStatus(o))
+	g.GET("/events/delegations", handlers.Delegations(o))
+	g.GET("/events/undelegations", handlers.Undelegations(o))
+	g.GET("/events/stakechanges", handlers.StakeChanges(o))
+	g.GET("/events/withdrawals", handlers.EarningsWithdrawals(o))
+
+	g.GET("/operator/fees", handlers.Fees(o))
+	g.GET("/operator/cut/change/:percent", draining, handlers.RequireSigner(o, models.ActionCut), handlers.ChangeCut(o))
+	g.GET("/operator/cut/changes", handlers.CutChanges(o))
+	g.GET("/operator/nodes", handlers.NodeAddresses(o))
+	g.POST("/operator/nodes", draining, handlers.RequireSigner(o, models.ActionNodes), handlers.SetNodeAddresses(o))
+	g.GET("/operator/metadata", handlers.Metadata(o))
+	g.POST("/operator/metadata", draining, handlers.RequireSigner(o, models.ActionMetadata), handlers.UpdateMetadata(o))
+
+	g.GET("/delegators", handlers.Delegators(o))
+	g.GET("/delegators/:address/statement", handlers.DelegatorStatement(o))
+
+	g.GET("/accounting/summary", handlers.AccountingSummary(o))
+	g.GET("/accounting/yield", handlers.AccountingYield(o))
+	g.GET("/accounting/withdrawals", handlers.AccountingWithdrawals(o))
+	g.GET("/accounting/snapshot", handlers.AccountingSnapshot(o))
+
+	g.GET("/export/ledger", handlers.ExportLedger(o))
+
+	g.GET("/guard/earnings", handlers.EarningsGuardStatus(o))
+	g.GET("/guard/earnings/check", draining, handlers.RequireSigner(o, models.ActionWithdraw), handlers.EarningsGuardCheck(o))
+	g.GET("/guard/earnings/actions", handlers.EarningsGuardActions(o))
+
+	g.GET("/rebalance/targets", handlers.GetRebalanceTargets(o))
+	g.POST("/rebalance/targets", handlers.SetRebalanceTargets(o))
+	g.GET("/rebalance/plan", handlers.RebalancePlan(o))
+	g.GET("/rebalance/execute", draining, handlers.RequireSigner(o, models.ActionReduceStake), handlers.Rebalance(o))
+
+	g.GET("/sponsorships", handlers.Sponsorships(o))
+	g.GET("/sponsorships/:address", handlers.Sponsorship(o))
+
+	g.GET("/exit/plan", handlers.ExitPlan(o))
+	g.GET("/exit/check", draining, handlers.RequireSigner(o, models.ActionUnstake), handlers.ExitCheck(o))
+	g.GET("/exit/reports", handlers.ExitReports(o))
+
+	g.GET("/reviews", handlers.Reviews(o))
+	g.GET("/reviews/vote/:sponsorship/:target/:vote", draining, handlers.RequireSigner(o, models.ActionVote), handlers.VoteOnFlag(o))
+	g.GET("/flag/:sponsorship/:target", draining, handlers.RequireSigner(o, models.ActionFlag), handlers.Flag(o))
+
+	g.GET("/token", handlers.TokenInfo(o))
+	g.GET("/token/balances", handlers.TokenBalances(o))
+	g.GET("/token/balance/:address", handlers.TokenBalance(o))
+	g.GET("/token/allowance/:spender", handlers.TokenAllowance(o))
+	g.GET("/token/approve/:spender/:amount", draining, handlers.RequireSigner(o, models.ActionApprove), handlers.TokenApprove(o))
+
+	g.GET("/wallet", handlers.Wallet(o))
+	g.GET("/wallet/check", handlers.WalletCheck(o))
+
+	g.GET("/alerts", handlers.Alerts(o))
+}